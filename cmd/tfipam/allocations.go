@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+// allocationDetail is the output of allocations show.
+type allocationDetail struct {
+	storage.Allocation
+	Addresses []storage.Address `json:"addresses"`
+}
+
+func runAllocations(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: tfipam allocations <list|show> [flags]")
+	}
+
+	switch args[0] {
+	case "list":
+		return runAllocationsList(ctx, args[1:])
+	case "show":
+		return runAllocationsShow(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown allocations command %q, expected list or show", args[0])
+	}
+}
+
+func runAllocationsList(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("allocations list", "allocations list [--pool name] [--tag key=value] [flags]")
+	poolName := fs.String("pool", "", "only list allocations from this pool")
+	tags := tagFlags(fs, "allocations")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	s, err := opts.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	var allocations []storage.Allocation
+	if *poolName != "" {
+		allocations, err = s.ListAllocationsByPool(ctx, *poolName)
+	} else {
+		allocations, err = s.ListAllocations(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list allocations: %w", err)
+	}
+	allocations = slices.DeleteFunc(allocations, func(alloc storage.Allocation) bool { return !alloc.HasTags(tags) })
+	sortAllocations(allocations)
+
+	return render(opts.output, allocations, func(w io.Writer) {
+		writeAllocationTable(w, allocations)
+	})
+}
+
+func runAllocationsShow(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("allocations show", "allocations show <id> [flags]")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: tfipam allocations show <id> [flags]")
+	}
+
+	s, err := opts.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	allocation, err := s.GetAllocation(ctx, positional[0])
+	if err != nil {
+		return fmt.Errorf("allocation %s: %w", positional[0], err)
+	}
+
+	addresses, err := s.ListAddressesByAllocation(ctx, allocation.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list addresses: %w", err)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i].ID < addresses[j].ID })
+
+	detail := allocationDetail{
+		Allocation: *allocation,
+		Addresses:  addresses,
+	}
+	return render(opts.output, detail, func(w io.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", allocation.ID)
+		fmt.Fprintf(w, "Pool:\t%s\n", allocation.PoolName)
+		fmt.Fprintf(w, "CIDR:\t%s\n", allocation.AllocatedCIDR)
+		fmt.Fprintf(w, "Prefix Length:\t%d\n", allocation.PrefixLength)
+		if len(allocation.Blocks) > 0 {
+			fmt.Fprintf(w, "Blocks:\t%s\n", strings.Join(allocation.Blocks, ", "))
+		}
+		writeMetadata(w, allocation.Metadata)
+		if allocation.Orphaned {
+			fmt.Fprintf(w, "Orphaned:\ttrue\n")
+		}
+		if allocation.ExpiresAt != nil {
+			fmt.Fprintf(w, "Expires At:\t%s\n", allocation.ExpiresAt.UTC().Format(time.RFC3339))
+		}
+		fmt.Fprintf(w, "Addresses:\t%d\n", len(addresses))
+		if len(addresses) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "ID\tADDRESS")
+			for _, address := range addresses {
+				fmt.Fprintf(w, "%s\t%s\n", address.ID, address.Address)
+			}
+		}
+	})
+}
+
+func writeAllocationTable(w io.Writer, allocations []storage.Allocation) {
+	fmt.Fprintln(w, "ID\tPOOL\tCIDR")
+	for _, alloc := range allocations {
+		cidr := alloc.AllocatedCIDR
+		if alloc.Orphaned {
+			cidr += " (orphaned)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", alloc.ID, alloc.PoolName, cidr)
+	}
+}
+
+func sortAllocations(allocations []storage.Allocation) {
+	sort.Slice(allocations, func(i, j int) bool { return allocations[i].ID < allocations[j].ID })
+}
+
+// writeMetadata writes the description, owner and tags that are set.
+func writeMetadata(w io.Writer, metadata storage.Metadata) {
+	if metadata.Description != "" {
+		fmt.Fprintf(w, "Description:\t%s\n", metadata.Description)
+	}
+	if metadata.Owner != "" {
+		fmt.Fprintf(w, "Owner:\t%s\n", metadata.Owner)
+	}
+	if len(metadata.Tags) > 0 {
+		tags := make([]string, 0, len(metadata.Tags))
+		for key, value := range metadata.Tags {
+			tags = append(tags, key+"="+value)
+		}
+		sort.Strings(tags)
+		fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(tags, ", "))
+	}
+}
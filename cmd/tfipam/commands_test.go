@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+// testStorageFile writes a file backend with one pool and two allocations and
+// returns its path.
+func testStorageFile(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ipam-storage.json")
+
+	s, err := storage.NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SavePool(ctx, &storage.Pool{Name: "prod", CIDRs: []string{"10.0.0.0/22"}, Metadata: storage.Metadata{Owner: "network"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, alloc := range []storage.Allocation{
+		{ID: "web", PoolName: "prod", AllocatedCIDR: "10.0.0.0/24", PrefixLength: 24},
+		{ID: "fw", PoolName: "prod", AllocatedCIDR: "10.0.2.0/23", PrefixLength: 23, Blocks: []string{"10.0.2.0/24", "10.0.3.0/24"}},
+	} {
+		if err := s.SaveAllocation(ctx, &alloc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestCommands(t *testing.T) {
+	path := testStorageFile(t)
+
+	tests := []struct {
+		run  func(ctx context.Context, args []string) error
+		args []string
+		want []string
+	}{
+		{runPools, []string{"list"}, []string{"prod", "10.0.0.0/22"}},
+		{runPools, []string{"list", "-o", "json"}, []string{`"name": "prod"`, `"allocations": 2`}},
+		{runPools, []string{"show", "prod", "-o", "yaml"}, []string{"name: prod", "owner: network", "allocated_cidr: 10.0.2.0/23"}},
+		{runAllocations, []string{"list", "--pool", "prod"}, []string{"web", "fw", "10.0.2.0/23"}},
+		{runAllocations, []string{"show", "fw"}, []string{"10.0.2.0/23", "Blocks:", "10.0.2.0/24, 10.0.3.0/24"}},
+		{runFree, []string{"--pool", "prod", "--prefix", "24"}, []string{"10.0.1.0/24"}},
+		{runFsck, nil, []string{"no issues found"}},
+		{runExport, []string{"-o", "json"}, []string{`"pools": [`, `"id": "web"`}},
+		{runExport, []string{"-o", "yaml"}, []string{"pools:", "id: web"}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		stdout = &out
+
+		args := append(tt.args, "--file-path", path)
+		if err := tt.run(context.Background(), args); err != nil {
+			t.Errorf("%v: %s", tt.args, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%v: expected output to contain %q, got:\n%s", tt.args, want, out.String())
+			}
+		}
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	path := testStorageFile(t)
+
+	tests := []struct {
+		run  func(ctx context.Context, args []string) error
+		args []string
+		want string
+	}{
+		{runPools, []string{"delete"}, `unknown pools command "delete"`},
+		{runPools, []string{"show", "dev"}, "dev"},
+		{runAllocations, []string{"show", "missing"}, "missing"},
+		{runFree, []string{"--pool", "prod"}, "usage: tfipam free"},
+		{runFree, []string{"--pool", "prod", "--prefix", "24", "--strategy", "worst_fit"}, `unknown strategy "worst_fit"`},
+		{runPools, []string{"list", "-o", "xml"}, `unknown output format "xml"`},
+	}
+	for _, tt := range tests {
+		stdout = &bytes.Buffer{}
+
+		args := append(tt.args, "--file-path", path)
+		err := tt.run(context.Background(), args)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: expected error containing %q, got %v", tt.args, tt.want, err)
+		}
+	}
+}
+
+func TestFreeLeasesAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	path := testStorageFile(t)
+
+	s, err := storage.NewFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, _ := s.GetPool(ctx, "prod")
+	pool.ReclaimExpired = true
+	pool.Leases = []storage.Lease{{ID: "run", CIDR: "10.0.1.0/24", ExpiresAt: time.Now().Add(time.Hour)}}
+	if err := s.SavePool(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	web, _ := s.GetAllocation(ctx, "web")
+	web.ExpiresAt = &past
+	if err := s.SaveAllocation(ctx, web); err != nil {
+		t.Fatal(err)
+	}
+
+	// the leased block is taken and the expired allocation's block is free, as for the provider
+	var out bytes.Buffer
+	stdout = &out
+	if err := runFree(ctx, []string{"--pool", "prod", "--prefix", "24", "-o", "json", "--file-path", path}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"10.0.0.0/24"`) || strings.Contains(out.String(), "10.0.1.0/24") {
+		t.Errorf("expected only 10.0.0.0/24 to be free, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+func runExport(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("export", "export [--file path] [flags]")
+	file := fs.String("file", "", "write the export to this file instead of stdout")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	snap, err := openSnapshot(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	out := stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch opts.output {
+	case "yaml":
+		return writeYAML(out, snap)
+	case "json", "table", "":
+		// a table can't hold the full export, so it falls back to json
+		return writeJSON(out, snap)
+	default:
+		return fmt.Errorf("unknown output format %q, expected json or yaml", opts.output)
+	}
+}
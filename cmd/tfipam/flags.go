@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+// options are the flags shared by every command that works with a single backend.
+type options struct {
+	storage *storage.Config
+	output  string
+}
+
+// newFlagSet creates the flag set for a command with the storage backend and output flags registered.
+func newFlagSet(name, usage string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	opts := &options{
+		storage: storageFlags(fs, ""),
+	}
+	fs.StringVar(&opts.output, "output", "table", "output format: table, json or yaml")
+	fs.StringVar(&opts.output, "o", "table", "shorthand for -output")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: tfipam %s\n\nStorage flags fall back to the matching TFIPAM_* environment variable, e.g. TFIPAM_S3_BUCKET_NAME.\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs, opts
+}
+
+// open creates the storage backend from the flags and TFIPAM_* environment variables.
+func (o *options) open(ctx context.Context) (storage.Storage, error) {
+	if err := o.storage.ApplyEnv(); err != nil {
+		return nil, err
+	}
+
+	s, err := storage.Factory(ctx, o.storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage backend: %w", err)
+	}
+	return s, nil
+}
+
+// parseArgs parses flags that may appear before or after positional arguments
+// and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// storageFlags registers the storage backend flags on fs and returns the
+// config they populate. Every flag name is prefixed with prefix so more than
+// one backend can be configured on the same command line.
+func storageFlags(fs *flag.FlagSet, prefix string) *storage.Config {
+	config := &storage.Config{}
+
+	fs.StringVar(&config.Type, prefix+"storage-type", "", "storage backend type: file, azure_blob or aws_s3 (default file)")
+	fs.StringVar(&config.FilePath, prefix+"file-path", "", "path to the storage file for the file backend (default .terraform/ipam-storage.json)")
+	fs.StringVar(&config.AzureConnectionString, prefix+"azure-connection-string", "", "connection string for Azure Blob Storage")
+	fs.StringVar(&config.AzureContainerName, prefix+"azure-container-name", "", "container name for Azure Blob Storage")
+	fs.StringVar(&config.AzureBlobName, prefix+"azure-blob-name", "", "blob name for Azure Blob Storage (default ipam-storage.json)")
+	fs.StringVar(&config.S3Region, prefix+"s3-region", "", "AWS region of the S3 bucket")
+	fs.StringVar(&config.S3BucketName, prefix+"s3-bucket-name", "", "S3 bucket name")
+	fs.StringVar(&config.S3ObjectKey, prefix+"s3-object-key", "", "S3 object key (default ipam-storage.json)")
+	fs.StringVar(&config.S3AccessKeyID, prefix+"s3-access-key-id", "", "AWS access key ID, uses the default credential chain if empty")
+	fs.StringVar(&config.S3SecretAccessKey, prefix+"s3-secret-access-key", "", "AWS secret access key")
+	fs.StringVar(&config.S3SessionToken, prefix+"s3-session-token", "", "AWS session token for temporary credentials")
+	fs.DurationVar(&config.CacheTTL, prefix+"cache-ttl", 0, "how long reads are served from memory for the remote backends")
+
+	return config
+}
+
+// tagFlag collects repeated --tag key=value flags.
+type tagFlag map[string]string
+
+func (t tagFlag) String() string {
+	pairs := make([]string, 0, len(t))
+	for key, value := range t {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (t tagFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("tag %q must be in the form key=value", value)
+	}
+	t[key] = val
+	return nil
+}
+
+// tagFlags registers the repeatable --tag flag on fs.
+func tagFlags(fs *flag.FlagSet, kind string) tagFlag {
+	tags := tagFlag{}
+	fs.Var(tags, "tag", fmt.Sprintf("only list %s with this tag, as key=value (repeatable)", kind))
+	return tags
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		pool       string
+	}{
+		{nil, nil, ""},
+		{[]string{"web"}, []string{"web"}, ""},
+		{[]string{"--pool", "prod", "web"}, []string{"web"}, "prod"},
+		{[]string{"web", "--pool", "prod"}, []string{"web"}, "prod"},
+		{[]string{"web", "-pool=prod", "db"}, []string{"web", "db"}, "prod"},
+		{[]string{"--", "-web"}, []string{"-web"}, ""},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		pool := fs.String("pool", "", "")
+
+		positional, err := parseArgs(fs, tt.args)
+		if err != nil {
+			t.Errorf("parseArgs(%v): %s", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(positional, tt.positional) || *pool != tt.pool {
+			t.Errorf("parseArgs(%v) = %v with pool %q, want %v with pool %q", tt.args, positional, *pool, tt.positional, tt.pool)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(nopWriter{})
+	if _, err := parseArgs(fs, []string{"web", "--unknown"}); err == nil {
+		t.Error("expected an unknown flag after a positional argument to fail")
+	}
+}
+
+func TestStorageFlags(t *testing.T) {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	from := storageFlags(fs, "from-")
+	to := storageFlags(fs, "to-")
+
+	err := fs.Parse([]string{
+		"--from-file-path", "ipam.json",
+		"--to-storage-type", "aws_s3", "--to-s3-region", "eu-west-1", "--to-s3-bucket-name", "ipam", "--to-cache-ttl", "30s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from.FilePath != "ipam.json" || from.Type != "" {
+		t.Errorf("unexpected source config %+v", from)
+	}
+	if to.Type != "aws_s3" || to.S3Region != "eu-west-1" || to.S3BucketName != "ipam" || to.CacheTTL.String() != "30s" || to.FilePath != "" {
+		t.Errorf("unexpected destination config %+v", to)
+	}
+}
+
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) { return len(p), nil }
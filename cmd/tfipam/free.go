@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+type freeBlocks struct {
+	Pool         string   `json:"pool"`
+	PrefixLength int      `json:"prefix_length"`
+	CIDRs        []string `json:"cidrs"`
+}
+
+func runFree(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("free", "free --pool name --prefix length [flags]")
+	poolName := fs.String("pool", "", "pool to search (required)")
+	prefixLength := fs.Int("prefix", -1, "prefix length of the free blocks to list (required)")
+	limit := fs.Int("limit", 10, "maximum number of free blocks to list")
+	strategy := fs.String("strategy", "", "allocation strategy to list the blocks in, defaults to the pool's strategy")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *poolName == "" || *prefixLength < 0 {
+		return errors.New("usage: tfipam free --pool name --prefix length [flags]")
+	}
+	if *strategy != "" && !slices.Contains(ipam.Strategies(), *strategy) {
+		return fmt.Errorf("unknown strategy %q, expected one of %v", *strategy, ipam.Strategies())
+	}
+
+	s, err := opts.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// the same pool space the provider allocates from, with every block found
+	// treated as taken
+	space, err := storage.LoadPoolSpace(ctx, s, *poolName, nil)
+	if err != nil {
+		return err
+	}
+	if *strategy == "" {
+		*strategy = space.Pool.AllocationStrategy
+	}
+	allocator := space.Allocator()
+
+	result := freeBlocks{Pool: space.Pool.Name, PrefixLength: *prefixLength, CIDRs: []string{}}
+	for len(result.CIDRs) < *limit {
+		candidate, ok := allocator.AllocateWith(*prefixLength, ipam.Strategy(*strategy))
+		if !ok {
+			break
+		}
+		result.CIDRs = append(result.CIDRs, candidate.String())
+	}
+
+	return render(opts.output, result, func(w io.Writer) {
+		if len(result.CIDRs) == 0 {
+			fmt.Fprintf(w, "No free /%d blocks in pool %s\n", result.PrefixLength, result.Pool)
+			return
+		}
+		fmt.Fprintln(w, "CIDR")
+		for _, cidr := range result.CIDRs {
+			fmt.Fprintln(w, cidr)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func runFsck(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("fsck", "fsck [flags]")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	snap, err := openSnapshot(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	issues := storage.Verify(snap)
+	if issues == nil {
+		issues = []storage.Issue{}
+	}
+
+	err = render(opts.output, issues, func(w io.Writer) {
+		if len(issues) == 0 {
+			fmt.Fprintf(w, "Checked %d pools, %d allocations and %d addresses, no issues found\n", len(snap.Pools), len(snap.Allocations), len(snap.Addresses))
+			return
+		}
+		fmt.Fprintln(w, "KIND\tSUBJECT\tISSUE")
+		for _, issue := range issues {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", issue.Kind, issue.Subject, issue.Message)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("found %d issue(s)", len(issues))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func runLookup(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("lookup", "lookup <ip-or-cidr> [flags]")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: tfipam lookup <ip-or-cidr> [flags]")
+	}
+
+	cidr, err := ipam.ParsePrefixOrAddr(positional[0])
+	if err != nil {
+		return err
+	}
+
+	snap, err := openSnapshot(ctx, opts)
+	if err != nil {
+		return err
+	}
+	result := storage.Lookup(snap, cidr)
+
+	return render(opts.output, result, func(w io.Writer) {
+		fmt.Fprintf(w, "CIDR:\t%s\n", result.CIDR)
+		fmt.Fprintf(w, "Status:\t%s\n", result.Status)
+		for _, pool := range result.Pools {
+			fmt.Fprintf(w, "Pool:\t%s\n", pool.Name)
+		}
+		if alloc := result.Allocation; alloc != nil {
+			fmt.Fprintf(w, "Allocation:\t%s\n", alloc.ID)
+			fmt.Fprintf(w, "Allocation Pool:\t%s\n", alloc.PoolName)
+			fmt.Fprintf(w, "Allocated CIDR:\t%s\n", alloc.AllocatedCIDR)
+			writeMetadata(w, alloc.Metadata)
+		}
+		if result.Address != nil {
+			fmt.Fprintf(w, "Address:\t%s\n", result.Address.ID)
+		}
+		if len(result.Overlapping) > 0 {
+			fmt.Fprintln(w)
+			writeAllocationTable(w, result.Overlapping)
+		}
+	})
+}
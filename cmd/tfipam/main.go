@@ -0,0 +1,69 @@
+// Command tfipam works with tfipam storage backends outside of Terraform.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: tfipam <command> [flags]
+
+Commands:
+  pools list                        List pools
+  pools show <name>                 Show a pool and its allocations
+  allocations list [--pool name]    List allocations
+  allocations show <id>             Show an allocation
+  free --pool name --prefix length  List free blocks of a prefix length in a pool
+  lookup <ip-or-cidr>               Show the pools and allocation holding an IP or CIDR
+  fsck                              Check the stored data for integrity problems
+  reclaim [--pool name]             Remove expired allocations and record them in the audit log
+  export                            Dump all pools and allocations
+  migrate                           Copy all pools and allocations from one storage backend to another
+
+Commands read the storage backend configuration from flags, falling back to
+the TFIPAM_* environment variables used by the provider. Output is a table by
+default, use -o json or -o yaml for machine readable output.
+
+Run "tfipam <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+
+	var err error
+	switch os.Args[1] {
+	case "pools":
+		err = runPools(ctx, os.Args[2:])
+	case "allocations":
+		err = runAllocations(ctx, os.Args[2:])
+	case "free":
+		err = runFree(ctx, os.Args[2:])
+	case "lookup":
+		err = runLookup(ctx, os.Args[2:])
+	case "fsck":
+		err = runFsck(ctx, os.Args[2:])
+	case "reclaim":
+		err = runReclaim(ctx, os.Args[2:])
+	case "export":
+		err = runExport(ctx, os.Args[2:])
+	case "migrate":
+		err = runMigrate(ctx, os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func runMigrate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := storageFlags(fs, "from-")
+	to := storageFlags(fs, "to-")
+	dryRun := fs.Bool("dry-run", false, "only show the changes the migration would make")
+	overwrite := fs.Bool("overwrite", false, "migrate into a destination that already holds data, replacing pools and allocations that differ")
+	verbose := fs.Bool("verbose", false, "also list pools and allocations that are unchanged")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: tfipam migrate [flags]\n\nCopies all pools and allocations from the --from-* backend to the --to-* backend.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	result, err := storage.MigrateConfig(ctx, from, to, storage.MigrateOptions{
+		DryRun:    *dryRun,
+		Overwrite: *overwrite,
+	})
+	if result != nil {
+		printMigrationResult(result, *verbose)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *dryRun:
+		fmt.Println("\nDry run, no changes were written.")
+	case result.Verified:
+		fmt.Println("\nMigration complete, destination verified.")
+	}
+
+	return nil
+}
+
+func printMigrationResult(result *storage.MigrationResult, verbose bool) {
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tKIND\tKEY")
+	for _, change := range result.Changes {
+		if change.Action == storage.ChangeUnchanged && !verbose {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", change.Action, change.Kind, change.Key)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d to create, %d to update, %d unchanged, %d conflicting, %d audit record(s) to copy\n",
+		result.Count(storage.ChangeCreate), result.Count(storage.ChangeUpdate),
+		result.Count(storage.ChangeUnchanged), result.Count(storage.ChangeConflict),
+		result.AuditRecords)
+
+	for _, issue := range result.Issues {
+		fmt.Fprintf(os.Stderr, "validation: %s\n", issue)
+	}
+}
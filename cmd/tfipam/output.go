@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// stdout is where commands write their output.
+var stdout io.Writer = os.Stdout
+
+// render writes v to stdout in the requested format. The table function
+// renders the human readable form and is only called for the table format.
+func render(format string, v any, table func(w io.Writer)) error {
+	switch format {
+	case "table", "":
+		if table == nil {
+			return fmt.Errorf("table output is not supported by this command, use json or yaml")
+		}
+		w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	case "json":
+		return writeJSON(stdout, v)
+	case "yaml":
+		return writeYAML(stdout, v)
+	default:
+		return fmt.Errorf("unknown output format %q, expected table, json or yaml", format)
+	}
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeYAML goes through JSON first so the YAML keys match the json tags of the storage types.
+func writeYAML(w io.Writer, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestRender(t *testing.T) {
+	pool := storage.Pool{
+		Name:     "prod",
+		CIDRs:    []string{"10.0.0.0/16"},
+		Metadata: storage.Metadata{Owner: "network"},
+	}
+	table := func(w io.Writer) {
+		fmt.Fprintf(w, "NAME\tCIDRS\n%s\t%s\n", pool.Name, pool.CIDRs[0])
+	}
+
+	tests := []struct {
+		format  string
+		table   func(w io.Writer)
+		want    []string
+		wantErr string
+	}{
+		{format: "table", table: table, want: []string{"NAME  CIDRS", "prod  10.0.0.0/16"}},
+		{format: "", table: table, want: []string{"prod  10.0.0.0/16"}},
+		{format: "json", table: table, want: []string{`"name": "prod"`, `"owner": "network"`, `"cidrs": [`}},
+		{format: "yaml", want: []string{"name: prod", "owner: network", "- 10.0.0.0/16"}},
+		{format: "table", wantErr: "table output is not supported"},
+		{format: "xml", table: table, wantErr: `unknown output format "xml"`},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		stdout = &out
+
+		err := render(tt.format, pool, tt.table)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("render(%q): expected error containing %q, got %v", tt.format, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("render(%q): %s", tt.format, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("render(%q): expected output to contain %q, got:\n%s", tt.format, want, out.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+type poolSummary struct {
+	storage.Pool
+	Allocations int `json:"allocations"`
+}
+
+type poolDetail struct {
+	storage.Pool
+	ChildPools         []string             `json:"child_pools"`
+	TotalAddresses     string               `json:"total_addresses"`
+	AllocatedAddresses string               `json:"allocated_addresses"`
+	Allocations        []storage.Allocation `json:"allocations"`
+}
+
+func runPools(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: tfipam pools <list|show> [flags]")
+	}
+
+	switch args[0] {
+	case "list":
+		return runPoolsList(ctx, args[1:])
+	case "show":
+		return runPoolsShow(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown pools command %q, expected list or show", args[0])
+	}
+}
+
+func runPoolsList(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("pools list", "pools list [--tag key=value] [flags]")
+	tags := tagFlags(fs, "pools")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	snap, err := openSnapshot(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, alloc := range snap.Allocations {
+		counts[alloc.PoolName]++
+	}
+
+	pools := make([]poolSummary, 0, len(snap.Pools))
+	for _, pool := range snap.Pools {
+		if pool.HasTags(tags) {
+			pools = append(pools, poolSummary{Pool: pool, Allocations: counts[pool.Name]})
+		}
+	}
+
+	return render(opts.output, pools, func(w io.Writer) {
+		fmt.Fprintln(w, "NAME\tCIDRS\tPARENT\tALLOCATIONS")
+		for _, pool := range pools {
+			parent := pool.ParentPool
+			if parent == "" {
+				parent = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", pool.Name, strings.Join(pool.CIDRs, ","), parent, pool.Allocations)
+		}
+	})
+}
+
+func runPoolsShow(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("pools show", "pools show <name> [flags]")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: tfipam pools show <name> [flags]")
+	}
+
+	snap, err := openSnapshot(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	var pool *storage.Pool
+	for i := range snap.Pools {
+		if snap.Pools[i].Name == positional[0] {
+			pool = &snap.Pools[i]
+		}
+	}
+	if pool == nil {
+		return fmt.Errorf("pool %s: %w", positional[0], storage.ErrNotFound)
+	}
+
+	allocations := []storage.Allocation{}
+	for _, alloc := range snap.Allocations {
+		if alloc.PoolName == pool.Name {
+			allocations = append(allocations, alloc)
+		}
+	}
+	sortAllocations(allocations)
+
+	children := []string{}
+	for _, child := range storage.ChildPools(snap.Pools, pool.Name) {
+		children = append(children, child.Name)
+	}
+
+	// allocated addresses roll up from the pools below this one
+	var allocated []string
+	for _, alloc := range storage.SubtreeAllocations(snap, pool.Name) {
+		allocated = append(allocated, alloc.AllocatedCIDR)
+	}
+
+	detail := poolDetail{
+		Pool:               *pool,
+		ChildPools:         children,
+		TotalAddresses:     ipam.AddressCount(ipam.ParseCIDRs(pool.CIDRs)).String(),
+		AllocatedAddresses: ipam.AddressCount(ipam.ParseCIDRs(allocated)).String(),
+		Allocations:        allocations,
+	}
+	return render(opts.output, detail, func(w io.Writer) {
+		fmt.Fprintf(w, "Name:\t%s\n", pool.Name)
+		fmt.Fprintf(w, "CIDRs:\t%s\n", strings.Join(pool.CIDRs, ", "))
+		writeMetadata(w, pool.Metadata)
+		if pool.ParentPool != "" {
+			fmt.Fprintf(w, "Parent:\t%s\n", pool.ParentPool)
+		}
+		if len(children) > 0 {
+			fmt.Fprintf(w, "Children:\t%s\n", strings.Join(children, ", "))
+		}
+		fmt.Fprintf(w, "Addresses:\t%s allocated of %s\n", detail.AllocatedAddresses, detail.TotalAddresses)
+		if len(pool.ReservedCIDRs) > 0 {
+			fmt.Fprintf(w, "Reserved:\t%s\n", strings.Join(pool.ReservedCIDRs, ", "))
+		}
+		fmt.Fprintf(w, "Allocations:\t%d\n", len(allocations))
+		if len(allocations) > 0 {
+			fmt.Fprintln(w)
+			writeAllocationTable(w, allocations)
+		}
+	})
+}
+
+// openSnapshot reads everything from the configured backend.
+func openSnapshot(ctx context.Context, opts *options) (*storage.Snapshot, error) {
+	s, err := opts.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	return storage.TakeSnapshot(ctx, s)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+// reclaimResult is the output of reclaim.
+type reclaimResult struct {
+	DryRun      bool                 `json:"dry_run"`
+	Allocations []storage.Allocation `json:"allocations"`
+}
+
+func runReclaim(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("reclaim", "reclaim [--pool name] [--dry-run] [flags]")
+	poolName := fs.String("pool", "", "only reclaim expired allocations from this pool")
+	dryRun := fs.Bool("dry-run", false, "only list the expired allocations without removing them")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	s, err := opts.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	expired, err := storage.ReclaimExpired(ctx, s, storage.ReclaimOptions{
+		PoolName: *poolName,
+		DryRun:   *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	result := reclaimResult{DryRun: *dryRun, Allocations: expired}
+	if result.Allocations == nil {
+		result.Allocations = []storage.Allocation{}
+	}
+	return render(opts.output, result, func(w io.Writer) {
+		if len(expired) == 0 {
+			fmt.Fprintln(w, "No expired allocations")
+			return
+		}
+		fmt.Fprintln(w, "ID\tPOOL\tCIDR\tEXPIRED AT")
+		for _, alloc := range expired {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", alloc.ID, alloc.PoolName, alloc.AllocatedCIDR, alloc.ExpiresAt.UTC().Format(time.RFC3339))
+		}
+		if *dryRun {
+			fmt.Fprintf(w, "\nDry run, %d expired allocation(s) were not removed.\n", len(expired))
+		} else {
+			fmt.Fprintf(w, "\nReclaimed %d expired allocation(s).\n", len(expired))
+		}
+	})
+}
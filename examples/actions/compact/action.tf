@@ -0,0 +1,5 @@
+action "tfipam_compact" "storage" {
+  config {
+    dry_run = true
+  }
+}
@@ -0,0 +1,10 @@
+action "tfipam_migrate_storage" "to_s3" {
+  config {
+    destination = {
+      storage_type   = "aws_s3"
+      s3_region      = "us-east-1"
+      s3_bucket_name = "my-tfipam-bucket"
+    }
+    dry_run = true
+  }
+}
@@ -0,0 +1,5 @@
+action "tfipam_reclaim_expired" "previews" {
+  config {
+    pool_name = "previews"
+  }
+}
@@ -0,0 +1,6 @@
+action "tfipam_snapshot" "nightly" {
+  config {
+    path   = "backups/ipam-${formatdate("YYYY-MM-DD", timestamp())}.json"
+    verify = true
+  }
+}
@@ -0,0 +1 @@
+action "tfipam_verify" "storage" {}
@@ -0,0 +1,4 @@
+data "tfipam_allocations" "example" {
+  pool_name     = "pool_example"
+  prefix_length = 24
+}
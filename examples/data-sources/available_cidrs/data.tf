@@ -0,0 +1,5 @@
+data "tfipam_available_cidrs" "example" {
+  pool_name     = "pool_example"
+  prefix_length = 24
+  limit         = 5
+}
@@ -0,0 +1,7 @@
+data "tfipam_lookup" "incident" {
+  query = "10.0.4.17"
+}
+
+output "incident_owner" {
+  value = try(data.tfipam_lookup.incident.allocation.owner, null)
+}
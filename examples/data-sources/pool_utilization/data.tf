@@ -0,0 +1,7 @@
+data "tfipam_pool_utilization" "example" {
+  name = "pool_example"
+}
+
+output "pool_example_utilization" {
+  value = data.tfipam_pool_utilization.example.utilization_percent
+}
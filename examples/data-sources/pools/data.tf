@@ -0,0 +1,6 @@
+data "tfipam_pools" "prod" {
+  address_family = "ipv4"
+  tags = {
+    env = "prod"
+  }
+}
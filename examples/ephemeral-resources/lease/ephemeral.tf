@@ -0,0 +1,5 @@
+ephemeral "tfipam_lease" "example" {
+  pool_name     = "pool_example"
+  prefix_length = 24
+  ttl           = "1h"
+}
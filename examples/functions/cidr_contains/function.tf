@@ -0,0 +1,7 @@
+output "contains_address" {
+  value = provider::tfipam::cidr_contains("10.0.0.0/24", "10.0.0.10") # true
+}
+
+output "contains_cidr" {
+  value = provider::tfipam::cidr_contains("fd00::/48", "fd00:0:0:1::/64") # true
+}
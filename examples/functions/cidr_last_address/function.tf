@@ -0,0 +1,3 @@
+output "broadcast" {
+  value = provider::tfipam::cidr_last_address("10.0.0.0/24") # "10.0.0.255"
+}
@@ -0,0 +1,3 @@
+output "overlaps" {
+  value = provider::tfipam::cidr_overlaps("10.0.0.0/16", "10.0.128.0/17") # true
+}
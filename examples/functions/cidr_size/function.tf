@@ -0,0 +1,3 @@
+output "size" {
+  value = provider::tfipam::cidr_size("10.0.0.0/24") # 256
+}
@@ -0,0 +1,4 @@
+output "summary" {
+  # ["10.0.0.0/23", "fd00::/63"]
+  value = provider::tfipam::cidr_summarize(["10.0.1.0/24", "fd00::/64", "10.0.0.0/24", "fd00:0:0:1::/64"])
+}
@@ -0,0 +1,4 @@
+output "cidrs" {
+  # ["10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"]
+  value = provider::tfipam::range_to_cidrs("10.0.0.1", "10.0.0.6")
+}
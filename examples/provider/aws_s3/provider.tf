@@ -9,21 +9,23 @@ terraform {
 
 # Example 1: Using explicit AWS credentials
 provider "tfipam" {
-  storage_type         = "aws_s3"
-  s3_region            = "us-east-1"
-  s3_bucket_name       = "my-tfipam-bucket"
-  s3_object_key        = "ipam-storage.json" # Optional: defaults to "ipam-storage.json"
-  s3_access_key_id     = "AKIAABCDEFGHEXAMPLE"
-  s3_secret_access_key = "ACCESSKEYEXAMPLE1234567890"
-  # s3_session_token    = "token"              # Optional: for temporary credentials
+  s3 {
+    region            = "us-east-1"
+    bucket_name       = "my-tfipam-bucket"
+    object_key        = "ipam-storage.json" # Optional: defaults to "ipam-storage.json"
+    access_key_id     = "AKIAABCDEFGHEXAMPLE"
+    secret_access_key = "ACCESSKEYEXAMPLE1234567890"
+    # session_token    = "token"              # Optional: for temporary credentials
+  }
 }
 
 # Example 2: Using default AWS credential chain (IAM role, env vars, ~/.aws/credentials)
 # provider "tfipam" {
-#   storage_type   = "aws_s3"
-#   s3_region      = "us-east-1"
-#   s3_bucket_name = "my-tfipam-bucket"
-#   s3_object_key  = "ipam-storage.json"
+#   s3 {
+#     region      = "us-east-1"
+#     bucket_name = "my-tfipam-bucket"
+#     object_key  = "ipam-storage.json"
+#   }
 #   # Credentials will be loaded from:
 #   # 1. Environment variables (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY)
 #   # 2. Shared credentials file (~/.aws/credentials)
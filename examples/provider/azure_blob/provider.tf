@@ -8,10 +8,11 @@ terraform {
 }
 
 provider "tfipam" {
-  storage_type            = "azure_blob"
-  azure_connection_string = "DefaultEndpointsProtocol=https;AccountName=myaccount;AccountKey=mykey;EndpointSuffix=core.windows.net"
-  azure_container_name    = "tfipam"
-  azure_blob_name         = "ipam-storage.json" # Optional: defaults to "ipam-storage.json"
+  azure_blob {
+    connection_string = "DefaultEndpointsProtocol=https;AccountName=myaccount;AccountKey=mykey;EndpointSuffix=core.windows.net"
+    container_name    = "tfipam"
+    blob_name         = "ipam-storage.json" # Optional: defaults to "ipam-storage.json"
+  }
 }
 
 resource "tfipam_pool" "example" {
@@ -8,8 +8,9 @@ terraform {
 }
 
 provider "tfipam" {
-  storage_type = "file"
-  file_path    = "ipam_storage_example.json"
+  file {
+    path = "ipam_storage_example.json"
+  }
 }
 
 resource "tfipam_pool" "example" {
@@ -0,0 +1 @@
+terraform import tfipam_address.web address_web
@@ -0,0 +1,33 @@
+terraform {
+  required_providers {
+    tfipam = {
+      source  = "cthiel42/tfipam"
+      version = "1.1.0"
+    }
+  }
+}
+
+provider "tfipam" {}
+
+resource "tfipam_pool" "example" {
+  name             = "pool_example"
+  cidrs            = ["10.0.0.0/16"]
+  reserved_offsets = [2, 3]
+}
+
+resource "tfipam_allocation" "example" {
+  id            = "allocation_example"
+  pool_name     = tfipam_pool.example.name
+  prefix_length = 24
+}
+
+resource "tfipam_address" "web" {
+  id            = "address_web"
+  allocation_id = tfipam_allocation.example.id
+}
+
+resource "tfipam_address" "dns" {
+  id                = "address_dns"
+  allocation_id     = tfipam_allocation.example.id
+  requested_address = cidrhost(tfipam_allocation.example.allocated_cidr, 53)
+}
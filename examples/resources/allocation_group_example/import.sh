@@ -0,0 +1 @@
+terraform import tfipam_allocation_group.firewall firewall
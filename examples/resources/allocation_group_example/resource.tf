@@ -0,0 +1,12 @@
+resource "tfipam_allocation_group" "firewall" {
+  id             = "firewall"
+  pool_name      = tfipam_pool.example.name
+  prefix_lengths = [24, 24, 24, 24]
+}
+
+resource "tfipam_allocation_group" "vpc" {
+  id                    = "vpc-a"
+  pool_name             = tfipam_pool.example.name
+  prefix_lengths        = [24, 25, 26]
+  summary_prefix_length = 22
+}
@@ -0,0 +1 @@
+terraform import tfipam_allocation_set.nodes k8s-nodes
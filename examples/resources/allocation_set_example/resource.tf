@@ -0,0 +1,13 @@
+resource "tfipam_allocation_set" "nodes" {
+  id               = "k8s-nodes"
+  pool_name        = tfipam_pool.example.name
+  prefix_length    = 26
+  allocation_count = 64
+}
+
+resource "tfipam_allocation_set" "workers" {
+  id            = "workers"
+  pool_name     = tfipam_pool.example.name
+  prefix_length = 28
+  keys          = ["worker-a", "worker-b", "worker-c"]
+}
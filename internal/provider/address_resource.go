@@ -0,0 +1,371 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ resource.Resource = &AddressResource{}
+var _ resource.ResourceWithImportState = &AddressResource{}
+var _ resource.ResourceWithValidateConfig = &AddressResource{}
+
+func NewAddressResource() resource.Resource {
+	return &AddressResource{}
+}
+
+type AddressResource struct {
+	provider *IpamProvider
+}
+
+type AddressResourceModel struct {
+	ID               types.String `tfsdk:"id"`
+	AllocationID     types.String `tfsdk:"allocation_id"`
+	RequestedAddress types.String `tfsdk:"requested_address"`
+	Address          types.String `tfsdk:"address"`
+}
+
+func (r *AddressResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_address"
+}
+
+func (r *AddressResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "IPAM address resource for allocating a single host address from an allocation",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Unique identifier for this address",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"allocation_id": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "ID of the allocation to take the address from",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"requested_address": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Claim exactly this address instead of taking the lowest free one. It must be a usable address of the allocation that is neither reserved nor held by another address.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIf(requestedAddressRequiresReplace,
+						"Requesting a different address replaces the address.",
+						"Requesting a different address replaces the address."),
+				},
+			},
+			"address": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The allocated host address",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *AddressResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.provider = provider
+}
+
+func (r *AddressResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data AddressResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.RequestedAddress.IsNull() || data.RequestedAddress.IsUnknown() {
+		return
+	}
+
+	if _, err := netip.ParseAddr(data.RequestedAddress.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("requested_address"),
+			"Invalid Requested Address",
+			fmt.Sprintf("Address '%s' is not valid: %s", data.RequestedAddress.ValueString(), err),
+		)
+	}
+}
+
+func (r *AddressResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data AddressResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	allocationID := data.AllocationID.ValueString()
+
+	r.provider.addressMu.Lock()
+	defer r.provider.addressMu.Unlock()
+
+	allocation, err := r.provider.storage.GetAllocation(ctx, allocationID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("allocation_id"),
+				"Allocation Not Found",
+				fmt.Sprintf("Allocation %s does not exist in storage", allocationID),
+			)
+			return
+		}
+		resp.Diagnostics.AddError(
+			"Failed to Read Allocation",
+			fmt.Sprintf("Could not read allocation from storage: %s", err),
+		)
+		return
+	}
+
+	addr, err := r.pickAddress(ctx, allocation, data.ID.ValueString(), data.RequestedAddress.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Address Allocation Failed",
+			fmt.Sprintf("Unable to allocate an address from allocation %s: %s", allocationID, err),
+		)
+		return
+	}
+
+	address := &storage.Address{
+		ID:           data.ID.ValueString(),
+		AllocationID: allocationID,
+		Address:      addr.String(),
+	}
+	if err := r.provider.storage.SaveAddress(ctx, address); err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Save Address",
+			fmt.Sprintf("Could not save address to storage: %s", err),
+		)
+		return
+	}
+
+	data.Address = types.StringValue(address.Address)
+
+	tflog.Trace(ctx, "created address resource", map[string]any{
+		"id":            address.ID,
+		"allocation_id": allocationID,
+		"address":       address.Address,
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *AddressResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data AddressResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	address, err := r.provider.storage.GetAddress(ctx, data.ID.ValueString())
+	if err != nil {
+		if err == storage.ErrNotFound {
+			// address was deleted outside Terraform
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError(
+			"Failed to Read Address",
+			fmt.Sprintf("Could not read address from storage: %s", err),
+		)
+		return
+	}
+
+	// sync state with storage data
+	data.AllocationID = types.StringValue(address.AllocationID)
+	data.Address = types.StringValue(address.Address)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *AddressResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// Only requested_address can change in place, when it is removed or set to the held address
+	var data AddressResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *AddressResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data AddressResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.provider.storage.DeleteAddress(ctx, data.ID.ValueString()); err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Delete Address",
+			fmt.Sprintf("Could not delete address from storage: %s", err),
+		)
+		return
+	}
+
+	tflog.Trace(ctx, "deleted address resource", map[string]any{
+		"id":            data.ID.ValueString(),
+		"allocation_id": data.AllocationID.ValueString(),
+	})
+}
+
+func (r *AddressResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// For import we expect the ID to be the address ID
+	address, err := r.provider.storage.GetAddress(ctx, req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Address Not Found",
+			fmt.Sprintf("Address %s not found in storage: %s", req.ID, err),
+		)
+		return
+	}
+
+	data := AddressResourceModel{
+		ID:               types.StringValue(address.ID),
+		AllocationID:     types.StringValue(address.AllocationID),
+		RequestedAddress: types.StringNull(),
+		Address:          types.StringValue(address.Address),
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// pickAddress returns the requested address, or the lowest free one when nothing
+// is requested. The network address, the broadcast address, the gateway and the
+// pool's reserved offsets are never handed out, and neither are addresses held
+// by other addresses of the allocation. Addresses of a group allocation come from
+// its blocks, each of which is a network of its own, and never from the padding
+// of the summary CIDR.
+func (r *AddressResource) pickAddress(ctx context.Context, allocation *storage.Allocation, addressID string, requested string) (netip.Addr, error) {
+	cidrs := allocation.Blocks
+	if len(cidrs) == 0 {
+		cidrs = []string{allocation.AllocatedCIDR}
+	}
+	networks := make([]ipam.Network, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("allocated CIDR %s is not valid: %w", cidr, err)
+		}
+		networks = append(networks, ipam.DescribeNetwork(prefix))
+	}
+	// blocks are stored largest first, the lowest free address is searched by address
+	slices.SortFunc(networks, func(a, b ipam.Network) int {
+		return a.Prefix.Addr().Compare(b.Prefix.Addr())
+	})
+	described := strings.Join(cidrs, ", ")
+
+	// the pool of an allocation may be gone, which leaves the default gateway
+	pool, err := r.provider.storage.GetPool(ctx, allocation.PoolName)
+	if errors.Is(err, storage.ErrNotFound) {
+		pool = nil
+	} else if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to read pool %s: %w", allocation.PoolName, err)
+	}
+
+	// taken maps unavailable addresses to what holds them
+	taken := map[netip.Addr]string{}
+	offsets := []int64{gatewayOffset(pool)}
+	if pool != nil {
+		offsets = append(offsets, pool.ReservedOffsets...)
+	}
+	for _, network := range networks {
+		for i, offset := range offsets {
+			if addr, ok := network.UsableAt(offset); ok {
+				if i == 0 {
+					taken[addr] = "the gateway"
+				} else {
+					taken[addr] = fmt.Sprintf("reserved offset %d", offset)
+				}
+			}
+		}
+	}
+
+	addresses, err := r.provider.storage.ListAddressesByAllocation(ctx, allocation.ID)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("failed to list addresses: %w", err)
+	}
+	for _, address := range addresses {
+		if address.ID == addressID {
+			continue
+		}
+		if addr, err := netip.ParseAddr(address.Address); err == nil {
+			taken[addr] = fmt.Sprintf("address %s", address.ID)
+		}
+	}
+
+	if requested != "" {
+		addr, err := netip.ParseAddr(requested)
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("requested address %s is not valid: %w", requested, err)
+		}
+		if !slices.ContainsFunc(networks, func(n ipam.Network) bool { return n.Usable(addr) }) {
+			return netip.Addr{}, fmt.Errorf("requested address %s is not a usable address of %s", addr, described)
+		}
+		if holder, exists := taken[addr]; exists {
+			return netip.Addr{}, fmt.Errorf("requested address %s is held by %s", addr, holder)
+		}
+		return addr, nil
+	}
+
+	unavailable := make(map[netip.Addr]bool, len(taken))
+	for addr := range taken {
+		unavailable[addr] = true
+	}
+	for _, network := range networks {
+		if addr, ok := network.FirstFree(unavailable); ok {
+			return addr, nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("no free addresses left in %s", described)
+}
+
+// requestedAddressRequiresReplace replaces the address when requested_address changes,
+// unless it is removed or now requests the address already held, in any notation.
+func requestedAddressRequiresReplace(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+	var address types.String
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("address"), &address)...)
+
+	if req.PlanValue.IsNull() {
+		return
+	}
+	requested, err := netip.ParseAddr(req.PlanValue.ValueString())
+	resp.RequiresReplace = err != nil || requested.String() != address.ValueString()
+}
@@ -0,0 +1,293 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	tfresource "github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestAccAddressResource_Basic(t *testing.T) {
+	tfresource.Test(t, tfresource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []tfresource.TestStep{
+			// the gateway (.1) and reserved offset 2 are skipped
+			{
+				Config: testAccAddressResourceConfig("address-pool", "") + `
+resource "tfipam_address" "second" {
+  id            = "address-second"
+  allocation_id = tfipam_allocation.test.id
+  depends_on    = [tfipam_address.test]
+}
+`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_address.test", tfjsonpath.New("address"), knownvalue.StringExact("10.0.1.3")),
+					statecheck.ExpectKnownValue("tfipam_address.second", tfjsonpath.New("address"), knownvalue.StringExact("10.0.1.4")),
+				},
+			},
+			{
+				ResourceName:      "tfipam_address.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateId:     "address-test",
+			},
+		},
+	})
+}
+
+func TestAccAddressResource_RequestedAddress(t *testing.T) {
+	tfresource.Test(t, tfresource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []tfresource.TestStep{
+			{
+				Config: testAccAddressResourceConfig("address-requested-pool", "10.0.1.10"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_address.test", tfjsonpath.New("address"), knownvalue.StringExact("10.0.1.10")),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAddressResource_RequestedAddressUnusable(t *testing.T) {
+	tfresource.Test(t, tfresource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []tfresource.TestStep{
+			{
+				Config:      testAccAddressResourceConfig("address-broadcast-pool", "10.0.1.255"),
+				ExpectError: regexp.MustCompile(`not a usable address`),
+			},
+		},
+	})
+}
+
+func TestAccAddressResource_RequestedAddressReserved(t *testing.T) {
+	tfresource.Test(t, tfresource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []tfresource.TestStep{
+			{
+				Config:      testAccAddressResourceConfig("address-reserved-pool", "10.0.1.2"),
+				ExpectError: regexp.MustCompile(`held by reserved offset 2`),
+			},
+		},
+	})
+}
+
+func TestAccAddressResource_InvalidRequestedAddress(t *testing.T) {
+	tfresource.Test(t, tfresource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []tfresource.TestStep{
+			{
+				Config:      testAccAddressResourceConfig("address-invalid-pool", "10.0.1"),
+				ExpectError: regexp.MustCompile(`Invalid Requested Address`),
+			},
+		},
+	})
+}
+
+func testAccAddressResourceConfig(poolName, requestedAddress string) string {
+	requested := ""
+	if requestedAddress != "" {
+		requested = fmt.Sprintf("requested_address = %q", requestedAddress)
+	}
+
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name             = %[1]q
+  cidrs            = ["10.0.1.0/24"]
+  reserved_offsets = [2]
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "%[1]s-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+}
+
+resource "tfipam_address" "test" {
+  id            = "address-test"
+  allocation_id = tfipam_allocation.test.id
+  %[2]s
+}
+`, poolName, requested)
+}
+
+func TestPickAddress(t *testing.T) {
+	ctx := context.Background()
+	offset := int64(-1)
+	p := testPoolSpaceProvider(t, &storage.Pool{
+		Name:            "pool",
+		CIDRs:           []string{"10.0.0.0/29"},
+		GatewayOffset:   &offset,
+		ReservedOffsets: []int64{1, 3},
+	})
+	r := &AddressResource{provider: p}
+	allocation := &storage.Allocation{ID: "alloc", PoolName: "pool", AllocatedCIDR: "10.0.0.0/29", PrefixLength: 29}
+
+	// .1 and .3 are reserved and .6 is the gateway, which leaves .2, .4 and .5
+	for _, want := range []string{"10.0.0.2", "10.0.0.4", "10.0.0.5"} {
+		id := "addr-" + want
+		got, err := r.pickAddress(ctx, allocation, id, "")
+		if err != nil || got.String() != want {
+			t.Fatalf("expected %s, got %s (%v)", want, got, err)
+		}
+		if err := p.storage.SaveAddress(ctx, &storage.Address{ID: id, AllocationID: "alloc", Address: got.String()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := r.pickAddress(ctx, allocation, "full", ""); err == nil {
+		t.Fatal("expected the allocation to be full")
+	}
+
+	// an address keeps its own address when picked again
+	if got, err := r.pickAddress(ctx, allocation, "addr-10.0.0.4", "10.0.0.4"); err != nil || got.String() != "10.0.0.4" {
+		t.Fatalf("expected 10.0.0.4, got %s (%v)", got, err)
+	}
+
+	for requested, message := range map[string]string{
+		"10.0.0.0": "requested address 10.0.0.0 is not a usable address of 10.0.0.0/29",
+		"10.0.0.7": "requested address 10.0.0.7 is not a usable address of 10.0.0.0/29",
+		"10.0.0.6": "requested address 10.0.0.6 is held by the gateway",
+		"10.0.0.3": "requested address 10.0.0.3 is held by reserved offset 3",
+		"10.0.0.5": "requested address 10.0.0.5 is held by address addr-10.0.0.5",
+	} {
+		if _, err := r.pickAddress(ctx, allocation, "other", requested); err == nil || err.Error() != message {
+			t.Errorf("expected %q, got %v", message, err)
+		}
+	}
+}
+
+func TestPickAddress_Group(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/24"}})
+	r := &AddressResource{provider: p}
+	// blocks are stored largest first, 10.0.0.4/30 is padding of the summary
+	allocation := &storage.Allocation{
+		ID:            "group",
+		PoolName:      "pool",
+		AllocatedCIDR: "10.0.0.0/28",
+		PrefixLength:  28,
+		Blocks:        []string{"10.0.0.8/29", "10.0.0.0/30"},
+	}
+
+	// every block keeps its network, broadcast and gateway addresses
+	for _, want := range []string{"10.0.0.2", "10.0.0.10", "10.0.0.11", "10.0.0.12", "10.0.0.13", "10.0.0.14"} {
+		id := "addr-" + want
+		got, err := r.pickAddress(ctx, allocation, id, "")
+		if err != nil || got.String() != want {
+			t.Fatalf("expected %s, got %s (%v)", want, got, err)
+		}
+		if err := p.storage.SaveAddress(ctx, &storage.Address{ID: id, AllocationID: "group", Address: got.String()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := r.pickAddress(ctx, allocation, "full", ""); err == nil || err.Error() != "no free addresses left in 10.0.0.8/29, 10.0.0.0/30" {
+		t.Fatalf("expected the group to be full, got %v", err)
+	}
+
+	for requested, message := range map[string]string{
+		"10.0.0.5":  "requested address 10.0.0.5 is not a usable address of 10.0.0.8/29, 10.0.0.0/30",
+		"10.0.0.3":  "requested address 10.0.0.3 is not a usable address of 10.0.0.8/29, 10.0.0.0/30",
+		"10.0.0.8":  "requested address 10.0.0.8 is not a usable address of 10.0.0.8/29, 10.0.0.0/30",
+		"10.0.0.9":  "requested address 10.0.0.9 is held by the gateway",
+		"10.0.0.14": "requested address 10.0.0.14 is held by address addr-10.0.0.14",
+	} {
+		if _, err := r.pickAddress(ctx, allocation, "other", requested); err == nil || err.Error() != message {
+			t.Errorf("expected %q, got %v", message, err)
+		}
+	}
+}
+
+// poolErrorStorage fails to read pools with err.
+type poolErrorStorage struct {
+	storage.Storage
+	err error
+}
+
+func (s poolErrorStorage) GetPool(ctx context.Context, name string) (*storage.Pool, error) {
+	return nil, s.err
+}
+
+func TestPickAddress_PoolErrors(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/29"}})
+	allocation := &storage.Allocation{ID: "alloc", PoolName: "pool", AllocatedCIDR: "10.0.0.0/29", PrefixLength: 29}
+
+	// a missing pool leaves the default gateway
+	r := &AddressResource{provider: &IpamProvider{storage: poolErrorStorage{p.storage, storage.ErrNotFound}}}
+	if got, err := r.pickAddress(ctx, allocation, "addr", ""); err != nil || got.String() != "10.0.0.2" {
+		t.Fatalf("expected 10.0.0.2, got %s (%v)", got, err)
+	}
+
+	// other errors are not mistaken for a missing pool
+	failure := errors.New("storage unavailable")
+	r = &AddressResource{provider: &IpamProvider{storage: poolErrorStorage{p.storage, failure}}}
+	if _, err := r.pickAddress(ctx, allocation, "addr", ""); !errors.Is(err, failure) {
+		t.Fatalf("expected the storage error, got %v", err)
+	}
+}
+
+func TestAllocationResource_DeleteWithAddresses(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/24"}})
+	if err := p.storage.SaveAllocation(ctx, &storage.Allocation{ID: "alloc", PoolName: "pool", AllocatedCIDR: "10.0.0.0/28", PrefixLength: 28}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.storage.SaveAddress(ctx, &storage.Address{ID: "addr", AllocationID: "alloc", Address: "10.0.0.2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &AllocationResource{provider: p}
+	var schemaResp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResp)
+
+	state := tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil)}
+	metadata, _ := newMetadataModel(ctx, storage.Metadata{})
+	model := AllocationResourceModel{
+		ID:                  types.StringValue("alloc"),
+		MetadataModel:       metadata,
+		NetworkDetailsModel: newNetworkDetails("10.0.0.0/28", defaultGatewayOffset),
+	}
+	if diags := state.Set(ctx, &model); diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	deleteAllocation := func() *resource.DeleteResponse {
+		resp := &resource.DeleteResponse{State: state}
+		r.Delete(ctx, resource.DeleteRequest{State: state}, resp)
+		return resp
+	}
+
+	if resp := deleteAllocation(); !resp.Diagnostics.HasError() || resp.Diagnostics[0].Summary() != "Cannot Delete Allocation" {
+		t.Fatalf("expected the allocation with addresses to be kept, got %v", resp.Diagnostics)
+	}
+	if _, err := p.storage.GetAllocation(ctx, "alloc"); err != nil {
+		t.Fatalf("expected the allocation to still exist: %v", err)
+	}
+
+	if err := p.storage.DeleteAddress(ctx, "addr"); err != nil {
+		t.Fatal(err)
+	}
+	if resp := deleteAllocation(); resp.Diagnostics.HasError() {
+		t.Fatalf("expected the allocation to be deleted, got %v", resp.Diagnostics)
+	}
+}
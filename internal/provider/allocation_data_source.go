@@ -3,6 +3,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"maps"
 	"terraform-provider-tfipam/internal/provider/storage"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
@@ -25,6 +26,12 @@ type AllocationDataSourceModel struct {
 	PoolName      types.String `tfsdk:"pool_name"`
 	AllocatedCIDR types.String `tfsdk:"allocated_cidr"`
 	PrefixLength  types.Int64  `tfsdk:"prefix_length"`
+	Orphaned      types.Bool   `tfsdk:"orphaned"`
+	ExpiresAt     types.String `tfsdk:"expires_at"`
+
+	MetadataModel
+
+	NetworkDetailsModel
 }
 
 func (d *AllocationDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -52,8 +59,21 @@ func (d *AllocationDataSource) Schema(ctx context.Context, req datasource.Schema
 				MarkdownDescription: "Prefix length of the allocated CIDR",
 				Computed:            true,
 			},
+			"orphaned": schema.BoolAttribute{
+				MarkdownDescription: "Whether the pool CIDRs no longer contain the allocated CIDR",
+				Computed:            true,
+			},
+			"expires_at": schema.StringAttribute{
+				MarkdownDescription: "When the allocation expires in RFC 3339 format, null if it never expires",
+				Computed:            true,
+			},
 		},
 	}
+
+	maps.Copy(resp.Schema.Attributes, metadataDataSourceAttributes("allocation"))
+
+	// network details derived from allocated_cidr
+	maps.Copy(resp.Schema.Attributes, networkDetailsDataSourceAttributes())
 }
 
 func (d *AllocationDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
@@ -99,6 +119,16 @@ func (d *AllocationDataSource) Read(ctx context.Context, req datasource.ReadRequ
 	data.AllocatedCIDR = types.StringValue(allocation.AllocatedCIDR)
 	data.PoolName = types.StringValue(allocation.PoolName)
 	data.PrefixLength = types.Int64Value(int64(allocation.PrefixLength))
+	data.Orphaned = types.BoolValue(allocation.Orphaned)
+	data.ExpiresAt = expiresAtValue(types.StringNull(), allocation.ExpiresAt)
+	data.NetworkDetailsModel = d.provider.networkDetails(ctx, allocation.PoolName, allocation.AllocatedCIDR)
+
+	metadata, diags := newMetadataModel(ctx, allocation.Metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.MetadataModel = metadata
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+// validateExpiry checks the expires_at and ttl of an allocation configuration.
+func validateExpiry(expiresAt, ttl types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !expiresAt.IsNull() && !expiresAt.IsUnknown() {
+		if _, err := time.Parse(time.RFC3339, expiresAt.ValueString()); err != nil {
+			diags.AddAttributeError(
+				path.Root("expires_at"),
+				"Invalid Expiry",
+				fmt.Sprintf("expires_at must be an RFC 3339 timestamp such as '2030-01-31T18:00:00Z', got '%s'", expiresAt.ValueString()),
+			)
+		}
+	}
+
+	if !ttl.IsNull() && !ttl.IsUnknown() {
+		if d, err := time.ParseDuration(ttl.ValueString()); err != nil || d <= 0 {
+			diags.AddAttributeError(
+				path.Root("ttl"),
+				"Invalid TTL",
+				fmt.Sprintf("ttl must be a positive duration such as '30m' or '72h', got '%s'", ttl.ValueString()),
+			)
+		}
+	}
+
+	if !expiresAt.IsNull() && !ttl.IsNull() {
+		diags.AddAttributeError(
+			path.Root("ttl"),
+			"Conflicting Expiry",
+			"Only one of expires_at and ttl can be set.",
+		)
+	}
+
+	return diags
+}
+
+// resolveExpiry returns when the allocation expires: a known expires_at as is,
+// otherwise ttl counted from now. Without either the allocation never expires.
+func resolveExpiry(expiresAt, ttl types.String, now time.Time) (*time.Time, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if !expiresAt.IsNull() && !expiresAt.IsUnknown() {
+		t, err := time.Parse(time.RFC3339, expiresAt.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("expires_at"), "Invalid Expiry", err.Error())
+			return nil, diags
+		}
+		return &t, diags
+	}
+
+	if ttl.IsNull() {
+		return nil, diags
+	}
+	d, err := time.ParseDuration(ttl.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("ttl"), "Invalid TTL", err.Error())
+		return nil, diags
+	}
+	t := now.Add(d).Truncate(time.Second).UTC()
+	return &t, diags
+}
+
+// expiresAtValue returns the expiry stored for an allocation as an attribute
+// value. The current value is kept when it names the same time, so a timestamp
+// written with a different offset doesn't show as a change.
+func expiresAtValue(current types.String, stored *time.Time) types.String {
+	if stored == nil {
+		return types.StringNull()
+	}
+	if !current.IsNull() && !current.IsUnknown() {
+		if t, err := time.Parse(time.RFC3339, current.ValueString()); err == nil && t.Equal(*stored) {
+			return current
+		}
+	}
+	return types.StringValue(stored.UTC().Format(time.RFC3339))
+}
+
+// planExpiry plans expires_at. A configured expires_at is planned as is and
+// without expires_at or ttl the allocation never expires. With ttl, the expiry
+// is kept while ttl is unchanged and computed again when ttl changes or the
+// allocation is created or replaced.
+func (r *AllocationResource) planExpiry(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	var configExpiresAt, ttl types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("expires_at"), &configExpiresAt)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("ttl"), &ttl)...)
+	if resp.Diagnostics.HasError() || !configExpiresAt.IsNull() {
+		return
+	}
+
+	planned := types.StringUnknown()
+	switch {
+	case ttl.IsNull():
+		planned = types.StringNull()
+	case !req.State.Raw.IsNull() && len(resp.RequiresReplace) == 0:
+		var stateExpiresAt, stateTTL types.String
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("expires_at"), &stateExpiresAt)...)
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("ttl"), &stateTTL)...)
+		if stateTTL.Equal(ttl) && !stateExpiresAt.IsNull() {
+			planned = stateExpiresAt
+		}
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("expires_at"), planned)...)
+}
+
+// expiredAllocationWarning tells what happens to an allocation that expired,
+// depending on whether its pool reclaims expired allocations.
+func (p *IpamProvider) expiredAllocationWarning(ctx context.Context, alloc *storage.Allocation) diag.Diagnostic {
+	expired := alloc.ExpiresAt.UTC().Format(time.RFC3339)
+
+	if pool, err := p.storage.GetPool(ctx, alloc.PoolName); err == nil && pool.ReclaimExpired {
+		return diag.NewAttributeWarningDiagnostic(
+			path.Root("expires_at"),
+			"Allocation Expired",
+			fmt.Sprintf("Allocation %s expired at %s. Pool %s reclaims expired allocations, so %s is removed and handed out again with the next allocation from the pool.", alloc.ID, expired, alloc.PoolName, alloc.AllocatedCIDR),
+		)
+	}
+
+	return diag.NewAttributeWarningDiagnostic(
+		path.Root("expires_at"),
+		"Allocation Expired",
+		fmt.Sprintf("Allocation %s expired at %s and still holds %s. Destroy it, extend expires_at or ttl, or release it with `tfipam reclaim`.", alloc.ID, expired, alloc.AllocatedCIDR),
+	)
+}
@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestAccAllocationResource_Expiry(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationResourceExpiryConfig("expiry-pool", `expires_at = "2099-01-31T18:00:00+01:00"`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation.test", tfjsonpath.New("expires_at"), knownvalue.StringExact("2099-01-31T18:00:00+01:00")),
+				},
+			},
+			{
+				Config: testAccAllocationResourceExpiryConfig("expiry-pool", `ttl = "72h"`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation.test", tfjsonpath.New("expires_at"), knownvalue.StringRegexp(regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ$`))),
+					statecheck.ExpectKnownValue("tfipam_pool.test", tfjsonpath.New("reclaim_expired"), knownvalue.Bool(true)),
+				},
+			},
+			{
+				Config: testAccAllocationResourceExpiryConfig("expiry-pool", ""),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation.test", tfjsonpath.New("expires_at"), knownvalue.Null()),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAllocationResource_ConflictingExpiry(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAllocationResourceExpiryConfig("expiry-pool", "ttl = \"1h\"\n  expires_at = \"2099-01-01T00:00:00Z\""),
+				ExpectError: regexp.MustCompile("Conflicting Expiry"),
+			},
+		},
+	})
+}
+
+func TestReclaimExpired(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "keep", CIDRs: []string{"10.1.0.0/24"}})
+	if err := p.storage.SavePool(ctx, &storage.Pool{Name: "reclaim", CIDRs: []string{"10.0.0.0/24"}, ReclaimExpired: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	for _, alloc := range []storage.Allocation{
+		{ID: "expired", PoolName: "reclaim", AllocatedCIDR: "10.0.0.0/26", PrefixLength: 26, ExpiresAt: &past},
+		{ID: "live", PoolName: "reclaim", AllocatedCIDR: "10.0.0.64/26", PrefixLength: 26, ExpiresAt: &future},
+		{ID: "kept", PoolName: "keep", AllocatedCIDR: "10.1.0.0/26", PrefixLength: 26, ExpiresAt: &past},
+	} {
+		if err := p.storage.SaveAllocation(ctx, &alloc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.storage.SaveAddress(ctx, &storage.Address{ID: "host", AllocationID: "expired", Address: "10.0.0.10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a pool that doesn't reclaim keeps the CIDR of its expired allocation
+	space, _ := p.loadPool(ctx, "keep", "")
+	if got, _, _ := space.allocate(26, ""); got.String() != "10.1.0.64/26" {
+		t.Fatalf("expected the expired allocation to keep its CIDR, got %s", got)
+	}
+
+	// a pool that reclaims hands it out again, and the expired allocation is removed
+	r := &AllocationResource{provider: p}
+	cidr, err := r.allocateCIDRFromPool(ctx, "reclaim", "new", 26, "", storage.Metadata{}, nil)
+	if err != nil || cidr != "10.0.0.0/26" {
+		t.Fatalf("expected the expired CIDR 10.0.0.0/26, got %s (%v)", cidr, err)
+	}
+	if _, err := p.storage.GetAllocation(ctx, "expired"); err != storage.ErrNotFound {
+		t.Fatalf("expected the expired allocation to be removed, got %v", err)
+	}
+	if _, err := p.storage.GetAddress(ctx, "host"); err != storage.ErrNotFound {
+		t.Fatalf("expected the address of the expired allocation to be removed, got %v", err)
+	}
+	if _, err := p.storage.GetAllocation(ctx, "live"); err != nil {
+		t.Fatalf("expected the unexpired allocation to be kept, got %v", err)
+	}
+
+	records, _ := p.storage.ListAuditRecords(ctx)
+	if len(records) != 1 || records[0].Operation != storage.AuditReclaimExpired || records[0].AllocationID != "expired" || records[0].CIDR != "10.0.0.0/26" {
+		t.Fatalf("expected an audit record of the reclaimed allocation, got %v", records)
+	}
+
+	// explicit reclaiming ignores the pool setting, a dry run removes nothing
+	expired, err := storage.ReclaimExpired(ctx, p.storage, storage.ReclaimOptions{DryRun: true})
+	if err != nil || len(expired) != 1 || expired[0].ID != "kept" {
+		t.Fatalf("expected the dry run to find kept, got %v (%v)", expired, err)
+	}
+	if _, err := p.storage.GetAllocation(ctx, "kept"); err != nil {
+		t.Fatalf("expected the dry run to keep the allocation, got %v", err)
+	}
+	if _, err := storage.ReclaimExpired(ctx, p.storage, storage.ReclaimOptions{PoolName: "keep"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.storage.GetAllocation(ctx, "kept"); err != storage.ErrNotFound {
+		t.Fatalf("expected kept to be reclaimed, got %v", err)
+	}
+	if records, _ := p.storage.ListAuditRecords(ctx); len(records) != 2 {
+		t.Fatalf("expected a second audit record, got %v", records)
+	}
+}
+
+func TestResolveExpiry(t *testing.T) {
+	now := time.Date(2030, 1, 1, 12, 0, 0, 500, time.UTC)
+
+	got, diags := resolveExpiry(types.StringUnknown(), types.StringValue("90m"), now)
+	if diags.HasError() || got == nil || !got.Equal(time.Date(2030, 1, 1, 13, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected ttl to count from now, got %v %v", got, diags)
+	}
+
+	got, diags = resolveExpiry(types.StringValue("2031-06-01T00:00:00+02:00"), types.StringNull(), now)
+	if diags.HasError() || got == nil || !got.Equal(time.Date(2031, 5, 31, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected expires_at to be used, got %v %v", got, diags)
+	}
+
+	if got, diags := resolveExpiry(types.StringNull(), types.StringNull(), now); got != nil || diags.HasError() {
+		t.Errorf("expected no expiry, got %v %v", got, diags)
+	}
+}
+
+func TestExpiresAtValue(t *testing.T) {
+	stored := time.Date(2031, 5, 31, 22, 0, 0, 0, time.UTC)
+
+	if got := expiresAtValue(types.StringValue("2031-06-01T00:00:00+02:00"), &stored); got.ValueString() != "2031-06-01T00:00:00+02:00" {
+		t.Errorf("expected the configured offset to be kept, got %s", got)
+	}
+	if got := expiresAtValue(types.StringValue("2031-06-02T00:00:00Z"), &stored); got.ValueString() != "2031-05-31T22:00:00Z" {
+		t.Errorf("expected a changed expiry to be synced, got %s", got)
+	}
+	if got := expiresAtValue(types.StringUnknown(), nil); !got.IsNull() {
+		t.Errorf("expected null without an expiry, got %s", got)
+	}
+}
+
+func testAccAllocationResourceExpiryConfig(poolName, expiry string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name            = %[1]q
+  cidrs           = ["10.60.0.0/16"]
+  reclaim_expired = true
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "%[1]s-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+  %[2]s
+}
+`, poolName, expiry)
+}
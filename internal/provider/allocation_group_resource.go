@@ -0,0 +1,482 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/netip"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ resource.Resource = &AllocationGroupResource{}
+var _ resource.ResourceWithImportState = &AllocationGroupResource{}
+var _ resource.ResourceWithValidateConfig = &AllocationGroupResource{}
+var _ resource.ResourceWithModifyPlan = &AllocationGroupResource{}
+
+func NewAllocationGroupResource() resource.Resource {
+	return &AllocationGroupResource{}
+}
+
+type AllocationGroupResource struct {
+	provider *IpamProvider
+}
+
+type AllocationGroupResourceModel struct {
+	ID                  types.String `tfsdk:"id"`
+	PoolName            types.String `tfsdk:"pool_name"`
+	PrefixLengths       types.List   `tfsdk:"prefix_lengths"`
+	SummaryPrefixLength types.Int64  `tfsdk:"summary_prefix_length"`
+	Strategy            types.String `tfsdk:"allocation_strategy"`
+	CIDRs               types.List   `tfsdk:"cidrs"`
+	SummaryCIDR         types.String `tfsdk:"summary_cidr"`
+
+	MetadataModel
+}
+
+func (m AllocationGroupResourceModel) prefixLengths(ctx context.Context) ([]int, error) {
+	var lengths []int64
+	if diags := m.PrefixLengths.ElementsAs(ctx, &lengths, false); diags.HasError() {
+		return nil, fmt.Errorf("prefix_lengths can't be read")
+	}
+
+	prefixLengths := make([]int, len(lengths))
+	for i, length := range lengths {
+		prefixLengths[i] = int(length)
+	}
+	return prefixLengths, nil
+}
+
+// setBlocks sets the summary CIDR and blocks of the group from storage.
+func (m *AllocationGroupResourceModel) setBlocks(ctx context.Context, alloc *storage.Allocation) {
+	m.SummaryCIDR = types.StringValue(alloc.AllocatedCIDR)
+	m.SummaryPrefixLength = types.Int64Value(int64(alloc.PrefixLength))
+	m.CIDRs, _ = types.ListValueFrom(ctx, types.StringType, alloc.Blocks)
+}
+
+func (r *AllocationGroupResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_allocation_group"
+}
+
+func (r *AllocationGroupResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Allocates a group of contiguous CIDRs of given sizes that summarize into a single aligned CIDR",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Unique identifier for this allocation group. The group is stored as one allocation of `summary_cidr` with this ID.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"pool_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the pool to allocate from",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"prefix_lengths": schema.ListAttribute{
+				ElementType:         types.Int64Type,
+				Required:            true,
+				MarkdownDescription: "Prefix length of each block, such as `[24, 24, 24, 24]` for four /24s summarizing into a /22. Blocks of different sizes are placed largest first so each is aligned to its size.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"summary_prefix_length": schema.Int64Attribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Prefix length of the summary CIDR holding the blocks. Defaults to the longest prefix length that fits all blocks; set it shorter to keep room to spare for the group.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"allocation_strategy": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Overrides the pool's `allocation_strategy` for the summary CIDR. Supported values: 'first_fit', 'best_fit', 'last_fit', 'random', 'sequential'. Only used when the group is allocated, changing it does not move an existing group.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(ipam.Strategies()...),
+				},
+			},
+			"cidrs": schema.ListAttribute{
+				ElementType:         types.StringType,
+				Computed:            true,
+				MarkdownDescription: "The allocated block of each prefix length, in the order of `prefix_lengths`",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"summary_cidr": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The CIDR summarizing all blocks. It is held by the group as a whole, so no other allocation lands in it.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+
+	maps.Copy(resp.Schema.Attributes, metadataResourceAttributes("allocation group"))
+}
+
+func (r *AllocationGroupResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.provider = provider
+}
+
+func (r *AllocationGroupResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data AllocationGroupResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || data.PrefixLengths.IsUnknown() || data.PrefixLengths.IsNull() {
+		return
+	}
+
+	for _, element := range data.PrefixLengths.Elements() {
+		if element.IsUnknown() {
+			return
+		}
+	}
+
+	prefixLengths, err := data.prefixLengths(ctx)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("prefix_lengths"), "Invalid Prefix Lengths", err.Error())
+		return
+	}
+
+	needed, err := ipam.SummaryPrefixLength(prefixLengths)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("prefix_lengths"),
+			"Invalid Prefix Lengths",
+			fmt.Sprintf("Prefix lengths %v can't be allocated as a group: %s", prefixLengths, err),
+		)
+		return
+	}
+
+	if !data.SummaryPrefixLength.IsNull() && !data.SummaryPrefixLength.IsUnknown() && data.SummaryPrefixLength.ValueInt64() > int64(needed) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("summary_prefix_length"),
+			"Summary Too Small",
+			fmt.Sprintf("Blocks of prefix lengths %v need a summary prefix length of %d or shorter, got %d", prefixLengths, needed, data.SummaryPrefixLength.ValueInt64()),
+		)
+	}
+}
+
+// ModifyPlan shows the summary prefix length of a new group in the plan when it
+// isn't configured.
+func (r *AllocationGroupResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data AllocationGroupResourceModel
+	resp.Diagnostics.Append(resp.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || !data.SummaryPrefixLength.IsUnknown() || data.PrefixLengths.IsUnknown() {
+		return
+	}
+
+	prefixLengths, err := data.prefixLengths(ctx)
+	if err != nil {
+		return
+	}
+	needed, err := ipam.SummaryPrefixLength(prefixLengths)
+	if err != nil {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("summary_prefix_length"), types.Int64Value(int64(needed)))...)
+}
+
+func (r *AllocationGroupResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data AllocationGroupResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	prefixLengths, err := data.prefixLengths(ctx)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("prefix_lengths"), "Invalid Prefix Lengths", err.Error())
+		return
+	}
+
+	summaryPrefixLength, err := ipam.SummaryPrefixLength(prefixLengths)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("prefix_lengths"), "Invalid Prefix Lengths", err.Error())
+		return
+	}
+	if !data.SummaryPrefixLength.IsNull() && !data.SummaryPrefixLength.IsUnknown() {
+		summaryPrefixLength = int(data.SummaryPrefixLength.ValueInt64())
+	}
+
+	metadata, diags := data.MetadataModel.toStorage(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	poolName := data.PoolName.ValueString()
+	alloc, err := r.allocateGroup(ctx, poolName, data.ID.ValueString(), summaryPrefixLength, prefixLengths, ipam.Strategy(data.Strategy.ValueString()), metadata)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Allocation Failed",
+			fmt.Sprintf("Unable to allocate a contiguous /%d from pool %s: %s", summaryPrefixLength, poolName, err),
+		)
+		return
+	}
+
+	data.setBlocks(ctx, alloc)
+
+	tflog.Trace(ctx, "created allocation group resource", map[string]any{
+		"id":           alloc.ID,
+		"pool_name":    poolName,
+		"summary_cidr": alloc.AllocatedCIDR,
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *AllocationGroupResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data AllocationGroupResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	alloc, err := r.provider.storage.GetAllocation(ctx, data.ID.ValueString())
+	if err != nil {
+		if err == storage.ErrNotFound {
+			// group was deleted outside Terraform
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError(
+			"Failed to Read Allocation Group",
+			fmt.Sprintf("Could not read allocation from storage: %s", err),
+		)
+		return
+	}
+
+	data.PoolName = types.StringValue(alloc.PoolName)
+	data.setBlocks(ctx, alloc)
+
+	metadata, diags := data.MetadataModel.sync(ctx, alloc.Metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.MetadataModel = metadata
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *AllocationGroupResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// Only the metadata and allocation_strategy can change in place, and the strategy only applies when allocating
+	var data AllocationGroupResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	metadata, diags := data.MetadataModel.toStorage(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	alloc, err := r.provider.storage.GetAllocation(ctx, data.ID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Read Allocation Group",
+			fmt.Sprintf("Could not read allocation from storage: %s", err),
+		)
+		return
+	}
+
+	alloc.Metadata = metadata
+	if err := r.provider.storage.SaveAllocation(ctx, alloc); err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Update Allocation Group",
+			fmt.Sprintf("Could not save allocation to storage: %s", err),
+		)
+		return
+	}
+	data.setBlocks(ctx, alloc)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *AllocationGroupResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data AllocationGroupResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// host addresses taken from the group must be released first
+	addresses, err := r.provider.storage.ListAddressesByAllocation(ctx, data.ID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Check Addresses",
+			fmt.Sprintf("Could not check for addresses: %s", err),
+		)
+		return
+	}
+
+	if len(addresses) > 0 {
+		resp.Diagnostics.AddError(
+			"Cannot Delete Allocation Group",
+			fmt.Sprintf("Allocation group %s has %d active addresses. Please delete all addresses before deleting the group.", data.ID.ValueString(), len(addresses)),
+		)
+		return
+	}
+
+	if err := r.provider.storage.DeleteAllocation(ctx, data.ID.ValueString()); err != nil && err != storage.ErrNotFound {
+		resp.Diagnostics.AddError(
+			"Failed to Delete Allocation Group",
+			fmt.Sprintf("Could not delete allocation from storage: %s", err),
+		)
+		return
+	}
+
+	tflog.Trace(ctx, "deleted allocation group resource", map[string]any{
+		"id":        data.ID.ValueString(),
+		"pool_name": data.PoolName.ValueString(),
+	})
+}
+
+func (r *AllocationGroupResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// For import we expect the ID to be the allocation ID of the group
+	alloc, err := r.provider.storage.GetAllocation(ctx, req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Allocation Group Not Found",
+			fmt.Sprintf("Allocation %s not found in storage: %s", req.ID, err),
+		)
+		return
+	}
+	if len(alloc.Blocks) == 0 {
+		resp.Diagnostics.AddError(
+			"Not an Allocation Group",
+			fmt.Sprintf("Allocation %s has no blocks, import it as tfipam_allocation instead", req.ID),
+		)
+		return
+	}
+
+	prefixLengths := make([]int64, 0, len(alloc.Blocks))
+	for _, cidr := range alloc.Blocks {
+		block, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Invalid Allocation Group",
+				fmt.Sprintf("Block %q of allocation %s is not valid: %s", cidr, req.ID, err),
+			)
+			return
+		}
+		prefixLengths = append(prefixLengths, int64(block.Bits()))
+	}
+
+	data := AllocationGroupResourceModel{
+		ID:       types.StringValue(alloc.ID),
+		PoolName: types.StringValue(alloc.PoolName),
+		Strategy: types.StringNull(),
+	}
+	data.PrefixLengths, _ = types.ListValueFrom(ctx, types.Int64Type, prefixLengths)
+	data.setBlocks(ctx, alloc)
+
+	metadata, diags := newMetadataModel(ctx, alloc.Metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.MetadataModel = metadata
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// allocateGroup allocates a summary CIDR of the prefix length from the pool and
+// packs the blocks into it. The summary is saved as a single allocation holding
+// the blocks, so the space left over in it isn't handed out to anyone else.
+func (r *AllocationGroupResource) allocateGroup(ctx context.Context, poolName string, id string, summaryPrefixLength int, prefixLengths []int, strategy ipam.Strategy, metadata storage.Metadata) (*storage.Allocation, error) {
+	space, err := r.provider.loadPool(ctx, poolName, id)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, strategy, err := space.allocate(summaryPrefixLength, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := ipam.PackContiguous(summary, prefixLengths)
+	if err != nil {
+		return nil, err
+	}
+
+	alloc := &storage.Allocation{
+		ID:            id,
+		PoolName:      poolName,
+		AllocatedCIDR: summary.String(),
+		PrefixLength:  summary.Bits(),
+		Metadata:      metadata,
+	}
+	for _, block := range blocks {
+		alloc.Blocks = append(alloc.Blocks, block.String())
+	}
+
+	// the group, the removal of expired allocations it may take the space of
+	// and the pool's cursor are written at once
+	batch := &storage.Batch{CreateAllocations: []storage.Allocation{*alloc}}
+	reclaimed, err := r.provider.reclaimBatch(ctx, space, batch)
+	if err != nil {
+		return nil, err
+	}
+	if strategy == ipam.Sequential {
+		batch.SetCursor(poolName, summary.String())
+	}
+	if err := r.provider.storage.Apply(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to save allocation: %w", err)
+	}
+	logReclaimed(ctx, reclaimed)
+
+	tflog.Debug(ctx, "allocated contiguous blocks from pool", map[string]any{
+		"pool_name":    poolName,
+		"summary_cidr": summary.String(),
+		"blocks":       alloc.Blocks,
+	})
+
+	return alloc, nil
+}
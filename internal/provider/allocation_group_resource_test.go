@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestAccAllocationGroupResource_Basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationGroupResourceConfig("group-pool", "[24, 24, 24, 24]", ""),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation_group.test", tfjsonpath.New("summary_cidr"), knownvalue.StringExact("10.90.0.0/22")),
+					statecheck.ExpectKnownValue("tfipam_allocation_group.test", tfjsonpath.New("summary_prefix_length"), knownvalue.Int64Exact(22)),
+					statecheck.ExpectKnownValue("tfipam_allocation_group.test", tfjsonpath.New("cidrs"), knownvalue.ListExact([]knownvalue.Check{
+						knownvalue.StringExact("10.90.0.0/24"),
+						knownvalue.StringExact("10.90.1.0/24"),
+						knownvalue.StringExact("10.90.2.0/24"),
+						knownvalue.StringExact("10.90.3.0/24"),
+					})),
+				},
+			},
+			{
+				ResourceName:            "tfipam_allocation_group.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateId:           "firewall",
+				ImportStateVerifyIgnore: []string{"allocation_strategy"},
+			},
+		},
+	})
+}
+
+func TestAccAllocationGroupResource_MixedSizes(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationGroupResourceConfig("group-mixed-pool", "[26, 24, 25]", "summary_prefix_length = 22"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation_group.test", tfjsonpath.New("summary_cidr"), knownvalue.StringExact("10.90.0.0/22")),
+					statecheck.ExpectKnownValue("tfipam_allocation_group.test", tfjsonpath.New("cidrs"), knownvalue.ListExact([]knownvalue.Check{
+						knownvalue.StringExact("10.90.1.128/26"),
+						knownvalue.StringExact("10.90.0.0/24"),
+						knownvalue.StringExact("10.90.1.0/25"),
+					})),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAllocationGroupResource_SummaryTooSmall(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAllocationGroupResourceConfig("group-pool", "[24, 24, 24]", "summary_prefix_length = 23"),
+				ExpectError: regexp.MustCompile("Summary Too Small"),
+			},
+		},
+	})
+}
+
+func TestAllocationGroupAllocate(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/16"}})
+	if err := p.storage.SaveAllocation(ctx, &storage.Allocation{ID: "other", PoolName: "pool", AllocatedCIDR: "10.0.1.0/24", PrefixLength: 24}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &AllocationGroupResource{provider: p}
+
+	// the summary skips the aligned /22 overlapping the other allocation
+	alloc, err := r.allocateGroup(ctx, "pool", "fw", 22, []int{25, 24, 24, 25}, "", storage.Metadata{Owner: "network"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alloc.AllocatedCIDR != "10.0.4.0/22" {
+		t.Fatalf("expected summary 10.0.4.0/22, got %s", alloc.AllocatedCIDR)
+	}
+	for i, want := range []string{"10.0.6.0/25", "10.0.4.0/24", "10.0.5.0/24", "10.0.6.128/25"} {
+		if alloc.Blocks[i] != want {
+			t.Errorf("expected block %d at %s, got %s", i, want, alloc.Blocks[i])
+		}
+	}
+
+	stored, err := p.storage.GetAllocation(ctx, "fw")
+	if err != nil || len(stored.Blocks) != 4 || stored.Owner != "network" {
+		t.Fatalf("expected the group to be stored with its blocks, got %+v (%v)", stored, err)
+	}
+
+	snap, err := storage.TakeSnapshot(ctx, p.storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := storage.Verify(snap); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestAllocationGroupAllocate_ReclaimExpired(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/24"}, AllocationStrategy: "sequential", ReclaimExpired: true})
+	past := time.Now().Add(-time.Hour)
+	if err := p.storage.SaveAllocation(ctx, &storage.Allocation{ID: "expired", PoolName: "pool", AllocatedCIDR: "10.0.0.0/25", PrefixLength: 25, ExpiresAt: &past}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the group takes the space of the expired allocation, which is removed in the same write
+	r := &AllocationGroupResource{provider: p}
+	alloc, err := r.allocateGroup(ctx, "pool", "fw", 25, []int{26, 26}, "", storage.Metadata{})
+	if err != nil || alloc.AllocatedCIDR != "10.0.0.0/25" {
+		t.Fatalf("expected summary 10.0.0.0/25, got %v (%v)", alloc, err)
+	}
+	if _, err := p.storage.GetAllocation(ctx, "expired"); err != storage.ErrNotFound {
+		t.Fatalf("expected the expired allocation to be removed, got %v", err)
+	}
+	if pool, _ := p.storage.GetPool(ctx, "pool"); pool.Cursor != "10.0.0.0/25" {
+		t.Errorf("expected the cursor to move to 10.0.0.0/25, got %q", pool.Cursor)
+	}
+	if records, _ := p.storage.ListAuditRecords(ctx); len(records) != 1 || records[0].AllocationID != "expired" {
+		t.Errorf("expected an audit record of the reclaimed allocation, got %v", records)
+	}
+
+	// taking the ID again fails instead of replacing the group
+	if _, err := r.allocateGroup(ctx, "pool", "fw", 25, []int{25}, "", storage.Metadata{}); !errors.Is(err, storage.ErrExists) {
+		t.Errorf("expected the existing group to fail with ErrExists, got %v", err)
+	}
+}
+
+func testAccAllocationGroupResourceConfig(poolName, prefixLengths, extra string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name  = %[1]q
+  cidrs = ["10.90.0.0/16"]
+}
+
+resource "tfipam_allocation_group" "test" {
+  id             = "firewall"
+  pool_name      = tfipam_pool.test.name
+  prefix_lengths = %[2]s
+  %[3]s
+}
+`, poolName, prefixLengths, extra)
+}
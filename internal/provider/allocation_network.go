@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"math/big"
+	"net/netip"
+
+	datasourceschema "github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/numberplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+// defaultGatewayOffset puts the gateway on the first usable address of a network.
+const defaultGatewayOffset = 1
+
+// NetworkDetailsModel holds the addresses derived from an allocated CIDR. It is
+// embedded in the allocation resource and data source models.
+type NetworkDetailsModel struct {
+	NetworkAddress   types.String `tfsdk:"network_address"`
+	Netmask          types.String `tfsdk:"netmask"`
+	BroadcastAddress types.String `tfsdk:"broadcast_address"`
+	FirstUsable      types.String `tfsdk:"first_usable"`
+	LastUsable       types.String `tfsdk:"last_usable"`
+	Gateway          types.String `tfsdk:"gateway"`
+	HostCount        types.Number `tfsdk:"host_count"`
+	AddressFamily    types.String `tfsdk:"address_family"`
+}
+
+var networkDetailsDescriptions = map[string]string{
+	"network_address":   "First address of the allocated CIDR",
+	"netmask":           "Netmask of the allocated CIDR, e.g. `255.255.255.0` for a /24",
+	"broadcast_address": "Last address of an IPv4 CIDR, null for IPv6 and for /31 and /32 CIDRs, which have no broadcast address",
+	"first_usable":      "First address hosts can use. The network address is skipped, and the subnet-router anycast address for IPv6, except for /31, /32, /127 and /128 CIDRs",
+	"last_usable":       "Last address hosts can use. The broadcast address is skipped for IPv4",
+	"gateway":           "Gateway address at the pool's `gateway_offset` within the usable range, null if the offset is not usable",
+	"host_count":        "Number of usable addresses",
+	"address_family":    "`ipv4` or `ipv6`",
+}
+
+// networkDetailsResourceAttributes returns the computed network details attributes of the allocation resource.
+func networkDetailsResourceAttributes() map[string]schema.Attribute {
+	attributes := map[string]schema.Attribute{}
+	for name, description := range networkDetailsDescriptions {
+		if name == "host_count" {
+			attributes[name] = schema.NumberAttribute{
+				Computed:            true,
+				MarkdownDescription: description,
+				PlanModifiers: []planmodifier.Number{
+					numberplanmodifier.UseStateForUnknown(),
+				},
+			}
+			continue
+		}
+		attributes[name] = schema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: description,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		}
+	}
+	return attributes
+}
+
+// networkDetailsDataSourceAttributes returns the computed network details attributes of the allocation data source.
+func networkDetailsDataSourceAttributes() map[string]datasourceschema.Attribute {
+	attributes := map[string]datasourceschema.Attribute{}
+	for name, description := range networkDetailsDescriptions {
+		if name == "host_count" {
+			attributes[name] = datasourceschema.NumberAttribute{
+				Computed:            true,
+				MarkdownDescription: description,
+			}
+			continue
+		}
+		attributes[name] = datasourceschema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: description,
+		}
+	}
+	return attributes
+}
+
+// newNetworkDetails derives the network details of the CIDR, all null if it can't be parsed.
+func newNetworkDetails(cidr string, gatewayOffset int64) NetworkDetailsModel {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return NetworkDetailsModel{
+			NetworkAddress:   types.StringNull(),
+			Netmask:          types.StringNull(),
+			BroadcastAddress: types.StringNull(),
+			FirstUsable:      types.StringNull(),
+			LastUsable:       types.StringNull(),
+			Gateway:          types.StringNull(),
+			HostCount:        types.NumberNull(),
+			AddressFamily:    types.StringNull(),
+		}
+	}
+
+	n := ipam.DescribeNetwork(prefix)
+	details := NetworkDetailsModel{
+		NetworkAddress:   types.StringValue(n.Prefix.Addr().String()),
+		Netmask:          types.StringValue(n.Netmask.String()),
+		BroadcastAddress: types.StringNull(),
+		FirstUsable:      types.StringValue(n.FirstUsable.String()),
+		LastUsable:       types.StringValue(n.LastUsable.String()),
+		Gateway:          types.StringNull(),
+		HostCount:        types.NumberValue(new(big.Float).SetInt(n.HostCount)),
+		AddressFamily:    types.StringValue(n.AddressFamily()),
+	}
+	if n.Broadcast.IsValid() {
+		details.BroadcastAddress = types.StringValue(n.Broadcast.String())
+	}
+	if gateway, ok := n.UsableAt(gatewayOffset); ok {
+		details.Gateway = types.StringValue(gateway.String())
+	}
+	return details
+}
+
+// gatewayOffset returns the gateway offset of the pool, or the default when it isn't set.
+func gatewayOffset(pool *storage.Pool) int64 {
+	if pool == nil || pool.GatewayOffset == nil {
+		return defaultGatewayOffset
+	}
+	return *pool.GatewayOffset
+}
+
+// networkDetails derives the network details of an allocation from the pool named poolName.
+func (p *IpamProvider) networkDetails(ctx context.Context, poolName string, cidr string) NetworkDetailsModel {
+	pool, err := p.storage.GetPool(ctx, poolName)
+	if err != nil {
+		pool = nil
+	}
+	return newNetworkDetails(cidr, gatewayOffset(pool))
+}
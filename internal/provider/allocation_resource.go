@@ -2,23 +2,33 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math/big"
-	"net"
+	"maps"
+	"net/netip"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
+	"terraform-provider-tfipam/internal/provider/ipam"
 	"terraform-provider-tfipam/internal/provider/storage"
 )
 
 var _ resource.Resource = &AllocationResource{}
 var _ resource.ResourceWithImportState = &AllocationResource{}
+var _ resource.ResourceWithValidateConfig = &AllocationResource{}
+var _ resource.ResourceWithModifyPlan = &AllocationResource{}
 
 func NewAllocationResource() resource.Resource {
 	return &AllocationResource{}
@@ -33,6 +43,14 @@ type AllocationResourceModel struct {
 	PoolName      types.String `tfsdk:"pool_name"`
 	AllocatedCIDR types.String `tfsdk:"allocated_cidr"`
 	PrefixLength  types.Int64  `tfsdk:"prefix_length"`
+	Strategy      types.String `tfsdk:"allocation_strategy"`
+	RequestedCIDR types.String `tfsdk:"requested_cidr"`
+	Orphaned      types.Bool   `tfsdk:"orphaned"`
+	ExpiresAt     types.String `tfsdk:"expires_at"`
+	TTL           types.String `tfsdk:"ttl"`
+
+	MetadataModel
+	NetworkDetailsModel
 }
 
 func (r *AllocationResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -65,6 +83,13 @@ func (r *AllocationResource) Schema(ctx context.Context, req resource.SchemaRequ
 					stringplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"orphaned": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the pool CIDRs were changed with `allow_orphaned_allocations` so they no longer contain the allocated CIDR",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
 			"prefix_length": schema.Int64Attribute{
 				Required:            true,
 				MarkdownDescription: "Prefix length for the allocated CIDR (e.g., 32 for a single IPv4 host)",
@@ -72,8 +97,38 @@ func (r *AllocationResource) Schema(ctx context.Context, req resource.SchemaRequ
 					int64planmodifier.RequiresReplace(),
 				},
 			},
+			"requested_cidr": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Claim exactly this CIDR instead of letting the pool pick one. It must lie within the pool, have the length given by `prefix_length` and not overlap another allocation.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIf(requestedCIDRRequiresReplace,
+						"Requesting a different CIDR replaces the allocation.",
+						"Requesting a different CIDR replaces the allocation."),
+				},
+			},
+			"expires_at": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "When the allocation expires, in RFC 3339 format such as '2030-01-31T18:00:00Z'. Computed from `ttl` when that is set instead. Expired allocations are reclaimed by pools with `reclaim_expired` and reported as warnings otherwise. Can be changed in place.",
+			},
+			"ttl": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "How long after it is created the allocation expires, such as '72h'. Changing it moves `expires_at` to the new duration counted from the time of the change. Conflicts with a configured `expires_at`.",
+			},
+			"allocation_strategy": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Overrides the pool's `allocation_strategy` for this allocation. Supported values: 'first_fit', 'best_fit', 'last_fit', 'random', 'sequential'. Only used when the CIDR is allocated, changing it does not move an existing allocation.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(ipam.Strategies()...),
+				},
+			},
 		},
 	}
+
+	maps.Copy(resp.Schema.Attributes, metadataResourceAttributes("allocation"))
+
+	// network details derived from allocated_cidr
+	maps.Copy(resp.Schema.Attributes, networkDetailsResourceAttributes())
 }
 
 func (r *AllocationResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
@@ -110,10 +165,52 @@ func (r *AllocationResource) Create(ctx context.Context, req resource.CreateRequ
 		return
 	}
 
+	metadata, diags := data.MetadataModel.toStorage(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	expiresAt, diags := resolveExpiry(data.ExpiresAt, data.TTL, time.Now())
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	// Find the pool and allocate the range
 	poolName := data.PoolName.ValueString()
 	allocationID := data.ID.ValueString()
-	allocatedCIDR, err := r.allocateCIDRFromPool(ctx, poolName, allocationID, prefixLength)
+	defer r.provider.forgetPlanned(allocationID)
+
+	var allocatedCIDR string
+	var err error
+	switch {
+	case data.RequestedCIDR.ValueString() != "":
+		allocatedCIDR, err = r.claimCIDRFromPool(ctx, poolName, allocationID, data.RequestedCIDR.ValueString(), "", metadata, expiresAt)
+	case data.AllocatedCIDR.ValueString() != "":
+		// the plan showed this CIDR, so apply must allocate exactly it
+		allocatedCIDR, err = r.claimCIDRFromPool(ctx, poolName, allocationID, data.AllocatedCIDR.ValueString(), ipam.Strategy(data.Strategy.ValueString()), metadata, expiresAt)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("allocated_cidr"),
+				"Planned CIDR Unavailable",
+				fmt.Sprintf("The plan allocated %s from pool %s, but it can no longer be claimed: %s. Plan again to pick another CIDR.", data.AllocatedCIDR.ValueString(), poolName, err),
+			)
+			return
+		}
+	default:
+		allocatedCIDR, err = r.allocateCIDRFromPool(ctx, poolName, allocationID, prefixLength, ipam.Strategy(data.Strategy.ValueString()), metadata, expiresAt)
+	}
+
+	var conflict *allocationConflictError
+	if errors.As(err, &conflict) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("requested_cidr"),
+			"Requested CIDR Conflict",
+			fmt.Sprintf("Unable to claim %s from pool %s: %s", conflict.requested, poolName, conflict),
+		)
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Allocation Failed",
@@ -124,6 +221,9 @@ func (r *AllocationResource) Create(ctx context.Context, req resource.CreateRequ
 
 	data.ID = types.StringValue(allocationID)
 	data.AllocatedCIDR = types.StringValue(allocatedCIDR)
+	data.Orphaned = types.BoolValue(false)
+	data.ExpiresAt = expiresAtValue(data.ExpiresAt, expiresAt)
+	data.NetworkDetailsModel = r.provider.networkDetails(ctx, poolName, allocatedCIDR)
 
 	tflog.Trace(ctx, "created allocation resource", map[string]any{
 		"id":             allocationID,
@@ -134,6 +234,137 @@ func (r *AllocationResource) Create(ctx context.Context, req resource.CreateRequ
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// ModifyPlan shows the CIDR a new allocation gets in the plan, and the apply
+// claims exactly that CIDR. Terraform plans again right before the apply, in a
+// new provider process, so the CIDR is only shown when planning again gives the
+// same one: it is computed from storage alone, and stays unknown for the random
+// strategy and while another new allocation of the pool is planned. It also
+// stays unknown when the pool doesn't exist yet or the configuration isn't known.
+// Planning only reads storage, the CIDR is kept in memory until the allocation is
+// created.
+func (r *AllocationResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	r.planExpiry(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// only new allocations, replacements are planned once the old allocation is gone
+	if !req.State.Raw.IsNull() || r.provider == nil {
+		return
+	}
+
+	var data AllocationResourceModel
+	resp.Diagnostics.Append(resp.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.ID.IsUnknown() || data.PoolName.IsUnknown() || data.PrefixLength.IsUnknown() ||
+		data.RequestedCIDR.IsUnknown() || data.Strategy.IsUnknown() {
+		return
+	}
+
+	r.provider.planMu.Lock()
+	defer r.provider.planMu.Unlock()
+
+	allocationID := data.ID.ValueString()
+	space, err := r.provider.loadPool(ctx, data.PoolName.ValueString(), allocationID)
+	if err != nil {
+		// the pool is created in the same run
+		tflog.Debug(ctx, "allocated CIDR unknown until apply", map[string]any{
+			"id":    allocationID,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var planned netip.Prefix
+	if requested := data.RequestedCIDR.ValueString(); requested != "" {
+		prefix, err := netip.ParsePrefix(requested)
+		if err != nil {
+			return
+		}
+		planned = prefix.Masked()
+	} else {
+		strategy := ipam.Strategy(data.Strategy.ValueString())
+		if strategy == "" {
+			strategy = ipam.Strategy(space.pool.AllocationStrategy)
+		}
+		if strategy == ipam.Random || len(r.provider.plannedCIDRs(space.pool.Name, allocationID)) > 0 {
+			// the order of the creates decides which CIDR each one gets
+			tflog.Debug(ctx, "allocated CIDR unknown until apply", map[string]any{
+				"id":       allocationID,
+				"strategy": string(strategy),
+			})
+			return
+		}
+		if planned, _, err = space.allocate(int(data.PrefixLength.ValueInt64()), strategy); err != nil {
+			// leave it to apply to report the full pool
+			return
+		}
+	}
+
+	r.provider.setPlanned(allocationID, space.pool.Name, planned)
+
+	data.AllocatedCIDR = types.StringValue(planned.String())
+	data.NetworkDetailsModel = newNetworkDetails(planned.String(), gatewayOffset(space.pool))
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &data)...)
+}
+
+func (r *AllocationResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data AllocationResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateExpiry(data.ExpiresAt, data.TTL)...)
+
+	if data.RequestedCIDR.IsNull() || data.RequestedCIDR.IsUnknown() {
+		return
+	}
+
+	requested, err := netip.ParsePrefix(data.RequestedCIDR.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("requested_cidr"),
+			"Invalid Requested CIDR",
+			fmt.Sprintf("CIDR '%s' is not valid: %s", data.RequestedCIDR.ValueString(), err),
+		)
+		return
+	}
+
+	if requested != requested.Masked() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("requested_cidr"),
+			"Invalid Requested CIDR",
+			fmt.Sprintf("CIDR '%s' has host bits set, did you mean '%s'?", requested, requested.Masked()),
+		)
+		return
+	}
+
+	if !data.PrefixLength.IsNull() && !data.PrefixLength.IsUnknown() && int64(requested.Bits()) != data.PrefixLength.ValueInt64() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("requested_cidr"),
+			"Requested CIDR Prefix Mismatch",
+			fmt.Sprintf("CIDR '%s' has prefix length %d, but prefix_length is %d", requested, requested.Bits(), data.PrefixLength.ValueInt64()),
+		)
+	}
+
+	if !data.Strategy.IsNull() {
+		resp.Diagnostics.AddAttributeWarning(
+			path.Root("allocation_strategy"),
+			"Allocation Strategy Ignored",
+			"allocation_strategy has no effect when requested_cidr is set.",
+		)
+	}
+}
+
 func (r *AllocationResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
 	var data AllocationResourceModel
 
@@ -161,12 +392,26 @@ func (r *AllocationResource) Read(ctx context.Context, req resource.ReadRequest,
 	data.AllocatedCIDR = types.StringValue(allocation.AllocatedCIDR)
 	data.PoolName = types.StringValue(allocation.PoolName)
 	data.PrefixLength = types.Int64Value(int64(allocation.PrefixLength))
+	data.Orphaned = types.BoolValue(allocation.Orphaned)
+	data.ExpiresAt = expiresAtValue(data.ExpiresAt, allocation.ExpiresAt)
+	data.NetworkDetailsModel = r.provider.networkDetails(ctx, allocation.PoolName, allocation.AllocatedCIDR)
+
+	if allocation.Expired(time.Now()) {
+		resp.Diagnostics.Append(r.provider.expiredAllocationWarning(ctx, allocation))
+	}
+
+	metadata, diags := data.MetadataModel.sync(ctx, allocation.Metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.MetadataModel = metadata
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *AllocationResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	// All attributes require replacement, so this should never be called
+	// Only the metadata, expiry and allocation_strategy can change in place, and the strategy only applies when allocating
 	var data AllocationResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -174,6 +419,38 @@ func (r *AllocationResource) Update(ctx context.Context, req resource.UpdateRequ
 		return
 	}
 
+	metadata, diags := data.MetadataModel.toStorage(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	allocation, err := r.provider.storage.GetAllocation(ctx, data.ID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Read Allocation",
+			fmt.Sprintf("Could not read allocation from storage: %s", err),
+		)
+		return
+	}
+
+	expiresAt, diags := resolveExpiry(data.ExpiresAt, data.TTL, time.Now())
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	allocation.Metadata = metadata
+	allocation.ExpiresAt = expiresAt
+	if err := r.provider.storage.SaveAllocation(ctx, allocation); err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Update Allocation",
+			fmt.Sprintf("Could not save allocation to storage: %s", err),
+		)
+		return
+	}
+	data.ExpiresAt = expiresAtValue(data.ExpiresAt, expiresAt)
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
@@ -185,6 +462,24 @@ func (r *AllocationResource) Delete(ctx context.Context, req resource.DeleteRequ
 		return
 	}
 
+	// host addresses taken from the allocation must be released first
+	addresses, err := r.provider.storage.ListAddressesByAllocation(ctx, data.ID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Check Addresses",
+			fmt.Sprintf("Could not check for addresses: %s", err),
+		)
+		return
+	}
+
+	if len(addresses) > 0 {
+		resp.Diagnostics.AddError(
+			"Cannot Delete Allocation",
+			fmt.Sprintf("Allocation %s has %d active addresses. Please delete all addresses before deleting the allocation.", data.ID.ValueString(), len(addresses)),
+		)
+		return
+	}
+
 	if err := r.provider.storage.DeleteAllocation(ctx, data.ID.ValueString()); err != nil {
 		resp.Diagnostics.AddError(
 			"Failed to Delete Allocation",
@@ -216,181 +511,153 @@ func (r *AllocationResource) ImportState(ctx context.Context, req resource.Impor
 		PoolName:      types.StringValue(allocation.PoolName),
 		AllocatedCIDR: types.StringValue(allocation.AllocatedCIDR),
 		PrefixLength:  types.Int64Value(int64(allocation.PrefixLength)),
+		Orphaned:      types.BoolValue(allocation.Orphaned),
+		ExpiresAt:     expiresAtValue(types.StringNull(), allocation.ExpiresAt),
+
+		NetworkDetailsModel: r.provider.networkDetails(ctx, allocation.PoolName, allocation.AllocatedCIDR),
+	}
+
+	metadata, diags := newMetadataModel(ctx, allocation.Metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
+	data.MetadataModel = metadata
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 // allocateCIDRFromPool finds an available CIDR block in the pool and saves it to storage.
-// This implements a greedy search to find non-overlapping CIDR blocks
-// of the requested size within the pool's CIDR ranges.
-func (r *AllocationResource) allocateCIDRFromPool(ctx context.Context, poolName string, allocationId string, prefixLength int) (string, error) {
-	pool, err := r.provider.storage.GetPool(ctx, poolName)
+// The pool's free space is built as a free-list of the pool CIDRs minus existing
+// allocations, reservations and child pools, and a free block of the requested size
+// is picked by the strategy, falling back to the pool's strategy and then to first fit.
+func (r *AllocationResource) allocateCIDRFromPool(ctx context.Context, poolName string, allocationId string, prefixLength int, strategy ipam.Strategy, metadata storage.Metadata, expiresAt *time.Time) (string, error) {
+	space, err := r.provider.loadPool(ctx, poolName, allocationId)
 	if err != nil {
-		return "", fmt.Errorf("pool %s not found: %w", poolName, err)
+		return "", err
 	}
 
-	allocations, err := r.provider.storage.ListAllocationsByPool(ctx, poolName)
+	candidate, strategy, err := space.allocate(prefixLength, strategy)
 	if err != nil {
-		return "", fmt.Errorf("failed to list allocations: %w", err)
+		return "", err
 	}
+	allocatedCIDR := candidate.String()
 
-	var allocatedCIDRs []*net.IPNet
-	for _, alloc := range allocations {
-		_, allocNet, err := net.ParseCIDR(alloc.AllocatedCIDR)
-		if err != nil {
-			continue
-		}
-		allocatedCIDRs = append(allocatedCIDRs, allocNet)
+	if err := r.provider.reclaimExpired(ctx, space); err != nil {
+		return "", err
 	}
 
-	// look for available CIDR block in each pool CIDR
-	for _, poolCIDRStr := range pool.CIDRs {
-		_, poolNet, err := net.ParseCIDR(poolCIDRStr)
-		if err != nil {
-			continue
-		}
+	if err := r.saveAllocation(ctx, allocationId, poolName, candidate, metadata, expiresAt); err != nil {
+		return "", err
+	}
 
-		poolPrefixLen, _ := poolNet.Mask.Size()
+	if err := r.provider.recordAllocation(ctx, space.pool, strategy, candidate); err != nil {
+		return "", err
+	}
 
-		// cant allocate a larger block than the pool itself
-		if prefixLength < poolPrefixLen {
-			continue
-		}
+	tflog.Debug(ctx, "allocated CIDR from pool", map[string]any{
+		"pool_name":      poolName,
+		"allocated_cidr": allocatedCIDR,
+		"strategy":       string(strategy),
+	})
 
-		// search for available cidr
-		candidateCIDR := findAvailableCIDR(poolNet, prefixLength, allocatedCIDRs)
-		if candidateCIDR != nil {
-			allocatedCIDR := candidateCIDR.String()
+	return allocatedCIDR, nil
+}
 
-			// save new allocation to storage
-			allocation := &storage.Allocation{
-				ID:            allocationId,
-				PoolName:      poolName,
-				AllocatedCIDR: allocatedCIDR,
-				PrefixLength:  prefixLength,
-			}
+// claimCIDRFromPool saves an allocation of exactly the requested CIDR to storage.
+// The CIDR must lie within one of the pool's CIDRs and must not overlap an existing
+// allocation, which is reported as an allocationConflictError, a reserved range or
+// a child pool. The pool's cursor follows the claimed CIDR when the strategy, or
+// the pool's strategy if it is empty, is sequential.
+func (r *AllocationResource) claimCIDRFromPool(ctx context.Context, poolName string, allocationId string, requestedCIDR string, strategy ipam.Strategy, metadata storage.Metadata, expiresAt *time.Time) (string, error) {
+	requested, err := netip.ParsePrefix(requestedCIDR)
+	if err != nil {
+		return "", fmt.Errorf("requested CIDR %s is not valid: %w", requestedCIDR, err)
+	}
+	requested = requested.Masked()
 
-			if err := r.provider.storage.SaveAllocation(ctx, allocation); err != nil {
-				return "", fmt.Errorf("failed to save allocation: %w", err)
-			}
+	space, err := r.provider.loadPool(ctx, poolName, allocationId)
+	if err != nil {
+		return "", err
+	}
 
-			return allocatedCIDR, nil
+	for _, alloc := range space.allocations {
+		allocated, err := netip.ParsePrefix(alloc.AllocatedCIDR)
+		if err == nil && allocated.Overlaps(requested) {
+			return "", &allocationConflictError{requested: requested.String(), holder: alloc.ID, held: alloc.AllocatedCIDR}
 		}
 	}
 
-	return "", fmt.Errorf("no available CIDR blocks of size /%d in pool %s", prefixLength, poolName)
-}
+	if reserved := overlappingCIDR(requested.String(), space.pool.ReservedCIDRs); reserved != "" {
+		return "", fmt.Errorf("requested CIDR %s overlaps the reserved range %s of pool %s", requested, reserved, poolName)
+	}
 
-// findAvailableCIDR searches for an available CIDR block of the requested prefix length
-// within the pool CIDR such that it doesn't overlap with any existing allocations.
-func findAvailableCIDR(poolNet *net.IPNet, prefixLength int, allocatedCIDRs []*net.IPNet) *net.IPNet {
-	poolPrefixLen, bits := poolNet.Mask.Size()
-
-	// Calculate number of blocks of the requested size that can fit in the pool
-	blockSizeDiff := prefixLength - poolPrefixLen
-	if blockSizeDiff < 0 {
-		return nil // Requested block is larger than pool
-	}
-	numBlocks := 1 << uint(blockSizeDiff) // 2^(prefixLength - poolPrefixLen)
-
-	// Limit iterations to prevent hanging on large IPv6 address spaces
-	// For IPv6 /32 to /64 allocations, numBlocks can be 2^32 (4 billion+)
-	// Limiting to 100,000 iterations which is more than enough for practical use
-	maxIterations := 100000
-	if numBlocks > maxIterations {
-		numBlocks = maxIterations
-	}
-
-	requestedMask := net.CIDRMask(prefixLength, bits)
-
-	// Iterate through all possible CIDR blocks of the requested size within the pool
-	// and check if they overlap with existing allocations
-	baseIP := poolNet.IP
-	for i := 0; i < numBlocks; i++ {
-		candidateIP := make(net.IP, len(baseIP))
-		copy(candidateIP, baseIP)
-		addIPOffset(candidateIP, i, prefixLength, bits)
-		candidateNet := &net.IPNet{
-			IP:   candidateIP.Mask(requestedMask),
-			Mask: requestedMask,
+	for _, child := range space.children {
+		if cidr := overlappingCIDR(requested.String(), child.CIDRs); cidr != "" {
+			return "", fmt.Errorf("requested CIDR %s overlaps %s of child pool %s", requested, cidr, child.Name)
 		}
+	}
 
-		// edge cases. ensure IP is in pool and last ip is in pool
-		if !poolNet.Contains(candidateNet.IP) {
-			continue
-		}
-		lastIP := getLastIPInCIDR(candidateNet)
-		if !poolNet.Contains(lastIP) {
-			continue
-		}
+	if !space.allocator.Claim(requested) {
+		return "", fmt.Errorf("requested CIDR %s is not within any of the pool CIDRs %s", requested, strings.Join(space.pool.CIDRs, ", "))
+	}
 
-		// check for overlaps with existing allocations
-		if !cidrsOverlap(candidateNet, allocatedCIDRs) {
-			return candidateNet
-		}
+	if err := r.provider.reclaimExpired(ctx, space); err != nil {
+		return "", err
 	}
 
-	return nil
-}
+	if err := r.saveAllocation(ctx, allocationId, poolName, requested, metadata, expiresAt); err != nil {
+		return "", err
+	}
 
-// addIPOffset adds an offset to an IP address based on block size.
-func addIPOffset(ip net.IP, blockIndex int, prefixLength int, totalBits int) {
-	// calculate IPs per block
-	hostBits := totalBits - prefixLength
-
-	if len(ip) == 4 {
-		// IPv4 - simple 32-bit arithmetic
-		blockSize := 1 << uint(hostBits)
-		offset := blockIndex * blockSize
-		ipInt := uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
-		ipInt += uint32(offset)
-		ip[0] = byte(ipInt >> 24)
-		ip[1] = byte(ipInt >> 16)
-		ip[2] = byte(ipInt >> 8)
-		ip[3] = byte(ipInt)
-	} else {
-		// IPv6 - use big.Int for 128 bit arithmetic
-		ipBigInt := big.NewInt(0).SetBytes(ip)
-		offsetBigInt := big.NewInt(int64(blockIndex))
-		blockSizeBigInt := big.NewInt(0).Exp(big.NewInt(2), big.NewInt(int64(hostBits)), nil)
-		offsetBigInt.Mul(offsetBigInt, blockSizeBigInt)
-		ipBigInt.Add(ipBigInt, offsetBigInt)
-		offsetBytes := ipBigInt.Bytes()
-
-		// pad with zeros if necessary
-		copy(ip, make([]byte, len(ip)))
-		copy(ip[len(ip)-len(offsetBytes):], offsetBytes)
+	if strategy == "" {
+		strategy = ipam.Strategy(space.pool.AllocationStrategy)
+	}
+	if err := r.provider.recordAllocation(ctx, space.pool, strategy, requested); err != nil {
+		return "", err
 	}
-}
 
-func getLastIPInCIDR(cidr *net.IPNet) net.IP {
-	ip := make(net.IP, len(cidr.IP))
-	copy(ip, cidr.IP)
+	tflog.Debug(ctx, "claimed requested CIDR from pool", map[string]any{
+		"pool_name":      poolName,
+		"allocated_cidr": requested.String(),
+	})
+
+	return requested.String(), nil
+}
 
-	// invert the mask and OR it with the IP to get the last address
-	for i := range ip {
-		ip[i] |= ^cidr.Mask[i]
+func (r *AllocationResource) saveAllocation(ctx context.Context, allocationId string, poolName string, cidr netip.Prefix, metadata storage.Metadata, expiresAt *time.Time) error {
+	allocation := &storage.Allocation{
+		ID:            allocationId,
+		PoolName:      poolName,
+		AllocatedCIDR: cidr.String(),
+		PrefixLength:  cidr.Bits(),
+		Metadata:      metadata,
+		ExpiresAt:     expiresAt,
 	}
 
-	return ip
+	if err := r.provider.storage.SaveAllocation(ctx, allocation); err != nil {
+		return fmt.Errorf("failed to save allocation: %w", err)
+	}
+	return nil
 }
 
-func cidrsOverlap(candidate *net.IPNet, allocated []*net.IPNet) bool {
-	for _, allocNet := range allocated {
-		// check if either CIDR contains the other's network address
-		if candidate.Contains(allocNet.IP) || allocNet.Contains(candidate.IP) {
-			return true
-		}
+// allocationConflictError reports a requested CIDR that overlaps an existing allocation.
+type allocationConflictError struct {
+	requested string
+	holder    string
+	held      string
+}
 
-		// check if the last IP of candidate is in allocated or vice versa
-		candidateLastIP := getLastIPInCIDR(candidate)
-		allocLastIP := getLastIPInCIDR(allocNet)
+func (e *allocationConflictError) Error() string {
+	return fmt.Sprintf("%s overlaps %s, which is held by allocation %s", e.requested, e.held, e.holder)
+}
 
-		if candidate.Contains(allocLastIP) || allocNet.Contains(candidateLastIP) {
-			return true
-		}
-	}
+// requestedCIDRRequiresReplace replaces the allocation when requested_cidr changes,
+// unless it is removed or now requests the CIDR the allocation already holds, which
+// happens when an existing allocation is imported or adopted.
+func requestedCIDRRequiresReplace(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+	var allocatedCIDR types.String
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("allocated_cidr"), &allocatedCIDR)...)
 
-	return false
+	resp.RequiresReplace = !req.PlanValue.IsNull() && req.PlanValue.ValueString() != allocatedCIDR.ValueString()
 }
@@ -1,14 +1,24 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/compare"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
 	"github.com/hashicorp/terraform-plugin-testing/statecheck"
 	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+
+	"terraform-provider-tfipam/internal/provider/storage"
 )
 
 func TestAccAllocationResource_Basic(t *testing.T) {
@@ -428,6 +438,324 @@ func TestAccAllocationResource_SequentialAllocations(t *testing.T) {
 	})
 }
 
+func TestAccAllocationResource_PoolStrategy(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationResourceConfigStrategy("strategy-pool", "last_fit", ""),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"tfipam_allocation.test",
+						tfjsonpath.New("allocated_cidr"),
+						knownvalue.StringExact("10.0.0.192/26"),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAllocationResource_StrategyOverride(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationResourceConfigStrategy("override-pool", "last_fit", "first_fit"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"tfipam_allocation.test",
+						tfjsonpath.New("allocated_cidr"),
+						knownvalue.StringExact("10.0.0.0/26"),
+					),
+				},
+			},
+			// changing the strategy keeps the allocated CIDR
+			{
+				Config: testAccAllocationResourceConfigStrategy("override-pool", "last_fit", "best_fit"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"tfipam_allocation.test",
+						tfjsonpath.New("allocated_cidr"),
+						knownvalue.StringExact("10.0.0.0/26"),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAllocationResource_InvalidStrategy(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAllocationResourceConfigStrategy("invalid-strategy-pool", "first_fit", "worst_fit"),
+				ExpectError: regexp.MustCompile("Invalid Attribute Value"),
+			},
+		},
+	})
+}
+
+func TestAccAllocationResource_RequestedCIDR(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationResourceConfigRequested("requested-pool", "10.0.200.0/24", 24),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"tfipam_allocation.test",
+						tfjsonpath.New("allocated_cidr"),
+						knownvalue.StringExact("10.0.200.0/24"),
+					),
+					// the next allocation skips the claimed block
+					statecheck.ExpectKnownValue(
+						"tfipam_allocation.next",
+						tfjsonpath.New("allocated_cidr"),
+						knownvalue.StringExact("10.0.0.0/24"),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAllocationResource_RequestedCIDRConflict(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAllocationResourceConfigRequested("conflict-pool", "10.0.0.0/24", 24),
+				ExpectError: regexp.MustCompile(`(?s)Requested CIDR Conflict.*held by allocation\s+conflict-pool-next`),
+			},
+		},
+	})
+}
+
+func TestAccAllocationResource_RequestedCIDROutsidePool(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAllocationResourceConfigRequested("outside-pool", "192.168.0.0/24", 24),
+				ExpectError: regexp.MustCompile("not within any of the pool CIDRs"),
+			},
+		},
+	})
+}
+
+func TestAccAllocationResource_RequestedCIDRPrefixMismatch(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAllocationResourceConfigRequested("mismatch-pool", "10.0.0.0/25", 24),
+				ExpectError: regexp.MustCompile("Requested CIDR Prefix Mismatch"),
+			},
+			{
+				Config:      testAccAllocationResourceConfigRequested("mismatch-pool", "10.0.0.1/24", 24),
+				ExpectError: regexp.MustCompile("host bits set"),
+			},
+		},
+	})
+}
+
+func TestAccAllocationResource_PlannedCIDR(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// the pool has to exist before allocations can be planned
+			{
+				Config: testAccPoolResourceConfig("planned-pool", []string{"10.0.0.0/24"}),
+			},
+			{
+				Config: testAccPoolResourceConfig("planned-pool", []string{"10.0.0.0/24"}) + `
+resource "tfipam_allocation" "first" {
+  id            = "planned-first"
+  pool_name     = "planned-pool"
+  prefix_length = 26
+}
+
+# created after first, which holds the CIDR it was planned with
+resource "tfipam_allocation" "second" {
+  id            = "planned-second"
+  pool_name     = "planned-pool"
+  prefix_length = 26
+
+  depends_on = [tfipam_allocation.first]
+}
+`,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectKnownValue("tfipam_allocation.first", tfjsonpath.New("allocated_cidr"), knownvalue.StringExact("10.0.0.0/26")),
+						// which CIDR second gets depends on when first is created
+						plancheck.ExpectUnknownValue("tfipam_allocation.second", tfjsonpath.New("allocated_cidr")),
+					},
+				},
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.CompareValuePairs(
+						"tfipam_allocation.first", tfjsonpath.New("allocated_cidr"),
+						"tfipam_allocation.second", tfjsonpath.New("allocated_cidr"),
+						compare.ValuesDiffer(),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAllocationResource_NetworkDetails(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "tfipam_pool" "test" {
+  name           = "network-details-pool"
+  cidrs          = ["10.0.1.0/24", "2001:db8::/48"]
+  gateway_offset = -1
+}
+
+resource "tfipam_allocation" "v4" {
+  id            = "network-details-v4"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+}
+
+resource "tfipam_allocation" "v6" {
+  id            = "network-details-v6"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 64
+}
+`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation.v4", tfjsonpath.New("network_address"), knownvalue.StringExact("10.0.1.0")),
+					statecheck.ExpectKnownValue("tfipam_allocation.v4", tfjsonpath.New("netmask"), knownvalue.StringExact("255.255.255.0")),
+					statecheck.ExpectKnownValue("tfipam_allocation.v4", tfjsonpath.New("broadcast_address"), knownvalue.StringExact("10.0.1.255")),
+					statecheck.ExpectKnownValue("tfipam_allocation.v4", tfjsonpath.New("first_usable"), knownvalue.StringExact("10.0.1.1")),
+					statecheck.ExpectKnownValue("tfipam_allocation.v4", tfjsonpath.New("last_usable"), knownvalue.StringExact("10.0.1.254")),
+					statecheck.ExpectKnownValue("tfipam_allocation.v4", tfjsonpath.New("gateway"), knownvalue.StringExact("10.0.1.254")),
+					statecheck.ExpectKnownValue("tfipam_allocation.v4", tfjsonpath.New("host_count"), knownvalue.Int64Exact(254)),
+					statecheck.ExpectKnownValue("tfipam_allocation.v4", tfjsonpath.New("address_family"), knownvalue.StringExact("ipv4")),
+					statecheck.ExpectKnownValue("tfipam_allocation.v6", tfjsonpath.New("broadcast_address"), knownvalue.Null()),
+					statecheck.ExpectKnownValue("tfipam_allocation.v6", tfjsonpath.New("first_usable"), knownvalue.StringExact("2001:db8::1")),
+					statecheck.ExpectKnownValue("tfipam_allocation.v6", tfjsonpath.New("address_family"), knownvalue.StringExact("ipv6")),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAllocationResource_Metadata(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationResourceConfigMetadata("metadata-alloc-pool", "web"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation.test", tfjsonpath.New("description"), knownvalue.StringExact("Subnet for web")),
+					statecheck.ExpectKnownValue("tfipam_allocation.test", tfjsonpath.New("owner"), knownvalue.StringExact("web-team")),
+					statecheck.ExpectKnownValue("tfipam_allocation.test", tfjsonpath.New("tags"), knownvalue.MapExact(map[string]knownvalue.Check{
+						"app": knownvalue.StringExact("web"),
+					})),
+				},
+			},
+			// metadata changes in place and keeps the allocated CIDR
+			{
+				Config: testAccAllocationResourceConfigMetadata("metadata-alloc-pool", "api"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("tfipam_allocation.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation.test", tfjsonpath.New("allocated_cidr"), knownvalue.StringExact("10.0.0.0/24")),
+					statecheck.ExpectKnownValue("tfipam_allocation.test", tfjsonpath.New("description"), knownvalue.StringExact("Subnet for api")),
+					statecheck.ExpectKnownValue("data.tfipam_allocation.test", tfjsonpath.New("tags"), knownvalue.MapExact(map[string]knownvalue.Check{
+						"app": knownvalue.StringExact("api"),
+					})),
+				},
+			},
+			{
+				ResourceName:      "tfipam_allocation.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+				ImportStateId:     "metadata-alloc",
+			},
+		},
+	})
+}
+
+func TestAllocationResource_ModifyPlan(t *testing.T) {
+	ctx := context.Background()
+	r := &AllocationResource{}
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	// plan returns the planned allocated_cidr of a new allocation
+	plan := func(p *IpamProvider, id, strategy string) types.String {
+		t.Helper()
+		values := nullValues(objectType)
+		values["id"] = tftypes.NewValue(tftypes.String, id)
+		values["pool_name"] = tftypes.NewValue(tftypes.String, "pool")
+		values["prefix_length"] = tftypes.NewValue(tftypes.Number, 26)
+		values["allocated_cidr"] = tftypes.NewValue(tftypes.String, tftypes.UnknownValue)
+		if strategy != "" {
+			values["allocation_strategy"] = tftypes.NewValue(tftypes.String, strategy)
+		}
+		raw := tftypes.NewValue(objectType, values)
+
+		req := fwresource.ModifyPlanRequest{
+			Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: raw},
+			Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: raw},
+			State:  tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, nil)},
+		}
+		resp := fwresource.ModifyPlanResponse{Plan: req.Plan}
+		(&AllocationResource{provider: p}).ModifyPlan(ctx, req, &resp)
+		if resp.Diagnostics.HasError() {
+			t.Fatal(resp.Diagnostics)
+		}
+
+		var cidr types.String
+		resp.Diagnostics.Append(resp.Plan.GetAttribute(ctx, path.Root("allocated_cidr"), &cidr)...)
+		return cidr
+	}
+
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/24"}})
+	if got := plan(p, "a", ""); got.ValueString() != "10.0.0.0/26" {
+		t.Fatalf("expected a to be planned at 10.0.0.0/26, got %s", got)
+	}
+
+	// the CIDR of b depends on whether a is created first
+	if got := plan(p, "b", ""); !got.IsUnknown() {
+		t.Fatalf("expected b to be unknown while a is pending, got %s", got)
+	}
+	if got := plan(p, "c", "random"); !got.IsUnknown() {
+		t.Fatalf("expected a random CIDR to be unknown, got %s", got)
+	}
+
+	// planning again in a new provider process gives a the same CIDR
+	again := &IpamProvider{storage: p.storage}
+	if got := plan(again, "a", ""); got.ValueString() != "10.0.0.0/26" {
+		t.Fatalf("expected a to be planned at 10.0.0.0/26 again, got %s", got)
+	}
+	if got := plan(p, "a", ""); got.ValueString() != "10.0.0.0/26" {
+		t.Fatalf("expected a to keep 10.0.0.0/26, got %s", got)
+	}
+}
+
 // testAccAllocationResourceConfig generates a Terraform configuration for an allocation resource.
 func testAccAllocationResourceConfig(poolName, allocID string, prefixLength int) string {
 	return fmt.Sprintf(`
@@ -625,3 +953,78 @@ resource "tfipam_allocation" "test_%[1]d" {
 
 	return config
 }
+
+// testAccAllocationResourceConfigStrategy generates config with a pool strategy and an optional allocation override.
+func testAccAllocationResourceConfigStrategy(poolName, poolStrategy, allocStrategy string) string {
+	override := ""
+	if allocStrategy != "" {
+		override = fmt.Sprintf("allocation_strategy = %q", allocStrategy)
+	}
+
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name                = %[1]q
+  cidrs               = ["10.0.0.0/24"]
+  allocation_strategy = %[2]q
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "%[1]s-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 26
+  %[3]s
+}
+`, poolName, poolStrategy, override)
+}
+
+// testAccAllocationResourceConfigRequested generates config with an allocation that
+// requests a CIDR, created after a second allocation that takes the first free block.
+func testAccAllocationResourceConfigRequested(poolName, requestedCIDR string, prefixLength int) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name  = %[1]q
+  cidrs = ["10.0.0.0/16"]
+}
+
+resource "tfipam_allocation" "next" {
+  id            = "%[1]s-next"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+}
+
+resource "tfipam_allocation" "test" {
+  id             = "%[1]s-requested"
+  pool_name      = tfipam_pool.test.name
+  prefix_length  = %[3]d
+  requested_cidr = %[2]q
+
+  depends_on = [tfipam_allocation.next]
+}
+`, poolName, requestedCIDR, prefixLength)
+}
+
+func testAccAllocationResourceConfigMetadata(poolName, app string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name  = %[1]q
+  cidrs = ["10.0.0.0/16"]
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "metadata-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+  description   = "Subnet for %[2]s"
+  owner         = "web-team"
+  tags = {
+    app = %[2]q
+  }
+}
+
+data "tfipam_allocation" "test" {
+  id         = tfipam_allocation.test.id
+  pool_name  = tfipam_pool.test.name
+  depends_on = [tfipam_allocation.test]
+}
+`, poolName, app)
+}
@@ -0,0 +1,614 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/resourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ resource.Resource = &AllocationSetResource{}
+var _ resource.ResourceWithImportState = &AllocationSetResource{}
+var _ resource.ResourceWithConfigValidators = &AllocationSetResource{}
+var _ resource.ResourceWithModifyPlan = &AllocationSetResource{}
+
+func NewAllocationSetResource() resource.Resource {
+	return &AllocationSetResource{}
+}
+
+type AllocationSetResource struct {
+	provider *IpamProvider
+}
+
+type AllocationSetResourceModel struct {
+	ID              types.String `tfsdk:"id"`
+	PoolName        types.String `tfsdk:"pool_name"`
+	PrefixLength    types.Int64  `tfsdk:"prefix_length"`
+	AllocationCount types.Int64  `tfsdk:"allocation_count"`
+	Keys            types.Set    `tfsdk:"keys"`
+	Strategy        types.String `tfsdk:"allocation_strategy"`
+	CIDRs           types.Map    `tfsdk:"cidrs"`
+	AllocationIDs   types.Map    `tfsdk:"allocation_ids"`
+
+	MetadataModel
+}
+
+// allocationSetMemberID is the ID of the allocation stored for a key of the set.
+func allocationSetMemberID(setID, key string) string {
+	return setID + "/" + key
+}
+
+// keys returns the keys of the set, "0" to "allocation_count - 1" when
+// allocation_count is set and the sorted keys otherwise. Members are allocated
+// in this order.
+func (m AllocationSetResourceModel) keys(ctx context.Context) ([]string, diag.Diagnostics) {
+	if !m.AllocationCount.IsNull() {
+		keys := make([]string, m.AllocationCount.ValueInt64())
+		for i := range keys {
+			keys[i] = strconv.Itoa(i)
+		}
+		return keys, nil
+	}
+
+	var keys []string
+	diags := m.Keys.ElementsAs(ctx, &keys, false)
+	sort.Strings(keys)
+	return keys, diags
+}
+
+func (r *AllocationSetResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_allocation_set"
+}
+
+func (r *AllocationSetResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Allocates a set of equally sized CIDRs from a pool, one per key, in a single storage write",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Unique identifier for this allocation set. Each member is stored as an allocation with the ID `<id>/<key>`, which must not be taken by another allocation.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"pool_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the pool to allocate from",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"prefix_length": schema.Int64Attribute{
+				Required:            true,
+				MarkdownDescription: "Prefix length of every CIDR in the set",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"allocation_count": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Number of CIDRs to allocate, keyed '0' to 'allocation_count - 1'. Exactly one of `allocation_count` and `keys` must be set. Changing it only allocates or releases the keys at the end.",
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
+			"keys": schema.SetAttribute{
+				ElementType:         types.StringType,
+				Optional:            true,
+				MarkdownDescription: "Keys to allocate a CIDR for, such as node names. Exactly one of `allocation_count` and `keys` must be set. Adding or removing keys only allocates or releases those keys.",
+				Validators: []validator.Set{
+					setvalidator.ValueStringsAre(stringvalidator.LengthAtLeast(1)),
+				},
+			},
+			"allocation_strategy": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Overrides the pool's `allocation_strategy` for the members of this set. Supported values: 'first_fit', 'best_fit', 'last_fit', 'random', 'sequential'. Only used when CIDRs are allocated, changing it does not move existing members.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(ipam.Strategies()...),
+				},
+			},
+			"cidrs": schema.MapAttribute{
+				ElementType:         types.StringType,
+				Computed:            true,
+				MarkdownDescription: "The allocated CIDR of each key",
+			},
+			"allocation_ids": schema.MapAttribute{
+				ElementType:         types.StringType,
+				Computed:            true,
+				MarkdownDescription: "The allocation ID of each key, for taking host addresses from a member with `tfipam_address`",
+			},
+		},
+	}
+
+	maps.Copy(resp.Schema.Attributes, metadataResourceAttributes("allocation set"))
+}
+
+func (r *AllocationSetResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.provider = provider
+}
+
+func (r *AllocationSetResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{
+		resourcevalidator.ExactlyOneOf(
+			path.MatchRoot("allocation_count"),
+			path.MatchRoot("keys"),
+		),
+	}
+}
+
+// ModifyPlan plans the allocation ID of every key, and keeps the CIDRs of keys
+// that stay in the set so only added keys show as unknown.
+func (r *AllocationSetResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data AllocationSetResourceModel
+	resp.Diagnostics.Append(resp.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.ID.IsUnknown() || data.AllocationCount.IsUnknown() || data.Keys.IsUnknown() {
+		return
+	}
+	keys, diags := data.keys(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ids := make(map[string]attr.Value, len(keys))
+	cidrs := make(map[string]attr.Value, len(keys))
+	for _, key := range keys {
+		ids[key] = types.StringValue(allocationSetMemberID(data.ID.ValueString(), key))
+		cidrs[key] = types.StringUnknown()
+	}
+
+	if !req.State.Raw.IsNull() && len(resp.RequiresReplace) == 0 {
+		var state AllocationSetResourceModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		for key, cidr := range state.CIDRs.Elements() {
+			if _, ok := cidrs[key]; ok {
+				cidrs[key] = cidr
+			}
+		}
+	}
+
+	data.AllocationIDs = types.MapValueMust(types.StringType, ids)
+	data.CIDRs = types.MapValueMust(types.StringType, cidrs)
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &data)...)
+}
+
+func (r *AllocationSetResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data AllocationSetResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	prefixLength := int(data.PrefixLength.ValueInt64())
+	if prefixLength < 0 || prefixLength > 128 {
+		resp.Diagnostics.AddError(
+			"Invalid Prefix Length",
+			fmt.Sprintf("Prefix length must be between 0 and 128, got %d", prefixLength),
+		)
+		return
+	}
+
+	keys, diags := data.keys(ctx)
+	resp.Diagnostics.Append(diags...)
+	metadata, diags := data.MetadataModel.toStorage(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	allocated, err := r.allocateMembers(ctx, &data, keys, metadata, &storage.Batch{})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Allocation Failed",
+			fmt.Sprintf("Unable to allocate %d CIDR(s) from pool %s: %s", len(keys), data.PoolName.ValueString(), err),
+		)
+		return
+	}
+
+	data.setMembers(allocated)
+
+	tflog.Trace(ctx, "created allocation set resource", map[string]any{
+		"id":        data.ID.ValueString(),
+		"pool_name": data.PoolName.ValueString(),
+		"members":   len(allocated),
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *AllocationSetResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data AllocationSetResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	members, err := r.members(ctx, data.PoolName.ValueString(), data.ID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Read Allocation Set",
+			fmt.Sprintf("Could not read allocations from storage: %s", err),
+		)
+		return
+	}
+
+	// members deleted outside Terraform drop out of the maps and are allocated again
+	var kept []storage.Allocation
+	for key := range data.AllocationIDs.Elements() {
+		if alloc, ok := members[key]; ok {
+			kept = append(kept, alloc)
+		}
+	}
+	if len(kept) == 0 && len(data.AllocationIDs.Elements()) > 0 {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	data.setMembers(kept)
+
+	if len(kept) > 0 {
+		metadata, diags := data.MetadataModel.sync(ctx, kept[0].Metadata)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.MetadataModel = metadata
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Update only touches the keys that were added or removed. Members that stay in
+// the set keep their CIDR, and are only written when the metadata changed.
+func (r *AllocationSetResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state AllocationSetResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	keys, diags := data.keys(ctx)
+	resp.Diagnostics.Append(diags...)
+	metadata, diags := data.MetadataModel.toStorage(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	setID := data.ID.ValueString()
+	members, err := r.members(ctx, data.PoolName.ValueString(), setID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Read Allocation Set",
+			fmt.Sprintf("Could not read allocations from storage: %s", err),
+		)
+		return
+	}
+
+	var added []string
+	var kept []storage.Allocation
+	for _, key := range keys {
+		if alloc, ok := members[key]; ok {
+			kept = append(kept, alloc)
+		} else {
+			added = append(added, key)
+		}
+	}
+
+	var removed []string
+	for key := range members {
+		if !slices.Contains(keys, key) {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+
+	// removed members, metadata changes and new members are written together
+	batch := &storage.Batch{}
+	ids, diags := r.memberDeletes(ctx, members, removed)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	batch.DeleteAllocations = ids
+
+	if !metadataEqual(data.MetadataModel, state.MetadataModel) {
+		for i := range kept {
+			kept[i].Metadata = metadata
+		}
+		batch.SaveAllocations = kept
+	}
+
+	allocated, err := r.allocateMembers(ctx, &data, added, metadata, batch)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Allocation Failed",
+			fmt.Sprintf("Unable to allocate %d CIDR(s) from pool %s: %s", len(added), data.PoolName.ValueString(), err),
+		)
+		return
+	}
+
+	data.setMembers(append(kept, allocated...))
+
+	tflog.Trace(ctx, "updated allocation set resource", map[string]any{
+		"id":      setID,
+		"added":   len(added),
+		"removed": len(removed),
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *AllocationSetResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data AllocationSetResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	members, err := r.members(ctx, data.PoolName.ValueString(), data.ID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Read Allocation Set",
+			fmt.Sprintf("Could not read allocations from storage: %s", err),
+		)
+		return
+	}
+
+	keys := slices.Sorted(maps.Keys(data.AllocationIDs.Elements()))
+	ids, diags := r.memberDeletes(ctx, members, keys)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.provider.storage.Apply(ctx, &storage.Batch{DeleteAllocations: ids}); err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Delete Allocations",
+			fmt.Sprintf("Could not delete allocations from storage: %s", err),
+		)
+		return
+	}
+
+	tflog.Trace(ctx, "deleted allocation set resource", map[string]any{
+		"id":        data.ID.ValueString(),
+		"pool_name": data.PoolName.ValueString(),
+	})
+}
+
+func (r *AllocationSetResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// For import we expect the ID to be the set ID, the members are the
+	// allocations stored for it
+	setID := req.ID
+
+	allocations, err := r.provider.storage.ListAllocations(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Read Allocation Set",
+			fmt.Sprintf("Could not read allocations from storage: %s", err),
+		)
+		return
+	}
+
+	var members []storage.Allocation
+	var keys []attr.Value
+	for _, alloc := range allocations {
+		if alloc.AllocationSet == setID {
+			members = append(members, alloc)
+			keys = append(keys, types.StringValue(strings.TrimPrefix(alloc.ID, setID+"/")))
+		}
+	}
+	if len(members) == 0 {
+		resp.Diagnostics.AddError(
+			"Allocation Set Not Found",
+			fmt.Sprintf("No allocations of set %s found in storage", setID),
+		)
+		return
+	}
+
+	data := AllocationSetResourceModel{
+		ID:              types.StringValue(setID),
+		PoolName:        types.StringValue(members[0].PoolName),
+		PrefixLength:    types.Int64Value(int64(members[0].PrefixLength)),
+		AllocationCount: types.Int64Null(),
+		Keys:            types.SetValueMust(types.StringType, keys),
+		Strategy:        types.StringNull(),
+	}
+	data.setMembers(members)
+
+	metadata, diags := newMetadataModel(ctx, members[0].Metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.MetadataModel = metadata
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// setMembers sets cidrs and allocation_ids to the given members of the set.
+func (m *AllocationSetResourceModel) setMembers(members []storage.Allocation) {
+	ids := make(map[string]attr.Value, len(members))
+	cidrs := make(map[string]attr.Value, len(members))
+	for _, alloc := range members {
+		key := strings.TrimPrefix(alloc.ID, m.ID.ValueString()+"/")
+		ids[key] = types.StringValue(alloc.ID)
+		cidrs[key] = types.StringValue(alloc.AllocatedCIDR)
+	}
+	m.AllocationIDs = types.MapValueMust(types.StringType, ids)
+	m.CIDRs = types.MapValueMust(types.StringType, cidrs)
+}
+
+// members returns the stored allocations of the set in the pool by key. Members
+// are matched by the set they were allocated for rather than by their ID, since
+// another set or a plain allocation may have an ID starting with the set ID.
+func (r *AllocationSetResource) members(ctx context.Context, poolName, setID string) (map[string]storage.Allocation, error) {
+	allocations, err := r.provider.storage.ListAllocationsByPool(ctx, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]storage.Allocation)
+	for _, alloc := range allocations {
+		if alloc.AllocationSet == setID {
+			members[strings.TrimPrefix(alloc.ID, setID+"/")] = alloc
+		}
+	}
+	return members, nil
+}
+
+// allocateMembers allocates a CIDR for each key from one view of the pool's free
+// space, adds the new members to batch together with the removal of expired
+// allocations and the pool's cursor, and applies batch with a single storage
+// write. CIDRs of allocations deleted by batch can be handed out again. Nothing
+// is saved if the ID of a member is already taken by another allocation.
+func (r *AllocationSetResource) allocateMembers(ctx context.Context, data *AllocationSetResourceModel, keys []string, metadata storage.Metadata, batch *storage.Batch) ([]storage.Allocation, error) {
+	poolName := data.PoolName.ValueString()
+	allocations := make([]storage.Allocation, 0, len(keys))
+	var reclaimed []storage.Allocation
+	var strategy ipam.Strategy
+
+	if len(keys) > 0 {
+		space, err := r.provider.loadPoolReleasing(ctx, poolName, "", batch.DeleteAllocations)
+		if err != nil {
+			return nil, err
+		}
+
+		var last netip.Prefix
+		for _, key := range keys {
+			last, strategy, err = space.allocate(int(data.PrefixLength.ValueInt64()), ipam.Strategy(data.Strategy.ValueString()))
+			if err != nil {
+				return nil, fmt.Errorf("%w, %d of %d CIDR(s) fit", err, len(allocations), len(keys))
+			}
+			allocations = append(allocations, storage.Allocation{
+				ID:            allocationSetMemberID(data.ID.ValueString(), key),
+				PoolName:      poolName,
+				AllocatedCIDR: last.String(),
+				PrefixLength:  last.Bits(),
+				Metadata:      metadata,
+				AllocationSet: data.ID.ValueString(),
+			})
+		}
+
+		reclaimed, err = r.provider.reclaimBatch(ctx, space, batch)
+		if err != nil {
+			return nil, err
+		}
+		batch.CreateAllocations = append(batch.CreateAllocations, allocations...)
+		if strategy == ipam.Sequential {
+			batch.SetCursor(poolName, last.String())
+		}
+	}
+
+	if batch.IsEmpty() {
+		return allocations, nil
+	}
+	if err := r.provider.storage.Apply(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to save allocations: %w", err)
+	}
+	logReclaimed(ctx, reclaimed)
+
+	if len(allocations) > 0 {
+		tflog.Debug(ctx, "allocated CIDRs from pool", map[string]any{
+			"pool_name": poolName,
+			"count":     len(allocations),
+			"strategy":  string(strategy),
+		})
+	}
+
+	return allocations, nil
+}
+
+// memberDeletes returns the IDs of the members of the given keys, for a batch
+// that removes them. Keys without a member are skipped. Like allocations,
+// members with host addresses can't be deleted. The addresses are checked
+// before the batch is written, so an address created in between isn't noticed.
+func (r *AllocationSetResource) memberDeletes(ctx context.Context, members map[string]storage.Allocation, keys []string) ([]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		member, ok := members[key]
+		if !ok {
+			continue
+		}
+		id := member.ID
+		addresses, err := r.provider.storage.ListAddressesByAllocation(ctx, id)
+		if err != nil {
+			diags.AddError(
+				"Failed to Check Addresses",
+				fmt.Sprintf("Could not check for addresses: %s", err),
+			)
+			return nil, diags
+		}
+		if len(addresses) > 0 {
+			diags.AddError(
+				"Cannot Delete Allocation",
+				fmt.Sprintf("Allocation %s has %d active addresses. Please delete all addresses before removing key %s from the set.", id, len(addresses), key),
+			)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if diags.HasError() {
+		return nil, diags
+	}
+	return ids, diags
+}
+
+// metadataEqual reports whether two metadata models describe the same metadata.
+func metadataEqual(a, b MetadataModel) bool {
+	return a.Description.Equal(b.Description) && a.Owner.Equal(b.Owner) && a.Tags.Equal(b.Tags)
+}
@@ -0,0 +1,244 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestAccAllocationSetResource_Count(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationSetResourceConfig("set-pool", "allocation_count = 3"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation_set.test", tfjsonpath.New("cidrs"), knownvalue.MapExact(map[string]knownvalue.Check{
+						"0": knownvalue.StringExact("10.80.0.0/26"),
+						"1": knownvalue.StringExact("10.80.0.64/26"),
+						"2": knownvalue.StringExact("10.80.0.128/26"),
+					})),
+					statecheck.ExpectKnownValue("tfipam_allocation_set.test", tfjsonpath.New("allocation_ids").AtMapKey("1"), knownvalue.StringExact("nodes/1")),
+				},
+			},
+			// shrinking keeps the remaining CIDRs
+			{
+				Config: testAccAllocationSetResourceConfig("set-pool", "allocation_count = 2"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("tfipam_allocation_set.test", plancheck.ResourceActionUpdate),
+						plancheck.ExpectKnownValue("tfipam_allocation_set.test", tfjsonpath.New("cidrs").AtMapKey("1"), knownvalue.StringExact("10.80.0.64/26")),
+					},
+				},
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation_set.test", tfjsonpath.New("cidrs"), knownvalue.MapSizeExact(2)),
+				},
+			},
+			// growing only allocates the added keys
+			{
+				Config: testAccAllocationSetResourceConfig("set-pool", "allocation_count = 4"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectKnownValue("tfipam_allocation_set.test", tfjsonpath.New("cidrs").AtMapKey("0"), knownvalue.StringExact("10.80.0.0/26")),
+						plancheck.ExpectUnknownValue("tfipam_allocation_set.test", tfjsonpath.New("cidrs").AtMapKey("3")),
+					},
+				},
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation_set.test", tfjsonpath.New("cidrs").AtMapKey("2"), knownvalue.StringExact("10.80.0.128/26")),
+					statecheck.ExpectKnownValue("tfipam_allocation_set.test", tfjsonpath.New("cidrs").AtMapKey("3"), knownvalue.StringExact("10.80.0.192/26")),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAllocationSetResource_Keys(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationSetResourceConfig("set-keys-pool", `keys = ["node-a", "node-b"]`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation_set.test", tfjsonpath.New("cidrs").AtMapKey("node-b"), knownvalue.StringExact("10.80.0.64/26")),
+				},
+			},
+			{
+				Config: testAccAllocationSetResourceConfig("set-keys-pool", `keys = ["node-b", "node-c"]`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_allocation_set.test", tfjsonpath.New("cidrs"), knownvalue.MapExact(map[string]knownvalue.Check{
+						"node-b": knownvalue.StringExact("10.80.0.64/26"),
+						"node-c": knownvalue.StringExact("10.80.0.0/26"),
+					})),
+				},
+			},
+			{
+				ResourceName:            "tfipam_allocation_set.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateId:           "nodes",
+				ImportStateVerifyIgnore: []string{"allocation_strategy"},
+			},
+		},
+	})
+}
+
+func TestAccAllocationSetResource_ConflictingKeys(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAllocationSetResourceConfig("set-pool", "allocation_count = 2\n  keys = [\"a\"]"),
+				ExpectError: regexp.MustCompile("Invalid Attribute Combination"),
+			},
+		},
+	})
+}
+
+func TestAllocationSetMembers(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/24"}})
+	if err := p.storage.SaveAllocation(ctx, &storage.Allocation{ID: "other", PoolName: "pool", AllocatedCIDR: "10.0.0.64/26", PrefixLength: 26}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &AllocationSetResource{provider: p}
+	data := &AllocationSetResourceModel{
+		ID:           types.StringValue("nodes"),
+		PoolName:     types.StringValue("pool"),
+		PrefixLength: types.Int64Value(26),
+	}
+
+	allocated, err := r.allocateMembers(ctx, data, []string{"a", "b", "c"}, storage.Metadata{Owner: "platform"}, &storage.Batch{})
+	if err != nil || len(allocated) != 3 {
+		t.Fatalf("expected 3 members, got %v (%v)", allocated, err)
+	}
+	// members skip the other allocation and each other
+	for i, want := range []string{"10.0.0.0/26", "10.0.0.128/26", "10.0.0.192/26"} {
+		if allocated[i].AllocatedCIDR != want {
+			t.Errorf("expected member %d at %s, got %s", i, want, allocated[i].AllocatedCIDR)
+		}
+	}
+
+	// nothing is saved when not all keys fit
+	if _, err := r.allocateMembers(ctx, data, []string{"d"}, storage.Metadata{}, &storage.Batch{}); err == nil {
+		t.Fatal("expected a full pool to fail")
+	}
+
+	members, _ := r.members(ctx, "pool", "nodes")
+	if len(members) != 3 || members["b"].Owner != "platform" {
+		t.Fatalf("expected 3 stored members with metadata, got %v", members)
+	}
+
+	// members holding host addresses can't be removed
+	if err := p.storage.SaveAddress(ctx, &storage.Address{ID: "host", AllocationID: "nodes/a", Address: "10.0.0.10"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, diags := r.memberDeletes(ctx, members, []string{"a", "b"}); !diags.HasError() {
+		t.Fatal("expected removing a member with addresses to fail")
+	}
+
+	// removed members free their CIDRs for the members added in the same write
+	ids, diags := r.memberDeletes(ctx, members, []string{"b", "c", "missing"})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	allocated, err = r.allocateMembers(ctx, data, []string{"d"}, storage.Metadata{}, &storage.Batch{DeleteAllocations: ids})
+	if err != nil || len(allocated) != 1 || allocated[0].AllocatedCIDR != "10.0.0.128/26" {
+		t.Fatalf("expected member d to take the CIDR of b, got %v (%v)", allocated, err)
+	}
+	if members, _ := r.members(ctx, "pool", "nodes"); len(members) != 2 || members["a"].ID == "" || members["d"].ID == "" {
+		t.Fatalf("expected members a and d, got %v", members)
+	}
+	if _, err := p.storage.GetAllocation(ctx, "other"); err != nil {
+		t.Fatalf("expected the other allocation to be kept, got %v", err)
+	}
+}
+
+func TestAllocationSetMembers_Collisions(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/24"}})
+	r := &AllocationSetResource{provider: p}
+	newSet := func(id string) *AllocationSetResourceModel {
+		return &AllocationSetResourceModel{
+			ID:           types.StringValue(id),
+			PoolName:     types.StringValue("pool"),
+			PrefixLength: types.Int64Value(28),
+		}
+	}
+
+	// a plain allocation and a nested set whose IDs start with "a/"
+	plain := storage.Allocation{ID: "a/x", PoolName: "pool", AllocatedCIDR: "10.0.0.240/28", PrefixLength: 28}
+	if err := p.storage.SaveAllocation(ctx, &plain); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.allocateMembers(ctx, newSet("a/b"), []string{"0", "1"}, storage.Metadata{}, &storage.Batch{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.allocateMembers(ctx, newSet("a"), []string{"0"}, storage.Metadata{}, &storage.Batch{}); err != nil {
+		t.Fatal(err)
+	}
+
+	members, err := r.members(ctx, "pool", "a")
+	if err != nil || len(members) != 1 || members["0"].ID != "a/0" {
+		t.Fatalf("expected only a/0 to be a member of a, got %v (%v)", members, err)
+	}
+	if members, _ := r.members(ctx, "pool", "a/b"); len(members) != 2 {
+		t.Fatalf("expected 2 members of a/b, got %v", members)
+	}
+
+	// a key whose member ID is taken is refused, and nothing is saved
+	for _, keys := range [][]string{{"1", "x"}, {"1", "b/0"}} {
+		if _, err := r.allocateMembers(ctx, newSet("a"), keys, storage.Metadata{}, &storage.Batch{}); !errors.Is(err, storage.ErrExists) {
+			t.Errorf("keys %v: expected the taken ID to be refused, got %v", keys, err)
+		}
+	}
+	if got, _ := p.storage.GetAllocation(ctx, "a/x"); got == nil || got.AllocatedCIDR != plain.AllocatedCIDR || got.AllocationSet != "" {
+		t.Errorf("expected the plain allocation to be unchanged, got %+v", got)
+	}
+	if _, err := p.storage.GetAllocation(ctx, "a/1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected no member to be saved for a refused batch, got %v", err)
+	}
+
+	// deleting the keys of a, even keys it doesn't have, leaves the others alone
+	ids, diags := r.memberDeletes(ctx, members, []string{"0", "x", "b/0"})
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if err := p.storage.Apply(ctx, &storage.Batch{DeleteAllocations: ids}); err != nil {
+		t.Fatal(err)
+	}
+	allocations, _ := p.storage.ListAllocations(ctx)
+	if len(allocations) != 3 {
+		t.Fatalf("expected a/x, a/b/0 and a/b/1 to be kept, got %v", allocations)
+	}
+}
+
+func testAccAllocationSetResourceConfig(poolName, keys string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name  = %[1]q
+  cidrs = ["10.80.0.0/16"]
+}
+
+resource "tfipam_allocation_set" "test" {
+  id            = "nodes"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 26
+  %[2]s
+}
+`, poolName, keys)
+}
@@ -0,0 +1,213 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ datasource.DataSource = &AllocationsDataSource{}
+
+func NewAllocationsDataSource() datasource.DataSource {
+	return &AllocationsDataSource{}
+}
+
+type AllocationsDataSource struct {
+	provider *IpamProvider
+}
+
+type AllocationsDataSourceModel struct {
+	PoolName      types.String `tfsdk:"pool_name"`
+	IDRegex       types.String `tfsdk:"id_regex"`
+	CIDR          types.String `tfsdk:"cidr"`
+	PrefixLength  types.Int64  `tfsdk:"prefix_length"`
+	AddressFamily types.String `tfsdk:"address_family"`
+	Tags          types.Map    `tfsdk:"tags"`
+
+	IDs         []string                          `tfsdk:"ids"`
+	Allocations []AllocationsDataSourceEntryModel `tfsdk:"allocations"`
+}
+
+type AllocationsDataSourceEntryModel struct {
+	ID            types.String `tfsdk:"id"`
+	PoolName      types.String `tfsdk:"pool_name"`
+	AllocatedCIDR types.String `tfsdk:"allocated_cidr"`
+	PrefixLength  types.Int64  `tfsdk:"prefix_length"`
+	Orphaned      types.Bool   `tfsdk:"orphaned"`
+
+	MetadataModel
+}
+
+func (d *AllocationsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_allocations"
+}
+
+func (d *AllocationsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "IPAM allocations data source for listing allocations, sorted by ID. All filters are optional and must all match.",
+
+		Attributes: map[string]schema.Attribute{
+			"pool_name": schema.StringAttribute{
+				MarkdownDescription: "Only list allocations from this pool",
+				Optional:            true,
+			},
+			"id_regex": schema.StringAttribute{
+				MarkdownDescription: "Only list allocations whose ID matches this regular expression",
+				Optional:            true,
+			},
+			"cidr": schema.StringAttribute{
+				MarkdownDescription: "Only list allocations that lie within this CIDR",
+				Optional:            true,
+			},
+			"prefix_length": schema.Int64Attribute{
+				MarkdownDescription: "Only list allocations with this prefix length",
+				Optional:            true,
+			},
+			"address_family": schema.StringAttribute{
+				MarkdownDescription: "Only list allocations of this address family, `ipv4` or `ipv6`",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf("ipv4", "ipv6"),
+				},
+			},
+			"tags": schema.MapAttribute{
+				MarkdownDescription: "Only list allocations that have all of these tags",
+				Optional:            true,
+				ElementType:         types.StringType,
+			},
+			"ids": schema.ListAttribute{
+				MarkdownDescription: "IDs of the matching allocations",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+			"allocations": schema.ListNestedAttribute{
+				MarkdownDescription: "The matching allocations",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: allocationsDataSourceEntryAttributes(),
+				},
+			},
+		},
+	}
+}
+
+// allocationsDataSourceEntryAttributes are the attributes of an allocation in
+// the list, also used for the allocation found by the lookup data source.
+func allocationsDataSourceEntryAttributes() map[string]schema.Attribute {
+	attributes := map[string]schema.Attribute{
+		"id": schema.StringAttribute{
+			MarkdownDescription: "Unique identifier of the allocation",
+			Computed:            true,
+		},
+		"pool_name": schema.StringAttribute{
+			MarkdownDescription: "Name of the pool the allocation belongs to",
+			Computed:            true,
+		},
+		"allocated_cidr": schema.StringAttribute{
+			MarkdownDescription: "CIDR block allocated to the resource",
+			Computed:            true,
+		},
+		"prefix_length": schema.Int64Attribute{
+			MarkdownDescription: "Prefix length of the allocated CIDR",
+			Computed:            true,
+		},
+		"orphaned": schema.BoolAttribute{
+			MarkdownDescription: "Whether the pool CIDRs no longer contain the allocated CIDR",
+			Computed:            true,
+		},
+	}
+	maps.Copy(attributes, metadataDataSourceAttributes("allocation"))
+	return attributes
+}
+
+func (d *AllocationsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.provider = provider
+}
+
+func (d *AllocationsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data AllocationsDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	filter, diags := newListFilter(ctx, "id_regex", data.IDRegex, data.CIDR, data.PrefixLength, data.AddressFamily, data.Tags)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var allocations []storage.Allocation
+	var err error
+	if poolName := data.PoolName.ValueString(); poolName != "" {
+		allocations, err = d.provider.storage.ListAllocationsByPool(ctx, poolName)
+	} else {
+		allocations, err = d.provider.storage.ListAllocations(ctx)
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to List Allocations",
+			fmt.Sprintf("Could not list allocations from storage: %s", err),
+		)
+		return
+	}
+
+	// storage returns allocations in map order
+	sort.Slice(allocations, func(i, j int) bool { return allocations[i].ID < allocations[j].ID })
+
+	data.IDs = []string{}
+	data.Allocations = []AllocationsDataSourceEntryModel{}
+	for _, alloc := range allocations {
+		if !filter.matchName(alloc.ID) || !filter.matchCIDR(alloc.AllocatedCIDR) || !filter.matchMetadata(alloc.Metadata) {
+			continue
+		}
+
+		entry, diags := newAllocationsDataSourceEntry(ctx, alloc)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		data.IDs = append(data.IDs, alloc.ID)
+		data.Allocations = append(data.Allocations, entry)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// newAllocationsDataSourceEntry converts a stored allocation to a list entry.
+func newAllocationsDataSourceEntry(ctx context.Context, alloc storage.Allocation) (AllocationsDataSourceEntryModel, diag.Diagnostics) {
+	metadata, diags := newMetadataModel(ctx, alloc.Metadata)
+	return AllocationsDataSourceEntryModel{
+		ID:            types.StringValue(alloc.ID),
+		PoolName:      types.StringValue(alloc.PoolName),
+		AllocatedCIDR: types.StringValue(alloc.AllocatedCIDR),
+		PrefixLength:  types.Int64Value(int64(alloc.PrefixLength)),
+		Orphaned:      types.BoolValue(alloc.Orphaned),
+		MetadataModel: metadata,
+	}, diags
+}
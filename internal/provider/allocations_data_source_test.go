@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+)
+
+func TestAccAllocationsDataSource_Basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationsDataSourceConfig("list-alloc-pool", ""),
+				ConfigStateChecks: []statecheck.StateCheck{
+					// sorted by ID, not by creation order
+					statecheck.ExpectKnownValue(
+						"data.tfipam_allocations.test",
+						tfjsonpath.New("ids"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("list-alloc-a"),
+							knownvalue.StringExact("list-alloc-b"),
+							knownvalue.StringExact("list-alloc-c"),
+						}),
+					),
+					statecheck.ExpectKnownValue(
+						"data.tfipam_allocations.test",
+						tfjsonpath.New("allocations").AtSliceIndex(0).AtMapKey("pool_name"),
+						knownvalue.StringExact("list-alloc-pool"),
+					),
+					statecheck.ExpectKnownValue(
+						"data.tfipam_allocations.test",
+						tfjsonpath.New("allocations").AtSliceIndex(2).AtMapKey("tags"),
+						knownvalue.MapExact(map[string]knownvalue.Check{
+							"env": knownvalue.StringExact("prod"),
+						}),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAllocationsDataSource_Filters(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAllocationsDataSourceConfig("filter-alloc-pool", `prefix_length = 24`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.tfipam_allocations.test",
+						tfjsonpath.New("ids"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("list-alloc-a"),
+							knownvalue.StringExact("list-alloc-c"),
+						}),
+					),
+				},
+			},
+			{
+				Config: testAccAllocationsDataSourceConfig("filter-alloc-pool", `tags = { env = "prod" }`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.tfipam_allocations.test",
+						tfjsonpath.New("ids"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("list-alloc-c"),
+						}),
+					),
+				},
+			},
+			{
+				Config: testAccAllocationsDataSourceConfig("filter-alloc-pool", `id_regex = "-[ab]$"`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.tfipam_allocations.test",
+						tfjsonpath.New("ids"),
+						knownvalue.ListSizeExact(2),
+					),
+				},
+			},
+			{
+				Config: testAccAllocationsDataSourceConfig("filter-alloc-pool", `address_family = "ipv6"`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.tfipam_allocations.test",
+						tfjsonpath.New("allocations"),
+						knownvalue.ListSizeExact(0),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAllocationsDataSource_InvalidRegex(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAllocationsDataSourceConfig("regex-alloc-pool", `id_regex = "("`),
+				ExpectError: regexp.MustCompile("Invalid Regular Expression"),
+			},
+		},
+	})
+}
+
+// testAccAllocationsDataSourceConfig generates a pool with three allocations, created
+// out of ID order, and a list data source with the given extra filter.
+func testAccAllocationsDataSourceConfig(poolName, filter string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name  = %[1]q
+  cidrs = ["10.0.0.0/16"]
+}
+
+resource "tfipam_allocation" "c" {
+  id            = "list-alloc-c"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+  tags          = { env = "prod" }
+}
+
+resource "tfipam_allocation" "a" {
+  id            = "list-alloc-a"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+  depends_on    = [tfipam_allocation.c]
+}
+
+resource "tfipam_allocation" "b" {
+  id            = "list-alloc-b"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 26
+  depends_on    = [tfipam_allocation.a]
+}
+
+data "tfipam_allocations" "test" {
+  pool_name = tfipam_pool.test.name
+  %[2]s
+
+  depends_on = [tfipam_allocation.a, tfipam_allocation.b, tfipam_allocation.c]
+}
+`, poolName, filter)
+}
@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+)
+
+var _ datasource.DataSource = &AvailableCIDRsDataSource{}
+
+// defaultAvailableCIDRsLimit matches the default of the `tfipam free` command.
+const defaultAvailableCIDRsLimit = 10
+
+func NewAvailableCIDRsDataSource() datasource.DataSource {
+	return &AvailableCIDRsDataSource{}
+}
+
+type AvailableCIDRsDataSource struct {
+	provider *IpamProvider
+}
+
+type AvailableCIDRsDataSourceModel struct {
+	PoolName     types.String `tfsdk:"pool_name"`
+	PrefixLength types.Int64  `tfsdk:"prefix_length"`
+	Limit        types.Int64  `tfsdk:"limit"`
+	CIDRs        []string     `tfsdk:"cidrs"`
+}
+
+func (d *AvailableCIDRsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_available_cidrs"
+}
+
+func (d *AvailableCIDRsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Available CIDRs data source for previewing the free blocks of a pool without allocating them",
+
+		Attributes: map[string]schema.Attribute{
+			"pool_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the pool to search",
+				Required:            true,
+			},
+			"prefix_length": schema.Int64Attribute{
+				MarkdownDescription: "Prefix length of the free blocks to list",
+				Required:            true,
+			},
+			"limit": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("Maximum number of free blocks to list. Defaults to `%d`.", defaultAvailableCIDRsLimit),
+				Optional:            true,
+			},
+			"cidrs": schema.ListAttribute{
+				MarkdownDescription: "The free blocks, in the order allocations from the pool would get them",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+		},
+	}
+}
+
+func (d *AvailableCIDRsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.provider = provider
+}
+
+func (d *AvailableCIDRsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data AvailableCIDRsDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	prefixLength := int(data.PrefixLength.ValueInt64())
+	if prefixLength < 0 || prefixLength > 128 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("prefix_length"),
+			"Invalid Prefix Length",
+			fmt.Sprintf("Prefix length must be between 0 and 128, got %d", prefixLength),
+		)
+		return
+	}
+
+	limit := int64(defaultAvailableCIDRsLimit)
+	if !data.Limit.IsNull() {
+		limit = data.Limit.ValueInt64()
+	}
+	if limit < 1 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("limit"),
+			"Invalid Limit",
+			fmt.Sprintf("Limit must be at least 1, got %d", limit),
+		)
+		return
+	}
+
+	cidrs, err := d.provider.availableCIDRs(ctx, data.PoolName.ValueString(), prefixLength, int(limit))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Read Available CIDRs",
+			fmt.Sprintf("Could not search pool %s: %s", data.PoolName.ValueString(), err),
+		)
+		return
+	}
+
+	data.CIDRs = cidrs
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// availableCIDRs returns up to limit free blocks of the prefix length, found
+// by the same search allocations use, with every block found treated as taken.
+// Only the in-memory free-list is changed, nothing is written to storage.
+// The random strategy lists blocks in first fit order so the result is stable.
+func (p *IpamProvider) availableCIDRs(ctx context.Context, poolName string, prefixLength int, limit int) ([]string, error) {
+	space, err := p.loadPool(ctx, poolName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := ipam.Strategy(space.pool.AllocationStrategy)
+	if strategy == ipam.Random {
+		strategy = ipam.FirstFit
+	}
+
+	cidrs := []string{}
+	for len(cidrs) < limit {
+		candidate, ok := space.allocator.AllocateWith(prefixLength, strategy)
+		if !ok {
+			break
+		}
+		cidrs = append(cidrs, candidate.String())
+	}
+	return cidrs, nil
+}
@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestAccAvailableCIDRsDataSource_Basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAvailableCIDRsDataSourceConfig("avail-pool", 3),
+				ConfigStateChecks: []statecheck.StateCheck{
+					// 10.0.0.0/26 is reserved and 10.0.0.64/26 allocated
+					statecheck.ExpectKnownValue(
+						"data.tfipam_available_cidrs.test",
+						tfjsonpath.New("cidrs"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("10.0.0.128/26"),
+							knownvalue.StringExact("10.0.0.192/26"),
+						}),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccAvailableCIDRsDataSource_InvalidLimit(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccAvailableCIDRsDataSourceConfig("avail-limit-pool", 0),
+				ExpectError: regexp.MustCompile("Invalid Limit"),
+			},
+		},
+	})
+}
+
+func TestAvailableCIDRs(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{
+		Name:          "pool",
+		CIDRs:         []string{"10.0.0.0/24"},
+		ReservedCIDRs: []string{"10.0.0.0/26"},
+	})
+	p.setPlanned("planned", "pool", netip.MustParsePrefix("10.0.0.128/26"))
+	if err := p.storage.SaveAllocation(ctx, &storage.Allocation{ID: "a", PoolName: "pool", AllocatedCIDR: "10.0.0.64/27", PrefixLength: 27}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := p.availableCIDRs(ctx, "pool", 27, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.96/27", "10.0.0.192/27", "10.0.0.224/27"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if got, _ := p.availableCIDRs(ctx, "pool", 27, 2); len(got) != 2 {
+		t.Fatalf("expected the limit to apply, got %v", got)
+	}
+
+	// listing must not claim anything
+	if got, _ := p.availableCIDRs(ctx, "pool", 27, 1); fmt.Sprint(got) != "[10.0.0.96/27]" {
+		t.Fatalf("expected the same first block on every read, got %v", got)
+	}
+	pool, _ := p.storage.GetPool(ctx, "pool")
+	if pool.Cursor != "" {
+		t.Fatalf("expected the pool to be unchanged, got %+v", pool)
+	}
+
+	if _, err := p.availableCIDRs(ctx, "missing", 27, 1); err == nil {
+		t.Fatal("expected an error for a missing pool")
+	}
+}
+
+func testAccAvailableCIDRsDataSourceConfig(poolName string, limit int) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name           = %[1]q
+  cidrs          = ["10.0.0.0/24"]
+  reserved_cidrs = ["10.0.0.0/26"]
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "%[1]s-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 26
+}
+
+data "tfipam_available_cidrs" "test" {
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 26
+  limit         = %[2]d
+  depends_on    = [tfipam_allocation.test]
+}
+`, poolName, limit)
+}
@@ -0,0 +1,391 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/netip"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+)
+
+var (
+	_ function.Function = &CIDROverlapsFunction{}
+	_ function.Function = &CIDRContainsFunction{}
+	_ function.Function = &CIDRSubtractFunction{}
+	_ function.Function = &CIDRSummarizeFunction{}
+	_ function.Function = &RangeToCIDRsFunction{}
+	_ function.Function = &CIDRSplitFunction{}
+	_ function.Function = &CIDRLastAddressFunction{}
+	_ function.Function = &CIDRSizeFunction{}
+)
+
+// parseCIDRArgument parses the CIDR passed as the argument at the given index,
+// clearing host bits.
+func parseCIDRArgument(argument int64, cidr string) (netip.Prefix, *function.FuncError) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, function.NewArgumentFuncError(argument, fmt.Sprintf("CIDR '%s' is not valid: %s", cidr, err))
+	}
+	return prefix.Masked(), nil
+}
+
+// parseCIDRListArgument parses the CIDRs passed as the argument at the given index.
+func parseCIDRListArgument(argument int64, cidrs []string) ([]netip.Prefix, *function.FuncError) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, funcErr := parseCIDRArgument(argument, cidr)
+		if funcErr != nil {
+			return nil, funcErr
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+func prefixStrings(prefixes []netip.Prefix) []string {
+	cidrs := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		cidrs[i] = prefix.String()
+	}
+	return cidrs
+}
+
+func NewCIDROverlapsFunction() function.Function {
+	return &CIDROverlapsFunction{}
+}
+
+type CIDROverlapsFunction struct{}
+
+func (f *CIDROverlapsFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "cidr_overlaps"
+}
+
+func (f *CIDROverlapsFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Check whether two CIDRs overlap",
+		MarkdownDescription: "Returns true if the two CIDRs share at least one address. CIDRs of different address families never overlap.",
+		Parameters: []function.Parameter{
+			function.StringParameter{Name: "a", MarkdownDescription: "First CIDR"},
+			function.StringParameter{Name: "b", MarkdownDescription: "Second CIDR"},
+		},
+		Return: function.BoolReturn{},
+	}
+}
+
+func (f *CIDROverlapsFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var a, b string
+	resp.Error = req.Arguments.Get(ctx, &a, &b)
+	if resp.Error != nil {
+		return
+	}
+
+	prefixA, funcErr := parseCIDRArgument(0, a)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+	prefixB, funcErr := parseCIDRArgument(1, b)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, prefixA.Overlaps(prefixB))
+}
+
+func NewCIDRContainsFunction() function.Function {
+	return &CIDRContainsFunction{}
+}
+
+type CIDRContainsFunction struct{}
+
+func (f *CIDRContainsFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "cidr_contains"
+}
+
+func (f *CIDRContainsFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Check whether a CIDR contains an IP address or CIDR",
+		MarkdownDescription: "Returns true if the IP address, or every address of the CIDR, lies within the containing CIDR.",
+		Parameters: []function.Parameter{
+			function.StringParameter{Name: "containing_cidr", MarkdownDescription: "CIDR to check against"},
+			function.StringParameter{Name: "ip_or_cidr", MarkdownDescription: "IP address or CIDR that should lie within `containing_cidr`"},
+		},
+		Return: function.BoolReturn{},
+	}
+}
+
+func (f *CIDRContainsFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var containing, contained string
+	resp.Error = req.Arguments.Get(ctx, &containing, &contained)
+	if resp.Error != nil {
+		return
+	}
+
+	outer, funcErr := parseCIDRArgument(0, containing)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+	inner, err := ipam.ParsePrefixOrAddr(contained)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, ipam.Contains(outer, inner))
+}
+
+func NewCIDRSubtractFunction() function.Function {
+	return &CIDRSubtractFunction{}
+}
+
+type CIDRSubtractFunction struct{}
+
+func (f *CIDRSubtractFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "cidr_subtract"
+}
+
+func (f *CIDRSubtractFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Remove CIDRs from a CIDR",
+		MarkdownDescription: "Returns the fewest CIDRs covering the addresses of `cidr` that are in none of the `exclude` CIDRs, in address order. Excluded CIDRs may extend beyond `cidr` or be of another address family.",
+		Parameters: []function.Parameter{
+			function.StringParameter{Name: "cidr", MarkdownDescription: "CIDR to remove addresses from"},
+			function.ListParameter{Name: "exclude", ElementType: types.StringType, MarkdownDescription: "CIDRs to remove"},
+		},
+		Return: function.ListReturn{ElementType: types.StringType},
+	}
+}
+
+func (f *CIDRSubtractFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var cidr string
+	var exclude []string
+	resp.Error = req.Arguments.Get(ctx, &cidr, &exclude)
+	if resp.Error != nil {
+		return
+	}
+
+	from, funcErr := parseCIDRArgument(0, cidr)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+	remove, funcErr := parseCIDRListArgument(1, exclude)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, prefixStrings(ipam.Subtract(from, remove)))
+}
+
+func NewCIDRSummarizeFunction() function.Function {
+	return &CIDRSummarizeFunction{}
+}
+
+type CIDRSummarizeFunction struct{}
+
+func (f *CIDRSummarizeFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "cidr_summarize"
+}
+
+func (f *CIDRSummarizeFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Merge CIDRs into the fewest CIDRs",
+		MarkdownDescription: "Returns the fewest CIDRs covering the same addresses as `cidrs`, merging overlapping and adjacent CIDRs. IPv4 CIDRs come before IPv6 CIDRs, each in address order.",
+		Parameters: []function.Parameter{
+			function.ListParameter{Name: "cidrs", ElementType: types.StringType, MarkdownDescription: "CIDRs to merge"},
+		},
+		Return: function.ListReturn{ElementType: types.StringType},
+	}
+}
+
+func (f *CIDRSummarizeFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var cidrs []string
+	resp.Error = req.Arguments.Get(ctx, &cidrs)
+	if resp.Error != nil {
+		return
+	}
+
+	prefixes, funcErr := parseCIDRListArgument(0, cidrs)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, prefixStrings(ipam.Summarize(prefixes)))
+}
+
+func NewRangeToCIDRsFunction() function.Function {
+	return &RangeToCIDRsFunction{}
+}
+
+type RangeToCIDRsFunction struct{}
+
+func (f *RangeToCIDRsFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "range_to_cidrs"
+}
+
+func (f *RangeToCIDRsFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Convert an address range to CIDRs",
+		MarkdownDescription: "Returns the fewest CIDRs covering the addresses from `first` to `last`, both included, in address order.",
+		Parameters: []function.Parameter{
+			function.StringParameter{Name: "first", MarkdownDescription: "First address of the range"},
+			function.StringParameter{Name: "last", MarkdownDescription: "Last address of the range, of the same address family as `first`"},
+		},
+		Return: function.ListReturn{ElementType: types.StringType},
+	}
+}
+
+func (f *RangeToCIDRsFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var first, last string
+	resp.Error = req.Arguments.Get(ctx, &first, &last)
+	if resp.Error != nil {
+		return
+	}
+
+	firstAddr, err := netip.ParseAddr(first)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, fmt.Sprintf("Address '%s' is not valid: %s", first, err))
+		return
+	}
+	lastAddr, err := netip.ParseAddr(last)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, fmt.Sprintf("Address '%s' is not valid: %s", last, err))
+		return
+	}
+
+	prefixes, err := ipam.RangeToCIDRs(firstAddr, lastAddr)
+	if err != nil {
+		resp.Error = function.NewFuncError(err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, prefixStrings(prefixes))
+}
+
+func NewCIDRSplitFunction() function.Function {
+	return &CIDRSplitFunction{}
+}
+
+type CIDRSplitFunction struct{}
+
+func (f *CIDRSplitFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "cidr_split"
+}
+
+func (f *CIDRSplitFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Split a CIDR into blocks of a prefix length",
+		MarkdownDescription: fmt.Sprintf("Returns all blocks of `prefix_length` in `cidr`, in address order. At most %d blocks are returned; splitting into more fails.", ipam.MaxSplit),
+		Parameters: []function.Parameter{
+			function.StringParameter{Name: "cidr", MarkdownDescription: "CIDR to split"},
+			function.Int64Parameter{Name: "prefix_length", MarkdownDescription: "Prefix length of the blocks, at least the prefix length of `cidr`"},
+		},
+		Return: function.ListReturn{ElementType: types.StringType},
+	}
+}
+
+func (f *CIDRSplitFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var cidr string
+	var prefixLength int64
+	resp.Error = req.Arguments.Get(ctx, &cidr, &prefixLength)
+	if resp.Error != nil {
+		return
+	}
+
+	prefix, funcErr := parseCIDRArgument(0, cidr)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+
+	blocks, err := ipam.Split(prefix, int(prefixLength))
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(1, err.Error())
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, prefixStrings(blocks))
+}
+
+func NewCIDRLastAddressFunction() function.Function {
+	return &CIDRLastAddressFunction{}
+}
+
+type CIDRLastAddressFunction struct{}
+
+func (f *CIDRLastAddressFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "cidr_last_address"
+}
+
+func (f *CIDRLastAddressFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Get the last address of a CIDR",
+		MarkdownDescription: "Returns the last address of the CIDR, which is the broadcast address of an IPv4 subnet.",
+		Parameters: []function.Parameter{
+			function.StringParameter{Name: "cidr", MarkdownDescription: "CIDR to get the last address of"},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *CIDRLastAddressFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var cidr string
+	resp.Error = req.Arguments.Get(ctx, &cidr)
+	if resp.Error != nil {
+		return
+	}
+
+	prefix, funcErr := parseCIDRArgument(0, cidr)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+
+	resp.Error = resp.Result.Set(ctx, ipam.LastAddress(prefix).String())
+}
+
+func NewCIDRSizeFunction() function.Function {
+	return &CIDRSizeFunction{}
+}
+
+type CIDRSizeFunction struct{}
+
+func (f *CIDRSizeFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "cidr_size"
+}
+
+func (f *CIDRSizeFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Get the number of addresses in a CIDR",
+		MarkdownDescription: "Returns the number of addresses in the CIDR. Terraform numbers are arbitrary precision, so the size of large IPv6 CIDRs is exact.",
+		Parameters: []function.Parameter{
+			function.StringParameter{Name: "cidr", MarkdownDescription: "CIDR to count the addresses of"},
+		},
+		Return: function.NumberReturn{},
+	}
+}
+
+func (f *CIDRSizeFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var cidr string
+	resp.Error = req.Arguments.Get(ctx, &cidr)
+	if resp.Error != nil {
+		return
+	}
+
+	prefix, funcErr := parseCIDRArgument(0, cidr)
+	if funcErr != nil {
+		resp.Error = funcErr
+		return
+	}
+
+	size := new(big.Float).SetInt(ipam.Size(prefix))
+	resp.Error = resp.Result.Set(ctx, size)
+}
@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// runFunction calls the function with the arguments and returns the result, or
+// the error the function reported.
+func runFunction(t *testing.T, f function.Function, result attr.Value, args ...attr.Value) (attr.Value, *function.FuncError) {
+	t.Helper()
+	resp := &function.RunResponse{Result: function.NewResultData(result)}
+	f.Run(context.Background(), function.RunRequest{Arguments: function.NewArgumentsData(args)}, resp)
+	return resp.Result.Value(), resp.Error
+}
+
+func stringList(values ...string) types.List {
+	elements := make([]attr.Value, len(values))
+	for i, v := range values {
+		elements[i] = types.StringValue(v)
+	}
+	return types.ListValueMust(types.StringType, elements)
+}
+
+func TestCIDROverlapsFunction(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"10.0.0.0/8", "10.1.0.0/16", true},
+		{"10.0.0.0/24", "10.0.1.0/24", false},
+		{"10.0.0.77/24", "10.0.0.128/25", true},
+		{"fd00::/48", "fd00:0:0:ff::/64", true},
+		{"fd00::/64", "fd00:0:0:1::/64", false},
+		{"10.0.0.0/8", "::/0", false},
+	}
+	for _, tt := range tests {
+		got, funcErr := runFunction(t, NewCIDROverlapsFunction(), types.BoolUnknown(), types.StringValue(tt.a), types.StringValue(tt.b))
+		if funcErr != nil || !got.Equal(types.BoolValue(tt.want)) {
+			t.Errorf("cidr_overlaps(%s, %s) = %s, %v, want %v", tt.a, tt.b, got, funcErr, tt.want)
+		}
+	}
+
+	if _, funcErr := runFunction(t, NewCIDROverlapsFunction(), types.BoolUnknown(), types.StringValue("10.0.0.0/8"), types.StringValue("10.0.0.1")); funcErr == nil || funcErr.FunctionArgument == nil || *funcErr.FunctionArgument != 1 {
+		t.Errorf("expected an error for the second argument, got %v", funcErr)
+	}
+}
+
+func TestCIDRContainsFunction(t *testing.T) {
+	tests := []struct {
+		cidr, query string
+		want        bool
+	}{
+		{"10.0.0.0/24", "10.0.0.10", true},
+		{"10.0.0.0/24", "10.0.1.10", false},
+		{"10.0.0.0/16", "10.0.4.0/22", true},
+		{"10.0.0.0/16", "10.0.0.0/8", false},
+		{"fd00::/64", "fd00::1", true},
+		{"fd00::/64", "fd00:0:0:1::/80", false},
+	}
+	for _, tt := range tests {
+		got, funcErr := runFunction(t, NewCIDRContainsFunction(), types.BoolUnknown(), types.StringValue(tt.cidr), types.StringValue(tt.query))
+		if funcErr != nil || !got.Equal(types.BoolValue(tt.want)) {
+			t.Errorf("cidr_contains(%s, %s) = %s, %v, want %v", tt.cidr, tt.query, got, funcErr, tt.want)
+		}
+	}
+
+	if _, funcErr := runFunction(t, NewCIDRContainsFunction(), types.BoolUnknown(), types.StringValue("10.0.0.0/8"), types.StringValue("web")); funcErr == nil {
+		t.Error("expected an error for an invalid address")
+	}
+}
+
+func TestCIDRSubtractFunction(t *testing.T) {
+	got, funcErr := runFunction(t, NewCIDRSubtractFunction(), types.ListUnknown(types.StringType),
+		types.StringValue("10.0.0.0/24"), stringList("10.0.0.64/26", "fd00::/64"))
+	if funcErr != nil || !got.Equal(stringList("10.0.0.0/26", "10.0.0.128/25")) {
+		t.Errorf("unexpected IPv4 result %s, %v", got, funcErr)
+	}
+
+	got, funcErr = runFunction(t, NewCIDRSubtractFunction(), types.ListUnknown(types.StringType),
+		types.StringValue("fd00::/62"), stringList("fd00:0:0:1::/64"))
+	if funcErr != nil || !got.Equal(stringList("fd00::/64", "fd00:0:0:2::/63")) {
+		t.Errorf("unexpected IPv6 result %s, %v", got, funcErr)
+	}
+
+	got, funcErr = runFunction(t, NewCIDRSubtractFunction(), types.ListUnknown(types.StringType),
+		types.StringValue("10.0.0.0/24"), stringList("10.0.0.0/8"))
+	if funcErr != nil || !got.Equal(stringList()) {
+		t.Errorf("expected an empty list, got %s, %v", got, funcErr)
+	}
+
+	if _, funcErr := runFunction(t, NewCIDRSubtractFunction(), types.ListUnknown(types.StringType),
+		types.StringValue("10.0.0.0/24"), stringList("10.0.0.0/33")); funcErr == nil {
+		t.Error("expected an error for an invalid excluded CIDR")
+	}
+}
+
+func TestCIDRSummarizeFunction(t *testing.T) {
+	got, funcErr := runFunction(t, NewCIDRSummarizeFunction(), types.ListUnknown(types.StringType),
+		stringList("fd00:0:0:1::/64", "10.0.1.0/24", "fd00::/64", "10.0.0.0/24", "10.0.3.0/24"))
+	if funcErr != nil || !got.Equal(stringList("10.0.0.0/23", "10.0.3.0/24", "fd00::/63")) {
+		t.Errorf("unexpected result %s, %v", got, funcErr)
+	}
+}
+
+func TestRangeToCIDRsFunction(t *testing.T) {
+	got, funcErr := runFunction(t, NewRangeToCIDRsFunction(), types.ListUnknown(types.StringType),
+		types.StringValue("10.0.0.1"), types.StringValue("10.0.0.6"))
+	if funcErr != nil || !got.Equal(stringList("10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32")) {
+		t.Errorf("unexpected IPv4 result %s, %v", got, funcErr)
+	}
+
+	got, funcErr = runFunction(t, NewRangeToCIDRsFunction(), types.ListUnknown(types.StringType),
+		types.StringValue("fd00::"), types.StringValue("fd00::1:ffff"))
+	if funcErr != nil || !got.Equal(stringList("fd00::/111")) {
+		t.Errorf("unexpected IPv6 result %s, %v", got, funcErr)
+	}
+
+	for _, args := range [][2]string{
+		{"10.0.0.9", "10.0.0.1"},
+		{"10.0.0.1", "fd00::1"},
+		{"10.0.0.0/24", "10.0.0.255"},
+	} {
+		if _, funcErr := runFunction(t, NewRangeToCIDRsFunction(), types.ListUnknown(types.StringType),
+			types.StringValue(args[0]), types.StringValue(args[1])); funcErr == nil {
+			t.Errorf("expected an error for %s - %s", args[0], args[1])
+		}
+	}
+}
+
+func TestCIDRSplitFunction(t *testing.T) {
+	got, funcErr := runFunction(t, NewCIDRSplitFunction(), types.ListUnknown(types.StringType),
+		types.StringValue("10.0.0.0/24"), types.Int64Value(26))
+	if funcErr != nil || !got.Equal(stringList("10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/26", "10.0.0.192/26")) {
+		t.Errorf("unexpected IPv4 result %s, %v", got, funcErr)
+	}
+
+	got, funcErr = runFunction(t, NewCIDRSplitFunction(), types.ListUnknown(types.StringType),
+		types.StringValue("fd00::/63"), types.Int64Value(64))
+	if funcErr != nil || !got.Equal(stringList("fd00::/64", "fd00:0:0:1::/64")) {
+		t.Errorf("unexpected IPv6 result %s, %v", got, funcErr)
+	}
+
+	if _, funcErr := runFunction(t, NewCIDRSplitFunction(), types.ListUnknown(types.StringType),
+		types.StringValue("fd00::/48"), types.Int64Value(112)); funcErr == nil || funcErr.FunctionArgument == nil || *funcErr.FunctionArgument != 1 {
+		t.Errorf("expected an error for the prefix length, got %v", funcErr)
+	}
+}
+
+func TestCIDRLastAddressFunction(t *testing.T) {
+	for cidr, want := range map[string]string{
+		"10.0.0.0/24":  "10.0.0.255",
+		"10.0.0.77/30": "10.0.0.79",
+		"fd00::/64":    "fd00::ffff:ffff:ffff:ffff",
+	} {
+		got, funcErr := runFunction(t, NewCIDRLastAddressFunction(), types.StringUnknown(), types.StringValue(cidr))
+		if funcErr != nil || !got.Equal(types.StringValue(want)) {
+			t.Errorf("cidr_last_address(%s) = %s, %v, want %s", cidr, got, funcErr, want)
+		}
+	}
+}
+
+func TestCIDRSizeFunction(t *testing.T) {
+	for cidr, want := range map[string]string{
+		"10.0.0.0/24": "256",
+		"10.0.0.1/32": "1",
+		"fd00::/64":   "18446744073709551616",
+		"::/0":        "340282366920938463463374607431768211456",
+	} {
+		size, _ := new(big.Float).SetString(want)
+		got, funcErr := runFunction(t, NewCIDRSizeFunction(), types.NumberUnknown(), types.StringValue(cidr))
+		if funcErr != nil || !got.Equal(types.NumberValue(size)) {
+			t.Errorf("cidr_size(%s) = %s, %v, want %s", cidr, got, funcErr, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/action"
+	"github.com/hashicorp/terraform-plugin-framework/action/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ action.Action = &CompactAction{}
+var _ action.ActionWithConfigure = &CompactAction{}
+
+func NewCompactAction() action.Action {
+	return &CompactAction{}
+}
+
+type CompactAction struct {
+	provider *IpamProvider
+}
+
+type CompactActionModel struct {
+	DryRun types.Bool `tfsdk:"dry_run"`
+}
+
+func (a *CompactAction) Metadata(ctx context.Context, req action.MetadataRequest, resp *action.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_compact"
+}
+
+func (a *CompactAction) Schema(ctx context.Context, req action.SchemaRequest, resp *action.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Rewrites the storage document without orphaned entries: addresses of missing allocations, allocations of missing pools, and expired leases",
+
+		Attributes: map[string]schema.Attribute{
+			"dry_run": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Only report the entries that would be removed. Defaults to false.",
+			},
+		},
+	}
+}
+
+func (a *CompactAction) Configure(ctx context.Context, req action.ConfigureRequest, resp *action.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Action Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	a.provider = provider
+}
+
+func (a *CompactAction) Invoke(ctx context.Context, req action.InvokeRequest, resp *action.InvokeResponse) {
+	var data CompactActionModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	opts := storage.CompactOptions{DryRun: data.DryRun.ValueBool()}
+
+	result, err := storage.Compact(ctx, a.provider.storage, opts)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Storage Compaction Failed",
+			fmt.Sprintf("Could not compact storage: %s", err),
+		)
+		return
+	}
+
+	for _, alloc := range result.Allocations {
+		resp.SendProgress(action.InvokeProgressEvent{Message: fmt.Sprintf("remove allocation %s of missing pool %s", alloc.ID, alloc.PoolName)})
+	}
+	for _, address := range result.Addresses {
+		resp.SendProgress(action.InvokeProgressEvent{Message: fmt.Sprintf("remove address %s of allocation %s", address.ID, address.AllocationID)})
+	}
+
+	resp.SendProgress(action.InvokeProgressEvent{
+		Message: fmt.Sprintf("%s (dry run: %t)", result, opts.DryRun),
+	})
+
+	tflog.Trace(ctx, "invoked compact action", map[string]any{
+		"dry_run": opts.DryRun,
+		"removed": result.Count(),
+	})
+}
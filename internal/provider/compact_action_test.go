@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestAccCompactAction_Basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_14_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccMaintenanceActionConfig("compact-pool", "tfipam_compact", ""),
+			},
+		},
+	})
+}
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{
+		Name:  "pool",
+		CIDRs: []string{"10.0.0.0/24"},
+		Leases: []storage.Lease{
+			{ID: "expired", CIDR: "10.0.0.128/26", ExpiresAt: time.Now().Add(-time.Minute)},
+			{ID: "live", CIDR: "10.0.0.192/26", ExpiresAt: time.Now().Add(time.Hour)},
+		},
+	})
+
+	for _, alloc := range []storage.Allocation{
+		{ID: "kept", PoolName: "pool", AllocatedCIDR: "10.0.0.0/26", PrefixLength: 26},
+		{ID: "orphaned", PoolName: "pool", AllocatedCIDR: "10.1.0.0/26", PrefixLength: 26, Orphaned: true},
+		{ID: "poolless", PoolName: "deleted", AllocatedCIDR: "10.0.0.64/26", PrefixLength: 26},
+	} {
+		if err := p.storage.SaveAllocation(ctx, &alloc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, address := range []storage.Address{
+		{ID: "kept-host", AllocationID: "kept", Address: "10.0.0.10"},
+		{ID: "poolless-host", AllocationID: "poolless", Address: "10.0.0.70"},
+		{ID: "dangling", AllocationID: "missing", Address: "10.0.0.20"},
+	} {
+		if err := p.storage.SaveAddress(ctx, &address); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := storage.Compact(ctx, p.storage, storage.CompactOptions{DryRun: true})
+	if err != nil || result.Count() != 4 {
+		t.Fatalf("expected the dry run to find 4 entries, got %s (%v)", result, err)
+	}
+	if records, _ := p.storage.ListAuditRecords(ctx); len(records) != 0 {
+		t.Fatalf("expected the dry run to write no audit records, got %v", records)
+	}
+
+	if _, err := storage.Compact(ctx, p.storage, storage.CompactOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, _ := storage.TakeSnapshot(ctx, p.storage)
+	if len(snap.Allocations) != 2 || snap.Allocations[0].ID != "kept" || snap.Allocations[1].ID != "orphaned" {
+		t.Errorf("expected kept and orphaned to remain, got %v", snap.Allocations)
+	}
+	if len(snap.Addresses) != 1 || snap.Addresses[0].ID != "kept-host" {
+		t.Errorf("expected only kept-host to remain, got %v", snap.Addresses)
+	}
+	if leases := snap.Pools[0].Leases; len(leases) != 1 || leases[0].ID != "live" {
+		t.Errorf("expected only the live lease to remain, got %v", leases)
+	}
+	if issues := storage.Verify(snap); len(issues) != 0 {
+		t.Errorf("expected compacted storage to pass verification, got %v", issues)
+	}
+
+	// every removed allocation and address is recorded, followed by a summary
+	records, _ := p.storage.ListAuditRecords(ctx)
+	if len(records) != 4 || records[3].Operation != storage.AuditCompact || records[3].AllocationID != "" {
+		t.Fatalf("expected 3 audit records and a summary, got %v", records)
+	}
+
+	// compacting clean storage still rewrites it with a summary record
+	result, err = storage.Compact(ctx, p.storage, storage.CompactOptions{})
+	if err != nil || result.Count() != 0 {
+		t.Fatalf("expected nothing to compact, got %s (%v)", result, err)
+	}
+	if records, _ := p.storage.ListAuditRecords(ctx); len(records) != 5 {
+		t.Fatalf("expected a second summary record, got %v", records)
+	}
+}
+
+// testAccMaintenanceActionConfig generates a config that invokes a maintenance
+// action with the given arguments once an allocation exists.
+func testAccMaintenanceActionConfig(poolName, actionType, arguments string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name  = %[1]q
+  cidrs = ["10.70.0.0/16"]
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "%[1]s-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+}
+
+action %[2]q "test" {
+  config {
+    %[3]s
+  }
+}
+
+resource "terraform_data" "trigger" {
+  input = tfipam_allocation.test.allocated_cidr
+
+  lifecycle {
+    action_trigger {
+      events  = [after_create]
+      actions = [action.%[2]s.test]
+    }
+  }
+}
+`, poolName, actionType, arguments)
+}
@@ -0,0 +1,311 @@
+package ipam
+
+import (
+	"math/big"
+	"net/netip"
+	"slices"
+)
+
+// Allocator hands out CIDR blocks from the CIDRs of a pool.
+//
+// Free space is kept as a free-list of the largest aligned blocks that are not
+// covered by an allocation, bucketed by address family and prefix length. Each
+// bucket is an ordered set sorted by pool CIDR and address, so the lowest,
+// highest or nearest free block that is large enough is found in one of at most
+// 129 buckets. Allocating takes that block and splits it buddy-style, which costs
+// O(bits * log n) for n free blocks, independent of the size of the pool or the
+// number of existing allocations.
+//
+// Building the free-list is not free: NewAllocator sorts the m allocated CIDRs
+// and pushes up to bits free blocks for each gap between them. An Allocator pays
+// off when it is used for many allocations; for a single one the build dominates.
+type Allocator struct {
+	pools   []netip.Prefix
+	buckets [2][129]*blockTree
+
+	// cursor is the block allocated last, where the sequential strategy continues.
+	cursor netip.Prefix
+}
+
+// NewAllocator builds the free-list of the pool CIDRs minus the allocated CIDRs.
+// Pool CIDRs are searched in the given order. Space that is shared by several
+// pool CIDRs belongs to the first one, so it is never handed out twice.
+// Building takes O(m log m + m * bits * log n) for m allocated CIDRs.
+func NewAllocator(poolCIDRs []netip.Prefix, allocated []netip.Prefix) *Allocator {
+	a := &Allocator{}
+
+	for i, pool := range poolCIDRs {
+		pool = pool.Masked()
+		a.pools = append(a.pools, pool)
+
+		occupied := make([]netip.Prefix, 0, len(allocated))
+		for _, prior := range a.pools[:i] {
+			if prior.Overlaps(pool) {
+				occupied = append(occupied, prior)
+			}
+		}
+		for _, alloc := range allocated {
+			if alloc.IsValid() && alloc.Overlaps(pool) {
+				occupied = append(occupied, alloc.Masked())
+			}
+		}
+
+		for _, r := range freeRanges(pool, occupied) {
+			for _, prefix := range rangeToPrefixes(r.first, r.last, pool.Addr().Is4()) {
+				a.push(block{pool: i, prefix: prefix})
+			}
+		}
+	}
+
+	return a
+}
+
+// Allocate takes the lowest free block of the given prefix length, searching the
+// pool CIDRs in order. It returns false if no pool CIDR has a large enough free block.
+func (a *Allocator) Allocate(prefixLength int) (netip.Prefix, bool) {
+	return a.AllocateWith(prefixLength, FirstFit)
+}
+
+// AllocateWith takes a free block of the given prefix length chosen by the strategy.
+// It returns false if no pool CIDR has a large enough free block.
+func (a *Allocator) AllocateWith(prefixLength int, strategy Strategy) (netip.Prefix, bool) {
+	var (
+		prefix netip.Prefix
+		ok     bool
+	)
+
+	switch strategy {
+	case BestFit:
+		prefix, ok = a.bestFit(prefixLength)
+	case LastFit:
+		prefix, ok = a.lastFit(prefixLength)
+	case Random:
+		prefix, ok = a.random(prefixLength)
+	case Sequential:
+		prefix, ok = a.sequential(prefixLength)
+	default:
+		prefix, ok = a.firstFit(prefixLength)
+	}
+
+	if ok {
+		a.cursor = prefix
+	}
+	return prefix, ok
+}
+
+// Cursor returns the block allocated last, or the one set with SetCursor.
+func (a *Allocator) Cursor() netip.Prefix {
+	return a.cursor
+}
+
+// SetCursor sets the block the sequential strategy continues after, normally
+// the block a previous allocator allocated last.
+func (a *Allocator) SetCursor(prefix netip.Prefix) {
+	a.cursor = prefix
+}
+
+// Free returns the free blocks ordered by pool CIDR and address.
+func (a *Allocator) Free() []netip.Prefix {
+	var blocks []block
+	for family := range a.buckets {
+		for _, t := range a.buckets[family] {
+			if t != nil {
+				blocks = append(blocks, t.all()...)
+			}
+		}
+	}
+	slices.SortFunc(blocks, func(x, y block) int {
+		if x.less(y) {
+			return -1
+		}
+		if y.less(x) {
+			return 1
+		}
+		return 0
+	})
+
+	free := make([]netip.Prefix, len(blocks))
+	for i, blk := range blocks {
+		free[i] = blk.prefix
+	}
+	return free
+}
+
+// fitting calls fn for every non-empty bucket holding blocks that are large
+// enough for the prefix length.
+func (a *Allocator) fitting(prefixLength int, fn func(t *blockTree)) {
+	for family := range a.buckets {
+		if prefixLength < 0 || prefixLength > familyBits(family) {
+			continue
+		}
+		for bits := 0; bits <= prefixLength; bits++ {
+			if t := a.buckets[family][bits]; t != nil && t.Len() > 0 {
+				fn(t)
+			}
+		}
+	}
+}
+
+// carve removes the free block and returns the parts of it around the target to the free-list.
+func (a *Allocator) carve(blk block, target netip.Prefix) netip.Prefix {
+	a.bucket(blk.prefix).remove(blk)
+
+	prefix := blk.prefix
+	is4 := prefix.Addr().Is4()
+	hostBits := prefix.Addr().BitLen()
+	start := fromAddr(target.Addr())
+
+	for bits := prefix.Bits() + 1; bits <= target.Bits(); bits++ {
+		lower := netip.PrefixFrom(prefix.Addr(), bits)
+		upperStart := fromAddr(prefix.Addr()).or(onesBelow(hostBits - bits)).add(uint128{lo: 1})
+		upper := netip.PrefixFrom(upperStart.addr(is4), bits)
+
+		if start.less(upperStart) {
+			a.push(block{pool: blk.pool, prefix: upper})
+			prefix = lower
+		} else {
+			a.push(block{pool: blk.pool, prefix: lower})
+			prefix = upper
+		}
+	}
+
+	return prefix
+}
+
+func (a *Allocator) push(blk block) {
+	a.bucket(blk.prefix).insert(blk)
+}
+
+func (a *Allocator) bucket(prefix netip.Prefix) *blockTree {
+	family := 1
+	if prefix.Addr().Is4() {
+		family = 0
+	}
+
+	t := a.buckets[family][prefix.Bits()]
+	if t == nil {
+		t = &blockTree{}
+		a.buckets[family][prefix.Bits()] = t
+	}
+	return t
+}
+
+func familyBits(family int) int {
+	if family == 0 {
+		return 32
+	}
+	return 128
+}
+
+// block is a free CIDR block of the pool CIDR at index pool.
+type block struct {
+	pool   int
+	prefix netip.Prefix
+}
+
+func (b block) less(o block) bool {
+	if b.pool != o.pool {
+		return b.pool < o.pool
+	}
+	return b.prefix.Addr().Less(o.prefix.Addr())
+}
+
+// addrRange is an inclusive range of addresses.
+type addrRange struct {
+	first, last uint128
+}
+
+// size returns the number of addresses in the range.
+func (r addrRange) size() *big.Int {
+	size := r.last.sub(r.first).big()
+	return size.Add(size, big.NewInt(1))
+}
+
+// freeRanges returns the ranges of the pool CIDR that are not covered by any of the occupied CIDRs.
+func freeRanges(pool netip.Prefix, occupied []netip.Prefix) []addrRange {
+	slices.SortFunc(occupied, func(x, y netip.Prefix) int {
+		return x.Addr().Compare(y.Addr())
+	})
+
+	var ranges []addrRange
+	cursor, last := prefixRange(pool)
+	for _, o := range occupied {
+		first, end := prefixRange(o)
+		if cursor.less(first) {
+			ranges = append(ranges, addrRange{first: cursor, last: first.sub(uint128{lo: 1})})
+		}
+		if !end.less(last) {
+			return ranges
+		}
+		if !end.less(cursor) {
+			cursor = end.add(uint128{lo: 1})
+		}
+	}
+
+	return append(ranges, addrRange{first: cursor, last: last})
+}
+
+// rangeToPrefixes splits an inclusive address range into the fewest aligned CIDR blocks.
+func rangeToPrefixes(first, last uint128, is4 bool) []netip.Prefix {
+	bits := 128
+	if is4 {
+		bits = 32
+	}
+
+	var prefixes []netip.Prefix
+	for {
+		hostBits := min(first.trailingZeros(), bits)
+		for hostBits > 0 && last.less(first.or(onesBelow(hostBits))) {
+			hostBits--
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(first.addr(is4), bits-hostBits))
+
+		end := first.or(onesBelow(hostBits))
+		if end == last {
+			return prefixes
+		}
+		first = end.add(uint128{lo: 1})
+	}
+}
+
+// prefixRange returns the first and last address of the CIDR.
+func prefixRange(prefix netip.Prefix) (uint128, uint128) {
+	first := fromAddr(prefix.Masked().Addr())
+	return first, first.or(onesBelow(prefix.Addr().BitLen() - prefix.Bits()))
+}
+
+// ParseCIDRs parses the CIDRs, skipping any that are invalid.
+func ParseCIDRs(cidrs []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+// Claim takes exactly the given block if it is free. It returns false if the
+// block is not within a pool CIDR or any part of it is already taken.
+func (a *Allocator) Claim(prefix netip.Prefix) bool {
+	prefix = prefix.Masked()
+
+	for i, pool := range a.pools {
+		if pool.Bits() > prefix.Bits() || !pool.Contains(prefix.Addr()) {
+			continue
+		}
+
+		// the block is free if one of the free blocks containing its address covers it
+		for bits := pool.Bits(); bits <= prefix.Bits(); bits++ {
+			blk := block{pool: i, prefix: netip.PrefixFrom(prefix.Addr(), bits).Masked()}
+			if a.bucket(blk.prefix).contains(blk) {
+				a.carve(blk, prefix)
+				return true
+			}
+		}
+	}
+
+	return false
+}
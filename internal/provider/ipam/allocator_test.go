@@ -0,0 +1,319 @@
+package ipam
+
+import (
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"testing"
+)
+
+func TestAllocator_FirstFit(t *testing.T) {
+	pools := ParseCIDRs([]string{"10.0.0.0/24", "10.5.0.0/24"})
+	allocated := ParseCIDRs([]string{"10.0.0.0/26", "10.0.0.128/25"})
+
+	a := NewAllocator(pools, allocated)
+	for _, want := range []string{"10.0.0.64/27", "10.0.0.96/27", "10.5.0.0/27"} {
+		got, ok := a.Allocate(27)
+		if !ok {
+			t.Fatalf("expected %s, pool reported full", want)
+		}
+		if got.String() != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestAllocator_PoolCIDROrder(t *testing.T) {
+	// the second pool CIDR has a lower address but is only used once the first is full
+	a := NewAllocator(ParseCIDRs([]string{"10.5.0.0/30", "10.0.0.0/30"}), nil)
+
+	var got []string
+	for {
+		prefix, ok := a.Allocate(31)
+		if !ok {
+			break
+		}
+		got = append(got, prefix.String())
+	}
+
+	want := []string{"10.5.0.0/31", "10.5.0.2/31", "10.0.0.0/31", "10.0.0.2/31"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestAllocator_Full(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"10.0.0.0/24"}), ParseCIDRs([]string{"10.0.0.0/25", "10.0.0.128/26", "10.0.0.192/26"}))
+	if prefix, ok := a.Allocate(32); ok {
+		t.Fatalf("expected a full pool, got %s", prefix)
+	}
+}
+
+func TestAllocator_LargerThanPool(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"10.0.0.0/24"}), nil)
+	if prefix, ok := a.Allocate(16); ok {
+		t.Fatalf("expected no block larger than the pool, got %s", prefix)
+	}
+	if prefix, ok := a.Allocate(64); ok {
+		t.Fatalf("expected no IPv6 sized block from an IPv4 pool, got %s", prefix)
+	}
+}
+
+func TestAllocator_MixedFamilies(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"10.0.0.0/24", "2001:db8::/48"}), nil)
+
+	if got, _ := a.Allocate(64); got.String() != "2001:db8::/64" {
+		t.Fatalf("expected 2001:db8::/64, got %s", got)
+	}
+	if got, _ := a.Allocate(28); got.String() != "10.0.0.0/28" {
+		t.Fatalf("expected 10.0.0.0/28, got %s", got)
+	}
+}
+
+func TestAllocator_OverlappingPoolCIDRs(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"10.0.0.0/24", "10.0.0.0/25"}), nil)
+
+	seen := map[netip.Prefix]bool{}
+	for {
+		prefix, ok := a.Allocate(26)
+		if !ok {
+			break
+		}
+		if seen[prefix] {
+			t.Fatalf("%s was allocated twice", prefix)
+		}
+		seen[prefix] = true
+	}
+	if len(seen) != 4 {
+		t.Fatalf("expected 4 blocks, got %d", len(seen))
+	}
+}
+
+func TestAllocator_IPv6BeyondLinearScanLimit(t *testing.T) {
+	// the previous allocator gave up after scanning 100,000 candidate blocks
+	pool := netip.MustParsePrefix("2001:db8::/48")
+	allocated := []netip.Prefix{netip.MustParsePrefix("2001:db8:0:0::/49")}
+
+	got, ok := NewAllocator([]netip.Prefix{pool}, allocated).Allocate(64)
+	if !ok {
+		t.Fatal("expected a free /64 in the upper half of the pool")
+	}
+	if got.String() != "2001:db8:0:8000::/64" {
+		t.Fatalf("expected 2001:db8:0:8000::/64, got %s", got)
+	}
+}
+
+func TestAllocator_FullIPv6Space(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"::/0"}), ParseCIDRs([]string{"::/1", "8000::/2", "ffff:ffff:ffff:ffff::/64"}))
+
+	if got, ok := a.Allocate(2); ok {
+		t.Fatalf("expected no free /2, got %s", got)
+	}
+	if got, _ := a.Allocate(3); got.String() != "c000::/3" {
+		t.Fatalf("expected c000::/3, got %s", got)
+	}
+	if got, _ := a.Allocate(128); got.String() != "e000::/128" {
+		t.Fatalf("expected e000::/128, got %s", got)
+	}
+
+	free := a.Free()
+	if last := free[len(free)-1]; last.String() != "ffff:ffff:ffff:fffe::/64" {
+		t.Fatalf("expected the last free block below the allocation at the top of the space, got %s", last)
+	}
+}
+
+func TestAllocator_Free(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"10.0.0.0/24"}), ParseCIDRs([]string{"10.0.0.16/28", "10.0.0.128/26"}))
+
+	want := []string{"10.0.0.0/28", "10.0.0.32/27", "10.0.0.64/26", "10.0.0.192/26"}
+	if fmt.Sprint(a.Free()) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, a.Free())
+	}
+}
+
+// TestAllocator_MatchesLinearScan checks the allocator against a brute force
+// first fit search over random IPv4 pools.
+func TestAllocator_MatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for round := 0; round < 200; round++ {
+		pools := []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/24"),
+			netip.MustParsePrefix("10.1.0.0/25"),
+		}
+		a := NewAllocator(pools, nil)
+		var allocated []netip.Prefix
+
+		for step := 0; step < 40; step++ {
+			prefixLength := 25 + rng.Intn(8)
+			want, wantOK := linearScan(pools, prefixLength, allocated)
+			got, ok := a.Allocate(prefixLength)
+			if ok != wantOK || got != want {
+				t.Fatalf("round %d step %d /%d: expected %s (%t), got %s (%t)", round, step, prefixLength, want, wantOK, got, ok)
+			}
+			if ok {
+				allocated = append(allocated, got)
+			}
+		}
+	}
+}
+
+func linearScan(pools []netip.Prefix, prefixLength int, allocated []netip.Prefix) (netip.Prefix, bool) {
+	for _, pool := range pools {
+		if prefixLength < pool.Bits() {
+			continue
+		}
+		first, last := prefixRange(pool)
+		step := uint128{lo: 1 << uint(32-prefixLength)}
+		for candidate := first; !last.less(candidate); candidate = candidate.add(step) {
+			prefix := netip.PrefixFrom(candidate.addr(true), prefixLength)
+			free := true
+			for _, alloc := range allocated {
+				if alloc.Overlaps(prefix) {
+					free = false
+					break
+				}
+			}
+			if free {
+				return prefix, true
+			}
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+func BenchmarkNewAllocator_IPv4(b *testing.B) {
+	pools := ParseCIDRs([]string{"10.0.0.0/8"})
+	allocated := benchmarkAllocations(b, pools, 24, 50_000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewAllocator(pools, allocated)
+	}
+}
+
+func BenchmarkAllocate_IPv4(b *testing.B) {
+	pools := ParseCIDRs([]string{"10.0.0.0/8"})
+	a := NewAllocator(pools, benchmarkAllocations(b, pools, 24, 50_000))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := a.Allocate(32); !ok {
+			a = NewAllocator(pools, nil)
+		}
+	}
+}
+
+func BenchmarkNewAllocator_IPv6(b *testing.B) {
+	pools := ParseCIDRs([]string{"2001:db8::/32"})
+	allocated := benchmarkAllocations(b, pools, 64, 200_000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewAllocator(pools, allocated)
+	}
+}
+
+func BenchmarkAllocate_IPv6(b *testing.B) {
+	pools := ParseCIDRs([]string{"2001:db8::/32"})
+	a := NewAllocator(pools, benchmarkAllocations(b, pools, 64, 200_000))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := a.Allocate(64); !ok {
+			b.Fatal("pool is full")
+		}
+	}
+}
+
+func BenchmarkAllocate_FragmentedIPv6(b *testing.B) {
+	// every other /64 of a /48 is taken, so the free-list holds 32,768 single blocks
+	pool := netip.MustParsePrefix("2001:db8::/48")
+	first, _ := prefixRange(pool)
+	var allocated []netip.Prefix
+	for i := uint64(0); i < 1<<16; i += 2 {
+		allocated = append(allocated, netip.PrefixFrom(first.add(uint128{hi: i}).addr(false), 64))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a := NewAllocator([]netip.Prefix{pool}, allocated)
+		if _, ok := a.Allocate(64); !ok {
+			b.Fatal("pool is full")
+		}
+	}
+}
+
+// benchmarkAllocations allocates count blocks of the prefix length from the pools.
+func benchmarkAllocations(b *testing.B, pools []netip.Prefix, prefixLength, count int) []netip.Prefix {
+	b.Helper()
+
+	a := NewAllocator(pools, nil)
+	allocated := make([]netip.Prefix, 0, count)
+	for len(allocated) < count {
+		prefix, ok := a.Allocate(prefixLength)
+		if !ok {
+			b.Fatalf("pool is full after %d allocations", len(allocated))
+		}
+		allocated = append(allocated, prefix)
+	}
+	return allocated
+}
+
+func TestAllocator_Claim(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"10.0.0.0/24"}), ParseCIDRs([]string{"10.0.0.0/26"}))
+
+	if !a.Claim(netip.MustParsePrefix("10.0.0.128/26")) {
+		t.Fatal("expected 10.0.0.128/26 to be claimed")
+	}
+	for _, cidr := range []string{"10.0.0.128/26", "10.0.0.0/28", "10.0.0.128/30", "10.0.1.0/26", "10.0.0.0/23"} {
+		if a.Claim(netip.MustParsePrefix(cidr)) {
+			t.Errorf("expected %s to be unavailable", cidr)
+		}
+	}
+
+	want := []string{"10.0.0.64/26", "10.0.0.192/26"}
+	if fmt.Sprint(a.Free()) != fmt.Sprint(want) {
+		t.Fatalf("expected %v to be free, got %v", want, a.Free())
+	}
+
+	// the remaining space is still handed out in order
+	if got, _ := a.Allocate(27); got.String() != "10.0.0.64/27" {
+		t.Fatalf("expected 10.0.0.64/27, got %s", got)
+	}
+}
+
+func TestAllocator_ClaimIPv6(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"2001:db8::/32"}), nil)
+
+	if !a.Claim(netip.MustParsePrefix("2001:db8:ffff:ffff::/64")) {
+		t.Fatal("expected the last /64 of the pool to be claimed")
+	}
+	if a.Claim(netip.MustParsePrefix("2001:db8:ffff::/48")) {
+		t.Fatal("expected the /48 holding the claimed /64 to be unavailable")
+	}
+	if got, _ := a.Allocate(64); got.String() != "2001:db8::/64" {
+		t.Fatalf("expected 2001:db8::/64, got %s", got)
+	}
+}
+
+func TestAddressCount(t *testing.T) {
+	tests := map[string]struct {
+		cidrs []string
+		want  string
+	}{
+		"single IPv4":      {[]string{"10.0.0.0/24"}, "256"},
+		"overlapping IPv4": {[]string{"10.0.0.0/24", "10.0.0.128/25", "10.0.1.0/24"}, "512"},
+		"mixed families":   {[]string{"10.0.0.0/31", "2001:db8::/127"}, "4"},
+		"full IPv6 space":  {[]string{"::/0"}, "340282366920938463463374607431768211456"},
+		"none":             {nil, "0"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := AddressCount(ParseCIDRs(tt.cidrs)).String(); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package ipam
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"slices"
+)
+
+// MaxSplit is the largest number of blocks Split returns, so splitting a large
+// IPv6 CIDR into small blocks fails instead of running out of memory.
+const MaxSplit = 65536
+
+// ParsePrefixOrAddr parses a CIDR or a single IP address, which becomes a /32
+// or /128. Host bits are cleared and IPv4-mapped IPv6 addresses become IPv4.
+func ParsePrefixOrAddr(s string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%q is neither an IP address nor a CIDR", s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Contains reports whether inner lies entirely within outer.
+func Contains(outer, inner netip.Prefix) bool {
+	return outer.Bits() <= inner.Bits() && outer.Contains(inner.Addr())
+}
+
+// LastAddress returns the last address of the CIDR.
+func LastAddress(prefix netip.Prefix) netip.Addr {
+	_, last := prefixRange(prefix)
+	return last.addr(prefix.Addr().Is4())
+}
+
+// Size returns the number of addresses in the CIDR.
+func Size(prefix netip.Prefix) *big.Int {
+	first, last := prefixRange(prefix)
+	return addrRange{first, last}.size()
+}
+
+// Subtract returns the fewest CIDRs covering the addresses of from that are not
+// in any of the removed CIDRs, in address order.
+func Subtract(from netip.Prefix, remove []netip.Prefix) []netip.Prefix {
+	from = from.Masked()
+
+	var occupied []netip.Prefix
+	for _, r := range remove {
+		if r.IsValid() && r.Overlaps(from) {
+			occupied = append(occupied, r.Masked())
+		}
+	}
+
+	remaining := []netip.Prefix{}
+	for _, r := range freeRanges(from, occupied) {
+		remaining = append(remaining, rangeToPrefixes(r.first, r.last, from.Addr().Is4())...)
+	}
+	return remaining
+}
+
+// Summarize merges the CIDRs into the fewest CIDRs covering the same addresses,
+// joining overlapping and adjacent ones. IPv4 CIDRs come before IPv6 CIDRs and
+// each family is in address order.
+func Summarize(prefixes []netip.Prefix) []netip.Prefix {
+	sorted := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if prefix.IsValid() {
+			sorted = append(sorted, prefix.Masked())
+		}
+	}
+	slices.SortFunc(sorted, func(x, y netip.Prefix) int {
+		return x.Addr().Compare(y.Addr())
+	})
+
+	summary := []netip.Prefix{}
+	var (
+		current addrRange
+		is4     bool
+	)
+	for i, prefix := range sorted {
+		first, last := prefixRange(prefix)
+		// sorted IPv4 before IPv6, so a family change starts a new range
+		adjacent := i > 0 && prefix.Addr().Is4() == is4 &&
+			(current.last == maxAddr(is4) || !current.last.add(uint128{lo: 1}).less(first))
+		if adjacent {
+			if current.last.less(last) {
+				current.last = last
+			}
+			continue
+		}
+		if i > 0 {
+			summary = append(summary, rangeToPrefixes(current.first, current.last, is4)...)
+		}
+		current, is4 = addrRange{first, last}, prefix.Addr().Is4()
+	}
+	if len(sorted) > 0 {
+		summary = append(summary, rangeToPrefixes(current.first, current.last, is4)...)
+	}
+	return summary
+}
+
+// RangeToCIDRs returns the fewest CIDRs covering the addresses from first to last.
+func RangeToCIDRs(first, last netip.Addr) ([]netip.Prefix, error) {
+	first, last = first.Unmap(), last.Unmap()
+	if first.Is4() != last.Is4() {
+		return nil, fmt.Errorf("%s and %s are not of the same address family", first, last)
+	}
+	if last.Less(first) {
+		return nil, fmt.Errorf("%s comes after %s", first, last)
+	}
+	return rangeToPrefixes(fromAddr(first), fromAddr(last), first.Is4()), nil
+}
+
+// Split divides the CIDR into all blocks of the prefix length, in address order.
+func Split(prefix netip.Prefix, prefixLength int) ([]netip.Prefix, error) {
+	prefix = prefix.Masked()
+	if prefixLength < prefix.Bits() || prefixLength > prefix.Addr().BitLen() {
+		return nil, fmt.Errorf("prefix length %d must be between %d and %d to split %s", prefixLength, prefix.Bits(), prefix.Addr().BitLen(), prefix)
+	}
+	if prefixLength-prefix.Bits() > 16 {
+		return nil, fmt.Errorf("splitting %s into /%d blocks gives more than %d blocks", prefix, prefixLength, MaxSplit)
+	}
+
+	count := 1 << (prefixLength - prefix.Bits())
+	step := onesBelow(prefix.Addr().BitLen() - prefixLength).add(uint128{lo: 1})
+	blocks := make([]netip.Prefix, 0, count)
+	addr := fromAddr(prefix.Addr())
+	for range count {
+		blocks = append(blocks, netip.PrefixFrom(addr.addr(prefix.Addr().Is4()), prefixLength))
+		addr = addr.add(step)
+	}
+	return blocks, nil
+}
+
+// SummaryPrefixLength returns the prefix length of the smallest CIDR that holds
+// contiguous blocks of the prefix lengths.
+func SummaryPrefixLength(prefixLengths []int) (int, error) {
+	if len(prefixLengths) == 0 {
+		return 0, fmt.Errorf("at least one block is required")
+	}
+
+	longest := slices.Max(prefixLengths)
+	if slices.Min(prefixLengths) < 0 || longest > 128 {
+		return 0, fmt.Errorf("prefix lengths must be between 0 and 128")
+	}
+
+	// total size in blocks of the longest prefix length, rounded up to a power of two
+	total := new(big.Int)
+	for _, prefixLength := range prefixLengths {
+		total.Add(total, new(big.Int).Lsh(big.NewInt(1), uint(longest-prefixLength)))
+	}
+	summary := longest - new(big.Int).Sub(total, big.NewInt(1)).BitLen()
+	if summary < 0 {
+		return 0, fmt.Errorf("blocks of prefix lengths %v don't fit in a single CIDR", prefixLengths)
+	}
+	return summary, nil
+}
+
+// PackContiguous places blocks of the prefix lengths next to each other from the
+// start of summary, and returns them in the order of the prefix lengths. Larger
+// blocks are placed first, so every block is aligned to its size without gaps
+// and the blocks summarize into the smallest CIDR holding them.
+func PackContiguous(summary netip.Prefix, prefixLengths []int) ([]netip.Prefix, error) {
+	summary = summary.Masked()
+	needed, err := SummaryPrefixLength(prefixLengths)
+	if err != nil {
+		return nil, err
+	}
+	for _, prefixLength := range prefixLengths {
+		if prefixLength > summary.Addr().BitLen() {
+			return nil, fmt.Errorf("prefix length %d is too long for %s", prefixLength, summary)
+		}
+	}
+	if needed < summary.Bits() {
+		return nil, fmt.Errorf("blocks of prefix lengths %v need a /%d, %s is too small", prefixLengths, needed, summary)
+	}
+
+	order := make([]int, len(prefixLengths))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(x, y int) int { return prefixLengths[x] - prefixLengths[y] })
+
+	is4 := summary.Addr().Is4()
+	bits := summary.Addr().BitLen()
+	blocks := make([]netip.Prefix, len(prefixLengths))
+	addr := fromAddr(summary.Addr())
+	for _, i := range order {
+		blocks[i] = netip.PrefixFrom(addr.addr(is4), prefixLengths[i])
+		addr = addr.add(onesBelow(bits - prefixLengths[i]).add(uint128{lo: 1}))
+	}
+	return blocks, nil
+}
+
+// maxAddr returns the last address of the family.
+func maxAddr(is4 bool) uint128 {
+	if is4 {
+		return onesBelow(32)
+	}
+	return onesBelow(128)
+}
@@ -0,0 +1,222 @@
+package ipam
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+)
+
+func TestParsePrefixOrAddr(t *testing.T) {
+	for s, want := range map[string]string{
+		"10.0.0.1":       "10.0.0.1/32",
+		"10.0.0.77/24":   "10.0.0.0/24",
+		"fd00::1":        "fd00::1/128",
+		"fd00::1/64":     "fd00::/64",
+		"::ffff:1.2.3.4": "1.2.3.4/32",
+	} {
+		got, err := ParsePrefixOrAddr(s)
+		if err != nil || got != netip.MustParsePrefix(want) {
+			t.Errorf("ParsePrefixOrAddr(%s) = %s, %v, want %s", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"web", "10.0.0.300", "10.0.0.0/33", ""} {
+		if _, err := ParsePrefixOrAddr(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		outer, inner string
+		want         bool
+	}{
+		{"10.0.0.0/8", "10.1.0.0/16", true},
+		{"10.0.0.0/8", "10.0.0.0/8", true},
+		{"10.0.0.0/16", "10.0.0.0/8", false},
+		{"10.0.0.0/8", "11.0.0.0/16", false},
+		{"fd00::/48", "fd00:0:0:1::/64", true},
+		{"fd00::/48", "10.0.0.0/24", false},
+	}
+	for _, tt := range tests {
+		if got := Contains(netip.MustParsePrefix(tt.outer), netip.MustParsePrefix(tt.inner)); got != tt.want {
+			t.Errorf("Contains(%s, %s) = %v, want %v", tt.outer, tt.inner, got, tt.want)
+		}
+	}
+}
+
+func TestLastAddressAndSize(t *testing.T) {
+	tests := []struct {
+		cidr, last, size string
+	}{
+		{"10.0.0.0/24", "10.0.0.255", "256"},
+		{"10.0.0.7/32", "10.0.0.7", "1"},
+		{"0.0.0.0/0", "255.255.255.255", "4294967296"},
+		{"fd00::/64", "fd00::ffff:ffff:ffff:ffff", "18446744073709551616"},
+		{"::/0", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "340282366920938463463374607431768211456"},
+	}
+	for _, tt := range tests {
+		prefix := netip.MustParsePrefix(tt.cidr)
+		if got := LastAddress(prefix); got.String() != tt.last {
+			t.Errorf("LastAddress(%s) = %s, want %s", tt.cidr, got, tt.last)
+		}
+		if got := Size(prefix); got.String() != tt.size {
+			t.Errorf("Size(%s) = %s, want %s", tt.cidr, got, tt.size)
+		}
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	tests := []struct {
+		from   string
+		remove []string
+		want   string
+	}{
+		{"10.0.0.0/24", []string{"10.0.0.64/26"}, "[10.0.0.0/26 10.0.0.128/25]"},
+		{"10.0.0.0/24", []string{"10.0.0.0/24"}, "[]"},
+		{"10.0.0.0/24", []string{"10.0.0.0/8"}, "[]"},
+		{"10.0.0.0/24", []string{"192.168.0.0/24", "fd00::/64"}, "[10.0.0.0/24]"},
+		{"10.0.0.0/30", []string{"10.0.0.1/32", "10.0.0.2/32"}, "[10.0.0.0/32 10.0.0.3/32]"},
+		{"fd00::/62", []string{"fd00:0:0:1::/64"}, "[fd00::/64 fd00:0:0:2::/63]"},
+	}
+	for _, tt := range tests {
+		got := Subtract(netip.MustParsePrefix(tt.from), ParseCIDRs(tt.remove))
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("Subtract(%s, %v) = %v, want %s", tt.from, tt.remove, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		cidrs []string
+		want  string
+	}{
+		{[]string{"10.0.1.0/24", "10.0.0.0/24"}, "[10.0.0.0/23]"},
+		{[]string{"10.0.0.0/24", "10.0.0.128/25", "10.0.1.0/24", "10.0.2.0/24"}, "[10.0.0.0/23 10.0.2.0/24]"},
+		{[]string{"10.0.1.0/24", "10.0.2.0/24"}, "[10.0.1.0/24 10.0.2.0/24]"},
+		{[]string{"fd00:0:0:1::/64", "10.0.0.0/25", "fd00::/64", "10.0.0.128/25"}, "[10.0.0.0/24 fd00::/63]"},
+		{[]string{"0.0.0.0/1", "128.0.0.0/1", "10.0.0.0/8"}, "[0.0.0.0/0]"},
+		{[]string{"::/1", "8000::/1"}, "[::/0]"},
+		{nil, "[]"},
+	}
+	for _, tt := range tests {
+		if got := Summarize(ParseCIDRs(tt.cidrs)); fmt.Sprint(got) != tt.want {
+			t.Errorf("Summarize(%v) = %v, want %s", tt.cidrs, got, tt.want)
+		}
+	}
+}
+
+func TestRangeToCIDRs(t *testing.T) {
+	tests := []struct {
+		first, last string
+		want        string
+	}{
+		{"10.0.0.0", "10.0.0.255", "[10.0.0.0/24]"},
+		{"10.0.0.1", "10.0.0.6", "[10.0.0.1/32 10.0.0.2/31 10.0.0.4/31 10.0.0.6/32]"},
+		{"10.0.0.5", "10.0.0.5", "[10.0.0.5/32]"},
+		{"0.0.0.0", "255.255.255.255", "[0.0.0.0/0]"},
+		{"fd00::", "fd00::1:ffff", "[fd00::/111]"},
+		{"::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "[::/0]"},
+	}
+	for _, tt := range tests {
+		got, err := RangeToCIDRs(netip.MustParseAddr(tt.first), netip.MustParseAddr(tt.last))
+		if err != nil || fmt.Sprint(got) != tt.want {
+			t.Errorf("RangeToCIDRs(%s, %s) = %v, %v, want %s", tt.first, tt.last, got, err, tt.want)
+		}
+	}
+
+	if _, err := RangeToCIDRs(netip.MustParseAddr("10.0.0.9"), netip.MustParseAddr("10.0.0.1")); err == nil {
+		t.Error("expected an error for a reversed range")
+	}
+	if _, err := RangeToCIDRs(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("fd00::1")); err == nil {
+		t.Error("expected an error for mixed families")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	got, err := Split(netip.MustParsePrefix("10.0.0.0/24"), 26)
+	if err != nil || fmt.Sprint(got) != "[10.0.0.0/26 10.0.0.64/26 10.0.0.128/26 10.0.0.192/26]" {
+		t.Errorf("unexpected IPv4 split %v, %v", got, err)
+	}
+
+	got, err = Split(netip.MustParsePrefix("fd00::/48"), 50)
+	if err != nil || fmt.Sprint(got) != "[fd00::/50 fd00:0:0:4000::/50 fd00:0:0:8000::/50 fd00:0:0:c000::/50]" {
+		t.Errorf("unexpected IPv6 split %v, %v", got, err)
+	}
+
+	if got, err := Split(netip.MustParsePrefix("10.0.0.0/24"), 24); err != nil || len(got) != 1 {
+		t.Errorf("expected splitting into the same length to return the CIDR, got %v, %v", got, err)
+	}
+	if got, err := Split(netip.MustParsePrefix("10.0.0.0/8"), 24); err != nil || len(got) != MaxSplit {
+		t.Errorf("expected %d blocks, got %d, %v", MaxSplit, len(got), err)
+	}
+
+	for _, tt := range []struct {
+		cidr string
+		bits int
+	}{
+		{"10.0.0.0/24", 23},
+		{"10.0.0.0/24", 33},
+		{"fd00::/48", 112},
+	} {
+		if _, err := Split(netip.MustParsePrefix(tt.cidr), tt.bits); err == nil {
+			t.Errorf("expected an error splitting %s into /%d", tt.cidr, tt.bits)
+		}
+	}
+}
+
+func TestSummaryPrefixLength(t *testing.T) {
+	for _, tt := range []struct {
+		prefixLengths []int
+		want          int
+	}{
+		{[]int{24, 24, 24, 24}, 22},
+		{[]int{24, 24, 24}, 22},
+		{[]int{24, 25, 26}, 23},
+		{[]int{26}, 26},
+		{[]int{64, 64}, 63},
+		{[]int{0}, 0},
+	} {
+		if got, err := SummaryPrefixLength(tt.prefixLengths); err != nil || got != tt.want {
+			t.Errorf("expected /%d for %v, got /%d, %v", tt.want, tt.prefixLengths, got, err)
+		}
+	}
+
+	for _, prefixLengths := range [][]int{nil, {0, 0}, {-1}, {129}} {
+		if _, err := SummaryPrefixLength(prefixLengths); err == nil {
+			t.Errorf("expected an error for %v", prefixLengths)
+		}
+	}
+}
+
+func TestPackContiguous(t *testing.T) {
+	got, err := PackContiguous(netip.MustParsePrefix("10.0.4.0/22"), []int{24, 24, 24, 24})
+	if err != nil || fmt.Sprint(got) != "[10.0.4.0/24 10.0.5.0/24 10.0.6.0/24 10.0.7.0/24]" {
+		t.Errorf("unexpected IPv4 blocks %v, %v", got, err)
+	}
+
+	// larger blocks go first so each is aligned, the result keeps the requested order
+	got, err = PackContiguous(netip.MustParsePrefix("10.0.0.0/23"), []int{26, 24, 25})
+	if err != nil || fmt.Sprint(got) != "[10.0.1.128/26 10.0.0.0/24 10.0.1.0/25]" {
+		t.Errorf("unexpected mixed blocks %v, %v", got, err)
+	}
+
+	got, err = PackContiguous(netip.MustParsePrefix("fd00::/63"), []int{64, 64})
+	if err != nil || fmt.Sprint(got) != "[fd00::/64 fd00:0:0:1::/64]" {
+		t.Errorf("unexpected IPv6 blocks %v, %v", got, err)
+	}
+
+	for _, tt := range []struct {
+		summary       string
+		prefixLengths []int
+	}{
+		{"10.0.0.0/23", []int{24, 24, 24}},
+		{"10.0.0.0/24", []int{33}},
+	} {
+		if _, err := PackContiguous(netip.MustParsePrefix(tt.summary), tt.prefixLengths); err == nil {
+			t.Errorf("expected an error packing %v into %s", tt.prefixLengths, tt.summary)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package ipam contains the address math used to allocate CIDR blocks from pools.
+package ipam
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+)
+
+// FindAvailableCIDR searches for an available CIDR block of the requested prefix length
+// within the pool CIDR such that it doesn't overlap with any existing allocations.
+// It returns the lowest such block, or nil if the pool has no room left.
+func FindAvailableCIDR(poolNet *net.IPNet, prefixLength int, allocatedCIDRs []*net.IPNet) *net.IPNet {
+	pool, ok := toPrefix(poolNet)
+	if !ok {
+		return nil
+	}
+
+	allocated := make([]netip.Prefix, 0, len(allocatedCIDRs))
+	for _, allocNet := range allocatedCIDRs {
+		if prefix, ok := toPrefix(allocNet); ok {
+			allocated = append(allocated, prefix)
+		}
+	}
+
+	candidate, ok := NewAllocator([]netip.Prefix{pool}, allocated).Allocate(prefixLength)
+	if !ok {
+		return nil
+	}
+
+	return &net.IPNet{
+		IP:   net.IP(candidate.Addr().AsSlice()),
+		Mask: net.CIDRMask(candidate.Bits(), candidate.Addr().BitLen()),
+	}
+}
+
+// toPrefix converts a net.IPNet, where IPv4 addresses may use the 16 byte form, to a netip.Prefix.
+func toPrefix(ipNet *net.IPNet) (netip.Prefix, bool) {
+	ones, bits := ipNet.Mask.Size()
+	addr, ok := netip.AddrFromSlice(ipNet.IP)
+	if !ok || bits == 0 {
+		return netip.Prefix{}, false
+	}
+	if bits == 32 {
+		addr = addr.Unmap()
+	}
+	return netip.PrefixFrom(addr, ones).Masked(), true
+}
+
+// LastIP returns the last address of the CIDR.
+func LastIP(cidr *net.IPNet) net.IP {
+	ip := make(net.IP, len(cidr.IP))
+	copy(ip, cidr.IP)
+
+	// invert the mask and OR it with the IP to get the last address
+	for i := range ip {
+		ip[i] |= ^cidr.Mask[i]
+	}
+
+	return ip
+}
+
+// Overlaps reports whether the candidate CIDR overlaps any of the allocated CIDRs.
+func Overlaps(candidate *net.IPNet, allocated []*net.IPNet) bool {
+	for _, allocNet := range allocated {
+		// check if either CIDR contains the other's network address
+		if candidate.Contains(allocNet.IP) || allocNet.Contains(candidate.IP) {
+			return true
+		}
+
+		// check if the last IP of candidate is in allocated or vice versa
+		candidateLastIP := LastIP(candidate)
+		allocLastIP := LastIP(allocNet)
+
+		if candidate.Contains(allocLastIP) || allocNet.Contains(candidateLastIP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddressCount returns the number of addresses in the CIDRs. Addresses covered
+// by more than one of the CIDRs are counted once.
+func AddressCount(prefixes []netip.Prefix) *big.Int {
+	total := new(big.Int)
+	for i, prefix := range prefixes {
+		prefix = prefix.Masked()
+
+		var counted []netip.Prefix
+		for _, prior := range prefixes[:i] {
+			if prior.Overlaps(prefix) {
+				counted = append(counted, prior.Masked())
+			}
+		}
+		for _, r := range freeRanges(prefix, counted) {
+			total.Add(total, r.size())
+		}
+	}
+	return total
+}
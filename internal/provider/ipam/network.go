@@ -0,0 +1,118 @@
+package ipam
+
+import (
+	"math/big"
+	"net/netip"
+)
+
+// Network describes the addresses of a CIDR as the hosts on the subnet see them.
+type Network struct {
+	Prefix netip.Prefix
+
+	// Netmask is the prefix length as an address, e.g. 255.255.255.0 for a /24
+	Netmask netip.Addr
+
+	// Broadcast is the last address of an IPv4 network, and invalid for IPv6 and
+	// for /31 and /32 networks, which have no broadcast address
+	Broadcast netip.Addr
+
+	// FirstUsable and LastUsable are the range of addresses hosts can use
+	FirstUsable netip.Addr
+	LastUsable  netip.Addr
+
+	// HostCount is the number of addresses from FirstUsable to LastUsable
+	HostCount *big.Int
+}
+
+// DescribeNetwork returns the network details of the CIDR. IPv4 networks keep
+// their first and last address for the network and broadcast, except for
+// point-to-point /31 networks (RFC 3021) and single hosts. IPv6 networks keep
+// their first address for the subnet-router anycast address, except for /127
+// (RFC 6164) and /128 networks.
+func DescribeNetwork(prefix netip.Prefix) Network {
+	prefix = prefix.Masked()
+	is4 := prefix.Addr().Is4()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+
+	first, last := prefixRange(prefix)
+	n := Network{
+		Prefix:  prefix,
+		Netmask: onesBelow(prefix.Addr().BitLen()).andNot(onesBelow(hostBits)).addr(is4),
+	}
+
+	firstUsable, lastUsable := first, last
+	switch {
+	case hostBits <= 1:
+		// every address is usable
+	case is4:
+		n.Broadcast = last.addr(true)
+		firstUsable = first.add(uint128{lo: 1})
+		lastUsable = last.sub(uint128{lo: 1})
+	default:
+		firstUsable = first.add(uint128{lo: 1})
+	}
+
+	n.FirstUsable = firstUsable.addr(is4)
+	n.LastUsable = lastUsable.addr(is4)
+	n.HostCount = addrRange{first: firstUsable, last: lastUsable}.size()
+	return n
+}
+
+// AddressFamily returns "ipv4" or "ipv6".
+func (n Network) AddressFamily() string {
+	return AddressFamily(n.Prefix.Addr())
+}
+
+// AddressFamily returns "ipv4" or "ipv6" for the address.
+func AddressFamily(addr netip.Addr) string {
+	if addr.Is4() {
+		return "ipv4"
+	}
+	return "ipv6"
+}
+
+// UsableAt returns the address at the offset from the network address. Negative
+// offsets count back from the end of the usable range, -1 being the last usable
+// address. It returns false if the address is not usable by hosts.
+func (n Network) UsableAt(offset int64) (netip.Addr, bool) {
+	is4 := n.Prefix.Addr().Is4()
+	first, _ := prefixRange(n.Prefix)
+	firstUsable, lastUsable := fromAddr(n.FirstUsable), fromAddr(n.LastUsable)
+
+	var addr uint128
+	if offset >= 0 {
+		distance := uint128{lo: uint64(offset)}
+		if lastUsable.sub(first).less(distance) {
+			return netip.Addr{}, false
+		}
+		addr = first.add(distance)
+	} else {
+		distance := uint128{lo: uint64(-(offset + 1))}
+		if lastUsable.sub(firstUsable).less(distance) {
+			return netip.Addr{}, false
+		}
+		addr = lastUsable.sub(distance)
+	}
+
+	if addr.less(firstUsable) {
+		return netip.Addr{}, false
+	}
+	return addr.addr(is4), true
+}
+
+// Usable reports whether the address is in the usable range of the network.
+func (n Network) Usable(addr netip.Addr) bool {
+	return addr.BitLen() == n.FirstUsable.BitLen() &&
+		addr.Compare(n.FirstUsable) >= 0 && addr.Compare(n.LastUsable) <= 0
+}
+
+// FirstFree returns the lowest usable address that isn't taken. It returns false
+// when every usable address is taken.
+func (n Network) FirstFree(taken map[netip.Addr]bool) (netip.Addr, bool) {
+	for addr := n.FirstUsable; n.Usable(addr); addr = addr.Next() {
+		if !taken[addr] {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
@@ -0,0 +1,115 @@
+package ipam
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestDescribeNetwork(t *testing.T) {
+	tests := map[string]struct {
+		netmask, broadcast, firstUsable, lastUsable, hostCount string
+	}{
+		"10.0.1.0/24":         {"255.255.255.0", "10.0.1.255", "10.0.1.1", "10.0.1.254", "254"},
+		"10.0.0.16/28":        {"255.255.255.240", "10.0.0.31", "10.0.0.17", "10.0.0.30", "14"},
+		"10.0.0.2/31":         {"255.255.255.254", "invalid IP", "10.0.0.2", "10.0.0.3", "2"},
+		"10.0.0.7/32":         {"255.255.255.255", "invalid IP", "10.0.0.7", "10.0.0.7", "1"},
+		"2001:db8::/64":       {"ffff:ffff:ffff:ffff::", "invalid IP", "2001:db8::1", "2001:db8::ffff:ffff:ffff:ffff", "18446744073709551615"},
+		"2001:db8::/127":      {"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe", "invalid IP", "2001:db8::", "2001:db8::1", "2"},
+		"::/0":                {"::", "invalid IP", "::1", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "340282366920938463463374607431768211455"},
+		"0.0.0.0/0":           {"0.0.0.0", "255.255.255.255", "0.0.0.1", "255.255.255.254", "4294967294"},
+		"2001:db8:1:2::5/128": {"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "invalid IP", "2001:db8:1:2::5", "2001:db8:1:2::5", "1"},
+	}
+
+	for cidr, want := range tests {
+		t.Run(cidr, func(t *testing.T) {
+			n := DescribeNetwork(netip.MustParsePrefix(cidr))
+			got := [...]string{n.Netmask.String(), n.Broadcast.String(), n.FirstUsable.String(), n.LastUsable.String(), n.HostCount.String()}
+			if got != [...]string{want.netmask, want.broadcast, want.firstUsable, want.lastUsable, want.hostCount} {
+				t.Fatalf("expected %+v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestNetwork_UsableAt(t *testing.T) {
+	tests := []struct {
+		cidr   string
+		offset int64
+		want   string
+	}{
+		{"10.0.1.0/24", 1, "10.0.1.1"},
+		{"10.0.1.0/24", 254, "10.0.1.254"},
+		{"10.0.1.0/24", 255, "invalid IP"},
+		{"10.0.1.0/24", 0, "invalid IP"},
+		{"10.0.1.0/24", -1, "10.0.1.254"},
+		{"10.0.1.0/24", -254, "10.0.1.1"},
+		{"10.0.1.0/24", -255, "invalid IP"},
+		{"10.0.0.2/31", 0, "10.0.0.2"},
+		{"10.0.0.7/32", 1, "invalid IP"},
+		{"2001:db8::/64", 1, "2001:db8::1"},
+		{"2001:db8::/64", -1, "2001:db8::ffff:ffff:ffff:ffff"},
+		{"::/0", 1 << 62, "::4000:0:0:0"},
+	}
+
+	for _, tt := range tests {
+		got, ok := DescribeNetwork(netip.MustParsePrefix(tt.cidr)).UsableAt(tt.offset)
+		if got.String() != tt.want || ok != got.IsValid() {
+			t.Errorf("%s at %d: expected %s, got %s (%t)", tt.cidr, tt.offset, tt.want, got, ok)
+		}
+	}
+}
+
+func TestNetwork_Usable(t *testing.T) {
+	tests := []struct {
+		cidr string
+		addr string
+		want bool
+	}{
+		{"10.0.1.0/24", "10.0.1.0", false},
+		{"10.0.1.0/24", "10.0.1.1", true},
+		{"10.0.1.0/24", "10.0.1.254", true},
+		{"10.0.1.0/24", "10.0.1.255", false},
+		{"10.0.1.0/24", "10.0.2.1", false},
+		{"10.0.0.2/31", "10.0.0.2", true},
+		{"2001:db8::/64", "2001:db8::", false},
+		{"2001:db8::/64", "2001:db8::ffff:ffff:ffff:ffff", true},
+		{"::/96", "0.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := DescribeNetwork(netip.MustParsePrefix(tt.cidr)).Usable(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("%s contains %s: expected %t, got %t", tt.cidr, tt.addr, tt.want, got)
+		}
+	}
+}
+
+func TestNetwork_FirstFree(t *testing.T) {
+	taken := func(addrs ...string) map[netip.Addr]bool {
+		m := make(map[netip.Addr]bool, len(addrs))
+		for _, addr := range addrs {
+			m[netip.MustParseAddr(addr)] = true
+		}
+		return m
+	}
+
+	tests := []struct {
+		cidr  string
+		taken map[netip.Addr]bool
+		want  string
+	}{
+		{"10.0.1.0/24", nil, "10.0.1.1"},
+		{"10.0.1.0/24", taken("10.0.1.1", "10.0.1.2", "10.0.1.4"), "10.0.1.3"},
+		{"10.0.0.0/30", taken("10.0.0.1", "10.0.0.2"), "invalid IP"},
+		{"10.0.0.2/31", taken("10.0.0.2"), "10.0.0.3"},
+		{"2001:db8::/64", taken("2001:db8::1"), "2001:db8::2"},
+		{"255.255.255.255/32", nil, "255.255.255.255"},
+		{"255.255.255.255/32", taken("255.255.255.255"), "invalid IP"},
+	}
+
+	for _, tt := range tests {
+		got, ok := DescribeNetwork(netip.MustParsePrefix(tt.cidr)).FirstFree(tt.taken)
+		if got.String() != tt.want || ok != got.IsValid() {
+			t.Errorf("%s: expected %s, got %s (%t)", tt.cidr, tt.want, got, ok)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package ipam
+
+import (
+	"math/rand/v2"
+	"net/netip"
+)
+
+// Strategy decides which free block an allocation takes.
+type Strategy string
+
+const (
+	// FirstFit takes the lowest free address, searching the pool CIDRs in order.
+	FirstFit Strategy = "first_fit"
+
+	// BestFit takes the smallest free block that fits, which keeps large blocks
+	// intact for later allocations.
+	BestFit Strategy = "best_fit"
+
+	// LastFit takes the highest free address, searching the pool CIDRs in reverse order.
+	LastFit Strategy = "last_fit"
+
+	// Random takes a random aligned block from a random free block that fits.
+	Random Strategy = "random"
+
+	// Sequential takes the first free block after the one allocated last and
+	// only wraps around to the start of the pool when it reaches the end, so
+	// released blocks are not reused right away.
+	Sequential Strategy = "sequential"
+)
+
+// Strategies returns the names of the supported allocation strategies.
+func Strategies() []string {
+	return []string{string(FirstFit), string(BestFit), string(LastFit), string(Random), string(Sequential)}
+}
+
+func (a *Allocator) firstFit(prefixLength int) (netip.Prefix, bool) {
+	var (
+		best  block
+		found bool
+	)
+	a.fitting(prefixLength, func(t *blockTree) {
+		if blk, _ := t.min(); !found || blk.less(best) {
+			best, found = blk, true
+		}
+	})
+	if !found {
+		return netip.Prefix{}, false
+	}
+
+	return a.carve(best, netip.PrefixFrom(best.prefix.Addr(), prefixLength)), true
+}
+
+func (a *Allocator) lastFit(prefixLength int) (netip.Prefix, bool) {
+	var (
+		best  block
+		found bool
+	)
+	a.fitting(prefixLength, func(t *blockTree) {
+		if blk, _ := t.max(); !found || best.less(blk) {
+			best, found = blk, true
+		}
+	})
+	if !found {
+		return netip.Prefix{}, false
+	}
+
+	_, last := prefixRange(best.prefix)
+	target := netip.PrefixFrom(last.addr(best.prefix.Addr().Is4()), prefixLength).Masked()
+	return a.carve(best, target), true
+}
+
+func (a *Allocator) bestFit(prefixLength int) (netip.Prefix, bool) {
+	var (
+		best     block
+		bestSize int
+		found    bool
+	)
+	a.fitting(prefixLength, func(t *blockTree) {
+		blk, _ := t.min()
+		size := blk.prefix.Addr().BitLen() - blk.prefix.Bits()
+		if !found || size < bestSize || (size == bestSize && blk.less(best)) {
+			best, bestSize, found = blk, size, true
+		}
+	})
+	if !found {
+		return netip.Prefix{}, false
+	}
+
+	return a.carve(best, netip.PrefixFrom(best.prefix.Addr(), prefixLength)), true
+}
+
+func (a *Allocator) random(prefixLength int) (netip.Prefix, bool) {
+	var trees []*blockTree
+	total := 0
+	a.fitting(prefixLength, func(t *blockTree) {
+		trees = append(trees, t)
+		total += t.Len()
+	})
+	if total == 0 {
+		return netip.Prefix{}, false
+	}
+
+	k := rand.IntN(total)
+	var blk block
+	for _, t := range trees {
+		if k < t.Len() {
+			blk = t.at(k)
+			break
+		}
+		k -= t.Len()
+	}
+
+	// pick one of the aligned blocks of the prefix length within the free block
+	hostBits := blk.prefix.Addr().BitLen() - prefixLength
+	offset := uint128{hi: rand.Uint64(), lo: rand.Uint64()}.
+		and(onesBelow(prefixLength - blk.prefix.Bits())).
+		shl(hostBits)
+	start := fromAddr(blk.prefix.Addr()).add(offset)
+
+	return a.carve(blk, netip.PrefixFrom(start.addr(blk.prefix.Addr().Is4()), prefixLength)), true
+}
+
+func (a *Allocator) sequential(prefixLength int) (netip.Prefix, bool) {
+	key, ok := a.afterCursor()
+	if !ok {
+		return a.firstFit(prefixLength)
+	}
+
+	var (
+		best   block
+		target netip.Prefix
+		found  bool
+	)
+	consider := func(blk block, candidate netip.Prefix) {
+		if c := (block{pool: blk.pool, prefix: candidate}); !found || c.less(block{pool: best.pool, prefix: target}) {
+			best, target, found = blk, candidate, true
+		}
+	}
+
+	start := key.prefix.Addr()
+	a.fitting(prefixLength, func(t *blockTree) {
+		// the free block the cursor ends in may still have room after it
+		if blk, ok := t.floor(key); ok && blk.pool == key.pool && blk.prefix.Contains(start) {
+			if candidate, ok := alignUp(start, prefixLength, blk.prefix); ok {
+				consider(blk, candidate)
+				return
+			}
+		}
+		if blk, ok := t.ceiling(key); ok {
+			consider(blk, netip.PrefixFrom(blk.prefix.Addr(), prefixLength))
+		}
+	})
+	if !found {
+		// reached the end of the pool, wrap around to the start
+		return a.firstFit(prefixLength)
+	}
+
+	return a.carve(best, target), true
+}
+
+// afterCursor returns the position right after the cursor as a search key.
+func (a *Allocator) afterCursor() (block, bool) {
+	if !a.cursor.IsValid() {
+		return block{}, false
+	}
+
+	for i, pool := range a.pools {
+		if !pool.Contains(a.cursor.Addr()) {
+			continue
+		}
+
+		_, cursorLast := prefixRange(a.cursor)
+		_, poolLast := prefixRange(pool)
+		if cursorLast == poolLast {
+			if i+1 == len(a.pools) {
+				return block{}, false
+			}
+			return block{pool: i + 1, prefix: netip.PrefixFrom(a.pools[i+1].Addr(), 0)}, true
+		}
+
+		next := cursorLast.add(uint128{lo: 1})
+		return block{pool: i, prefix: netip.PrefixFrom(next.addr(pool.Addr().Is4()), 0)}, true
+	}
+
+	return block{}, false
+}
+
+// alignUp returns the first block of the prefix length starting at or after addr
+// that lies within the free block.
+func alignUp(addr netip.Addr, prefixLength int, within netip.Prefix) (netip.Prefix, bool) {
+	mask := onesBelow(addr.BitLen() - prefixLength)
+	start := fromAddr(addr)
+	aligned := start.add(mask).andNot(mask)
+	if aligned.less(start) || (addr.Is4() && aligned.hi|aligned.lo>>32 != 0) {
+		// rounding up ran past the end of the address space
+		return netip.Prefix{}, false
+	}
+
+	candidate := netip.PrefixFrom(aligned.addr(addr.Is4()), prefixLength)
+	_, last := prefixRange(within)
+	_, candidateLast := prefixRange(candidate)
+	if last.less(candidateLast) {
+		return netip.Prefix{}, false
+	}
+
+	return candidate, true
+}
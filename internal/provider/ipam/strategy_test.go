@@ -0,0 +1,231 @@
+package ipam
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+// fragmentedPool has a free /26 at the bottom and a free /28 and /27 at the top.
+func fragmentedPool() *Allocator {
+	return NewAllocator(
+		ParseCIDRs([]string{"10.0.0.0/24"}),
+		ParseCIDRs([]string{"10.0.0.64/26", "10.0.0.128/26", "10.0.0.192/28"}),
+	)
+}
+
+func TestStrategies_SingleAllocation(t *testing.T) {
+	tests := map[Strategy]string{
+		FirstFit: "10.0.0.0/28",
+		BestFit:  "10.0.0.208/28",
+		LastFit:  "10.0.0.240/28",
+	}
+
+	for strategy, want := range tests {
+		t.Run(string(strategy), func(t *testing.T) {
+			got, ok := fragmentedPool().AllocateWith(28, strategy)
+			if !ok || got.String() != want {
+				t.Fatalf("expected %s, got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestStrategies_BestFitKeepsLargeBlocks(t *testing.T) {
+	// first_fit splits the only free /26 for a /28, best_fit uses the /28 hole
+	firstFit := fragmentedPool()
+	firstFit.AllocateWith(28, FirstFit)
+	if got, ok := firstFit.AllocateWith(26, FirstFit); ok {
+		t.Fatalf("expected no /26 left after first_fit, got %s", got)
+	}
+
+	bestFit := fragmentedPool()
+	bestFit.AllocateWith(28, BestFit)
+	if got, ok := bestFit.AllocateWith(26, BestFit); !ok || got.String() != "10.0.0.0/26" {
+		t.Fatalf("expected 10.0.0.0/26 after best_fit, got %s", got)
+	}
+}
+
+func TestStrategies_LastFitPoolCIDROrder(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"10.0.0.0/30", "10.5.0.0/30"}), nil)
+
+	var got []string
+	for {
+		prefix, ok := a.AllocateWith(31, LastFit)
+		if !ok {
+			break
+		}
+		got = append(got, prefix.String())
+	}
+
+	want := []string{"10.5.0.2/31", "10.5.0.0/31", "10.0.0.2/31", "10.0.0.0/31"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestStrategies_SequentialSkipsReleasedBlocks(t *testing.T) {
+	pools := ParseCIDRs([]string{"10.0.0.0/24"})
+	allocated := map[string]netip.Prefix{}
+	var cursor netip.Prefix
+
+	// every allocation rebuilds the allocator from storage, like the provider does
+	allocate := func(id string) string {
+		a := NewAllocator(pools, mapValues(allocated))
+		a.SetCursor(cursor)
+		prefix, ok := a.AllocateWith(26, Sequential)
+		if !ok {
+			t.Fatalf("allocation %s failed", id)
+		}
+		allocated[id] = prefix
+		cursor = a.Cursor()
+		return prefix.String()
+	}
+
+	allocate("a")
+	allocate("b")
+	delete(allocated, "a")
+
+	for _, want := range []string{"10.0.0.128/26", "10.0.0.192/26", "10.0.0.0/26"} {
+		if got := allocate(want); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestStrategies_SequentialMixedSizes(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"10.0.0.0/24", "10.1.0.0/24"}), nil)
+	a.SetCursor(netip.MustParsePrefix("10.0.0.4/30"))
+
+	// the next /28 boundary after the cursor, then the next pool CIDR once the first is exhausted
+	if got, _ := a.AllocateWith(28, Sequential); got.String() != "10.0.0.16/28" {
+		t.Fatalf("expected 10.0.0.16/28, got %s", got)
+	}
+	a.SetCursor(netip.MustParsePrefix("10.0.0.252/30"))
+	if got, _ := a.AllocateWith(28, Sequential); got.String() != "10.1.0.0/28" {
+		t.Fatalf("expected 10.1.0.0/28, got %s", got)
+	}
+}
+
+func TestStrategies_RandomStaysWithinFreeSpace(t *testing.T) {
+	a := NewAllocator(ParseCIDRs([]string{"10.0.0.0/24"}), ParseCIDRs([]string{"10.0.0.0/25"}))
+
+	var allocated []netip.Prefix
+	for {
+		prefix, ok := a.AllocateWith(29, Random)
+		if !ok {
+			break
+		}
+		if !netip.MustParsePrefix("10.0.0.128/25").Contains(prefix.Addr()) {
+			t.Fatalf("%s is outside the free space", prefix)
+		}
+		for _, other := range allocated {
+			if other.Overlaps(prefix) {
+				t.Fatalf("%s overlaps %s", prefix, other)
+			}
+		}
+		allocated = append(allocated, prefix)
+	}
+
+	if len(allocated) != 16 {
+		t.Fatalf("expected the 16 free /29s to be allocated, got %d", len(allocated))
+	}
+}
+
+func TestStrategies_RandomIPv6(t *testing.T) {
+	pool := netip.MustParsePrefix("2001:db8::/32")
+	a := NewAllocator([]netip.Prefix{pool}, nil)
+
+	seen := map[netip.Prefix]bool{}
+	for i := 0; i < 100; i++ {
+		prefix, ok := a.AllocateWith(64, Random)
+		if !ok || !pool.Contains(prefix.Addr()) || prefix.Bits() != 64 {
+			t.Fatalf("expected a /64 within %s, got %s", pool, prefix)
+		}
+		if seen[prefix] {
+			t.Fatalf("%s was allocated twice", prefix)
+		}
+		seen[prefix] = true
+	}
+}
+
+// TestStrategies_Fragmentation runs the same allocate and release workload with
+// each strategy and compares how fragmented the pool ends up.
+func TestStrategies_Fragmentation(t *testing.T) {
+	results := map[Strategy]fragmentation{}
+	for _, name := range Strategies() {
+		results[Strategy(name)] = churn(t, Strategy(name))
+		t.Logf("%-10s free blocks: %3d, largest free block: /%d, failed allocations: %d",
+			name, results[Strategy(name)].freeBlocks, results[Strategy(name)].largest, results[Strategy(name)].failed)
+	}
+
+	// the fitting strategies pack allocations together, random and sequential spread them over the pool
+	for _, packed := range []Strategy{FirstFit, BestFit, LastFit} {
+		for _, spread := range []Strategy{Random, Sequential} {
+			if results[packed].freeBlocks >= results[spread].freeBlocks {
+				t.Errorf("expected %s to leave fewer free blocks than %s, got %d and %d",
+					packed, spread, results[packed].freeBlocks, results[spread].freeBlocks)
+			}
+		}
+	}
+}
+
+type fragmentation struct {
+	freeBlocks int
+	largest    int
+	failed     int
+}
+
+// churn allocates and releases blocks of mixed sizes in a /16 in a fixed order.
+func churn(t *testing.T, strategy Strategy) fragmentation {
+	t.Helper()
+
+	rng := rand.New(rand.NewSource(42))
+	pools := ParseCIDRs([]string{"10.0.0.0/16"})
+	allocated := map[int]netip.Prefix{}
+	var cursor netip.Prefix
+	result := fragmentation{}
+
+	for step := 0; step < 2000; step++ {
+		if len(allocated) > 0 && rng.Intn(2) == 0 {
+			keys := mapKeys(allocated)
+			delete(allocated, keys[rng.Intn(len(keys))])
+			continue
+		}
+
+		a := NewAllocator(pools, mapValues(allocated))
+		a.SetCursor(cursor)
+		prefixLength := []int{24, 26, 28, 30}[rng.Intn(4)]
+		prefix, ok := a.AllocateWith(prefixLength, strategy)
+		if !ok {
+			result.failed++
+			continue
+		}
+		allocated[step] = prefix
+		cursor = a.Cursor()
+	}
+
+	free := NewAllocator(pools, mapValues(allocated)).Free()
+	result.freeBlocks = len(free)
+	result.largest = 33
+	for _, prefix := range free {
+		result.largest = min(result.largest, prefix.Bits())
+	}
+	return result
+}
+
+func mapKeys(m map[int]netip.Prefix) []int {
+	// map order is random, sort so the workload is the same on every run
+	return slices.Sorted(maps.Keys(m))
+}
+
+func mapValues[K comparable](m map[K]netip.Prefix) []netip.Prefix {
+	values := make([]netip.Prefix, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,213 @@
+package ipam
+
+import "math/rand/v2"
+
+// blockTree is an ordered set of free blocks, implemented as a treap with
+// subtree sizes so the lowest, highest, nearest and k-th block are all found
+// in O(log n).
+type blockTree struct {
+	root *treeNode
+}
+
+type treeNode struct {
+	blk         block
+	priority    uint64
+	size        int
+	left, right *treeNode
+}
+
+func (t *blockTree) Len() int {
+	return t.root.len()
+}
+
+func (t *blockTree) insert(blk block) {
+	t.root = t.root.insert(&treeNode{blk: blk, priority: rand.Uint64(), size: 1})
+}
+
+func (t *blockTree) remove(blk block) {
+	t.root = t.root.remove(blk)
+}
+
+// contains reports whether the exact block is in the tree.
+func (t *blockTree) contains(blk block) bool {
+	for n := t.root; n != nil; {
+		switch {
+		case blk.less(n.blk):
+			n = n.left
+		case n.blk.less(blk):
+			n = n.right
+		default:
+			return n.blk.prefix == blk.prefix
+		}
+	}
+	return false
+}
+
+func (t *blockTree) min() (block, bool) {
+	n := t.root
+	if n == nil {
+		return block{}, false
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return n.blk, true
+}
+
+func (t *blockTree) max() (block, bool) {
+	n := t.root
+	if n == nil {
+		return block{}, false
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return n.blk, true
+}
+
+// floor returns the highest block that does not sort after key.
+func (t *blockTree) floor(key block) (block, bool) {
+	var found *treeNode
+	for n := t.root; n != nil; {
+		if key.less(n.blk) {
+			n = n.left
+		} else {
+			found = n
+			n = n.right
+		}
+	}
+	if found == nil {
+		return block{}, false
+	}
+	return found.blk, true
+}
+
+// ceiling returns the lowest block that does not sort before key.
+func (t *blockTree) ceiling(key block) (block, bool) {
+	var found *treeNode
+	for n := t.root; n != nil; {
+		if n.blk.less(key) {
+			n = n.right
+		} else {
+			found = n
+			n = n.left
+		}
+	}
+	if found == nil {
+		return block{}, false
+	}
+	return found.blk, true
+}
+
+// at returns the k-th lowest block, counting from zero.
+func (t *blockTree) at(k int) block {
+	n := t.root
+	for {
+		left := n.left.len()
+		switch {
+		case k < left:
+			n = n.left
+		case k == left:
+			return n.blk
+		default:
+			k -= left + 1
+			n = n.right
+		}
+	}
+}
+
+// all returns the blocks in order.
+func (t *blockTree) all() []block {
+	blocks := make([]block, 0, t.Len())
+	var walk func(n *treeNode)
+	walk = func(n *treeNode) {
+		if n == nil {
+			return
+		}
+		walk(n.left)
+		blocks = append(blocks, n.blk)
+		walk(n.right)
+	}
+	walk(t.root)
+	return blocks
+}
+
+func (n *treeNode) len() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *treeNode) update() {
+	n.size = 1 + n.left.len() + n.right.len()
+}
+
+func (n *treeNode) insert(x *treeNode) *treeNode {
+	if n == nil {
+		return x
+	}
+	if x.priority > n.priority {
+		x.left, x.right = n.split(x.blk)
+		x.update()
+		return x
+	}
+	if x.blk.less(n.blk) {
+		n.left = n.left.insert(x)
+	} else {
+		n.right = n.right.insert(x)
+	}
+	n.update()
+	return n
+}
+
+func (n *treeNode) remove(blk block) *treeNode {
+	if n == nil {
+		return nil
+	}
+	switch {
+	case blk.less(n.blk):
+		n.left = n.left.remove(blk)
+	case n.blk.less(blk):
+		n.right = n.right.remove(blk)
+	default:
+		return merge(n.left, n.right)
+	}
+	n.update()
+	return n
+}
+
+// split divides the tree into the blocks sorting before key and the rest.
+func (n *treeNode) split(key block) (*treeNode, *treeNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if n.blk.less(key) {
+		left, right := n.right.split(key)
+		n.right = left
+		n.update()
+		return n, right
+	}
+	left, right := n.left.split(key)
+	n.left = right
+	n.update()
+	return left, n
+}
+
+// merge joins two trees where every block of left sorts before every block of right.
+func merge(left, right *treeNode) *treeNode {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	case left.priority > right.priority:
+		left.right = merge(left.right, right)
+		left.update()
+		return left
+	default:
+		right.left = merge(left, right.left)
+		right.update()
+		return right
+	}
+}
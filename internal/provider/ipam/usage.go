@@ -0,0 +1,52 @@
+package ipam
+
+import (
+	"math/big"
+	"net/netip"
+)
+
+// FreeSpace summarizes the free space of one pool CIDR.
+type FreeSpace struct {
+	CIDR netip.Prefix
+
+	// Free is the number of free addresses in the CIDR.
+	Free *big.Int
+
+	// Largest is the largest free block, the lowest one if several are equally
+	// large. It is invalid if the CIDR is full.
+	Largest netip.Prefix
+
+	// Blocks counts the free blocks by prefix length. Free space is split into
+	// the largest aligned blocks, so a free /23 counts as one /23, not two /24s.
+	Blocks map[int]int
+}
+
+// FreeSpace returns the free space of each pool CIDR, in the order of the pool
+// CIDRs. Space shared with an earlier pool CIDR is counted there.
+func (a *Allocator) FreeSpace() []FreeSpace {
+	spaces := make([]FreeSpace, len(a.pools))
+	for i, pool := range a.pools {
+		spaces[i] = FreeSpace{CIDR: pool, Free: new(big.Int), Blocks: map[int]int{}}
+	}
+
+	for family := range a.buckets {
+		for _, t := range a.buckets[family] {
+			if t == nil {
+				continue
+			}
+			for _, blk := range t.all() {
+				space := &spaces[blk.pool]
+				first, last := prefixRange(blk.prefix)
+				space.Free.Add(space.Free, addrRange{first, last}.size())
+				space.Blocks[blk.prefix.Bits()]++
+
+				if !space.Largest.IsValid() || blk.prefix.Bits() < space.Largest.Bits() ||
+					blk.prefix.Bits() == space.Largest.Bits() && blk.prefix.Addr().Less(space.Largest.Addr()) {
+					space.Largest = blk.prefix
+				}
+			}
+		}
+	}
+
+	return spaces
+}
@@ -0,0 +1,52 @@
+package ipam
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAllocator_FreeSpace(t *testing.T) {
+	pools := ParseCIDRs([]string{"10.0.0.0/24", "10.1.0.0/24", "fd00::/48"})
+	allocated := ParseCIDRs([]string{"10.0.0.0/26", "10.0.0.128/27", "10.1.0.0/24", "fd00::/64"})
+
+	spaces := NewAllocator(pools, allocated).FreeSpace()
+	if len(spaces) != 3 {
+		t.Fatalf("expected 3 pool CIDRs, got %d", len(spaces))
+	}
+
+	// 10.0.0.64/26, 10.0.0.160/27 and 10.0.0.192/26 are free
+	v4 := spaces[0]
+	if v4.Free.String() != "160" {
+		t.Errorf("expected 160 free addresses, got %s", v4.Free)
+	}
+	if v4.Largest.String() != "10.0.0.64/26" {
+		t.Errorf("expected the lowest of the largest blocks, got %s", v4.Largest)
+	}
+	if fmt.Sprint(v4.Blocks) != "map[26:2 27:1]" {
+		t.Errorf("unexpected free blocks %v", v4.Blocks)
+	}
+
+	if full := spaces[1]; full.Free.Sign() != 0 || full.Largest.IsValid() || len(full.Blocks) != 0 {
+		t.Errorf("expected a full pool CIDR, got %+v", full)
+	}
+
+	// 2^80 - 2^64 free addresses exceed 64 bits
+	v6 := spaces[2]
+	if v6.Free.String() != "1208907372870555465154560" {
+		t.Errorf("unexpected free IPv6 addresses %s", v6.Free)
+	}
+	if v6.Largest.String() != "fd00:0:0:8000::/49" {
+		t.Errorf("unexpected largest IPv6 block %s", v6.Largest)
+	}
+	if len(v6.Blocks) != 16 || v6.Blocks[64] != 1 || v6.Blocks[49] != 1 {
+		t.Errorf("expected one free block of each length from /49 to /64, got %v", v6.Blocks)
+	}
+}
+
+func TestAllocator_FreeSpaceOverlappingPoolCIDRs(t *testing.T) {
+	// the /25 is part of the /24, so its space belongs to the first pool CIDR
+	spaces := NewAllocator(ParseCIDRs([]string{"10.0.0.0/24", "10.0.0.0/25"}), nil).FreeSpace()
+	if spaces[0].Free.String() != "256" || spaces[1].Free.Sign() != 0 {
+		t.Fatalf("expected shared space to be counted once, got %s and %s", spaces[0].Free, spaces[1].Free)
+	}
+}
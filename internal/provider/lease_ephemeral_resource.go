@@ -0,0 +1,352 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ ephemeral.EphemeralResourceWithRenew = &LeaseEphemeralResource{}
+var _ ephemeral.EphemeralResourceWithClose = &LeaseEphemeralResource{}
+var _ ephemeral.EphemeralResourceWithConfigure = &LeaseEphemeralResource{}
+
+// defaultLeaseTTL is how long a lease is held when ttl is not set.
+const defaultLeaseTTL = 30 * time.Minute
+
+// leasePrivateKey is the private data key that carries the lease to Renew and Close.
+const leasePrivateKey = "lease"
+
+func NewLeaseEphemeralResource() ephemeral.EphemeralResource {
+	return &LeaseEphemeralResource{}
+}
+
+type LeaseEphemeralResource struct {
+	provider *IpamProvider
+}
+
+type LeaseEphemeralResourceModel struct {
+	PoolName      types.String `tfsdk:"pool_name"`
+	PrefixLength  types.Int64  `tfsdk:"prefix_length"`
+	Strategy      types.String `tfsdk:"allocation_strategy"`
+	TTL           types.String `tfsdk:"ttl"`
+	ID            types.String `tfsdk:"id"`
+	AllocatedCIDR types.String `tfsdk:"allocated_cidr"`
+	ExpiresAt     types.String `tfsdk:"expires_at"`
+}
+
+// leasePrivateData identifies the lease between Open, Renew and Close.
+type leasePrivateData struct {
+	PoolName string        `json:"pool_name"`
+	ID       string        `json:"id"`
+	TTL      time.Duration `json:"ttl"`
+}
+
+func (r *LeaseEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_lease"
+}
+
+func (r *LeaseEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Leases a CIDR from a pool for the duration of a Terraform run without writing it to state. " +
+			"The lease is renewed while the run lasts and released when it ends. Storage records when the lease expires, " +
+			"so the CIDR of a run that crashed becomes free again once `ttl` has passed.",
+
+		Attributes: map[string]schema.Attribute{
+			"pool_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the pool to lease from",
+			},
+			"prefix_length": schema.Int64Attribute{
+				Required:            true,
+				MarkdownDescription: "Prefix length of the leased CIDR",
+			},
+			"allocation_strategy": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Overrides the pool's `allocation_strategy` for this lease. Supported values: 'first_fit', 'best_fit', 'last_fit', 'random', 'sequential'.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(ipam.Strategies()...),
+				},
+			},
+			"ttl": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: fmt.Sprintf("How long the lease is held without being renewed, such as '10m' or '2h'. Defaults to '%s'.", defaultLeaseTTL),
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Generated identifier of the lease",
+			},
+			"allocated_cidr": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The leased CIDR",
+			},
+			"expires_at": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "When the lease expires unless renewed, in RFC 3339 format",
+			},
+		},
+	}
+}
+
+func (r *LeaseEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.provider = provider
+}
+
+func (r *LeaseEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var data LeaseEphemeralResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	prefixLength := int(data.PrefixLength.ValueInt64())
+	if prefixLength < 0 || prefixLength > 128 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("prefix_length"),
+			"Invalid Prefix Length",
+			fmt.Sprintf("Prefix length must be between 0 and 128, got %d", prefixLength),
+		)
+		return
+	}
+
+	ttl := defaultLeaseTTL
+	if !data.TTL.IsNull() {
+		var err error
+		ttl, err = time.ParseDuration(data.TTL.ValueString())
+		if err != nil || ttl <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("ttl"),
+				"Invalid TTL",
+				fmt.Sprintf("ttl must be a positive duration such as '10m' or '2h', got '%s'", data.TTL.ValueString()),
+			)
+			return
+		}
+	}
+
+	poolName := data.PoolName.ValueString()
+	lease, err := r.provider.openLease(ctx, poolName, prefixLength, ipam.Strategy(data.Strategy.ValueString()), ttl)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Lease Failed",
+			fmt.Sprintf("Unable to lease CIDR from pool %s: %s", poolName, err),
+		)
+		return
+	}
+
+	private, err := json.Marshal(leasePrivateData{PoolName: poolName, ID: lease.ID, TTL: ttl})
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to Encode Lease", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, leasePrivateKey, private)...)
+
+	data.ID = types.StringValue(lease.ID)
+	data.AllocatedCIDR = types.StringValue(lease.CIDR)
+	data.ExpiresAt = types.StringValue(lease.ExpiresAt.Format(time.RFC3339))
+	resp.RenewAt = leaseRenewAt(lease, ttl)
+
+	tflog.Trace(ctx, "opened lease", map[string]any{
+		"id":             lease.ID,
+		"pool_name":      poolName,
+		"allocated_cidr": lease.CIDR,
+	})
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
+
+func (r *LeaseEphemeralResource) Renew(ctx context.Context, req ephemeral.RenewRequest, resp *ephemeral.RenewResponse) {
+	private, diags := readLeasePrivateData(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	lease, err := r.provider.renewLease(ctx, private.PoolName, private.ID, private.TTL)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Renew Lease",
+			fmt.Sprintf("Unable to renew lease %s in pool %s: %s", private.ID, private.PoolName, err),
+		)
+		return
+	}
+
+	resp.RenewAt = leaseRenewAt(lease, private.TTL)
+}
+
+func (r *LeaseEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	private, diags := readLeasePrivateData(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.provider.closeLease(ctx, private.PoolName, private.ID); err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Release Lease",
+			fmt.Sprintf("Unable to release lease %s in pool %s: %s", private.ID, private.PoolName, err),
+		)
+	}
+}
+
+// privateDataGetter is the part of the framework's private data used here, which
+// is passed to Renew and Close.
+type privateDataGetter interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+func readLeasePrivateData(ctx context.Context, private privateDataGetter) (leasePrivateData, diag.Diagnostics) {
+	var data leasePrivateData
+	var diags diag.Diagnostics
+
+	raw, getDiags := private.GetKey(ctx, leasePrivateKey)
+	diags.Append(getDiags...)
+	if diags.HasError() {
+		return data, diags
+	}
+	if err := json.Unmarshal(raw, &data); err != nil || data.ID == "" {
+		diags.AddError("Missing Lease", "The lease to renew or release was not passed by Terraform.")
+	}
+	return data, diags
+}
+
+// leaseRenewAt asks Terraform to renew the lease halfway through its ttl, so a
+// slow run renews it well before it expires.
+func leaseRenewAt(lease storage.Lease, ttl time.Duration) time.Time {
+	return lease.ExpiresAt.Add(-ttl / 2)
+}
+
+// openLease takes a free block from the pool like an allocation would and holds
+// it for ttl. Expired leases of the pool are dropped.
+func (p *IpamProvider) openLease(ctx context.Context, poolName string, prefixLength int, strategy ipam.Strategy, ttl time.Duration) (storage.Lease, error) {
+	space, err := p.loadPool(ctx, poolName, "")
+	if err != nil {
+		return storage.Lease{}, err
+	}
+
+	candidate, strategy, err := space.allocate(prefixLength, strategy)
+	if err != nil {
+		return storage.Lease{}, err
+	}
+
+	if err := p.reclaimExpired(ctx, space); err != nil {
+		return storage.Lease{}, err
+	}
+
+	id, err := newLeaseID()
+	if err != nil {
+		return storage.Lease{}, err
+	}
+
+	now := time.Now()
+	lease := storage.Lease{
+		ID:        id,
+		CIDR:      candidate.String(),
+		ExpiresAt: now.Add(ttl),
+	}
+
+	pool := space.pool
+	pool.Leases = append(unexpiredLeases(pool.Leases, now), lease)
+	if strategy == ipam.Sequential {
+		pool.Cursor = candidate.String()
+	}
+	if err := p.storage.SavePool(ctx, pool); err != nil {
+		return storage.Lease{}, fmt.Errorf("failed to save lease: %w", err)
+	}
+
+	return lease, nil
+}
+
+// renewLease holds the lease for another ttl. A lease that already expired
+// can't be renewed, since its CIDR may have been handed out again.
+func (p *IpamProvider) renewLease(ctx context.Context, poolName string, id string, ttl time.Duration) (storage.Lease, error) {
+	pool, err := p.storage.GetPool(ctx, poolName)
+	if err != nil {
+		return storage.Lease{}, fmt.Errorf("pool %s not found: %w", poolName, err)
+	}
+
+	now := time.Now()
+	i := slices.IndexFunc(pool.Leases, func(lease storage.Lease) bool { return lease.ID == id })
+	if i < 0 || !pool.Leases[i].ExpiresAt.After(now) {
+		return storage.Lease{}, errors.New("the lease expired or was released")
+	}
+
+	pool.Leases[i].ExpiresAt = now.Add(ttl)
+	lease := pool.Leases[i]
+	pool.Leases = unexpiredLeases(pool.Leases, now)
+	if err := p.storage.SavePool(ctx, pool); err != nil {
+		return storage.Lease{}, fmt.Errorf("failed to save lease: %w", err)
+	}
+
+	return lease, nil
+}
+
+// closeLease releases the lease. A lease that is already gone, or whose pool
+// was deleted, needs no release.
+func (p *IpamProvider) closeLease(ctx context.Context, poolName string, id string) error {
+	pool, err := p.storage.GetPool(ctx, poolName)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read pool %s: %w", poolName, err)
+	}
+
+	i := slices.IndexFunc(pool.Leases, func(lease storage.Lease) bool { return lease.ID == id })
+	if i < 0 {
+		return nil
+	}
+
+	pool.Leases = unexpiredLeases(slices.Delete(pool.Leases, i, i+1), time.Now())
+	if err := p.storage.SavePool(ctx, pool); err != nil {
+		return fmt.Errorf("failed to save pool: %w", err)
+	}
+	return nil
+}
+
+func unexpiredLeases(leases []storage.Lease, now time.Time) []storage.Lease {
+	kept := []storage.Lease{}
+	for _, lease := range leases {
+		if lease.ExpiresAt.After(now) {
+			kept = append(kept, lease)
+		}
+	}
+	return kept
+}
+
+func newLeaseID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lease ID: %w", err)
+	}
+	return "lease-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,216 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/echoprovider"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestAccLeaseEphemeralResource_Basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() { testAccPreCheck(t) },
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+		ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+			"tfipam": providerserver.NewProtocol6WithError(New("test")()),
+			"echo":   echoprovider.NewProviderServer(),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLeaseEphemeralResourceConfig("lease-pool"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("echo.test", tfjsonpath.New("data").AtMapKey("allocated_cidr"), knownvalue.StringExact("10.50.0.0/24")),
+					statecheck.ExpectKnownValue("echo.test", tfjsonpath.New("data").AtMapKey("pool_name"), knownvalue.StringExact("lease-pool")),
+				},
+			},
+		},
+	})
+}
+
+// TestLeaseEphemeralResource_Server opens and closes a lease through the
+// provider server, so the provider's Configure has to hand the storage to the
+// ephemeral resource.
+func TestLeaseEphemeralResource_Server(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "ipam.json")
+	fs, err := storage.NewFileStorage(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.SavePool(ctx, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/24"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	providerType := schemas.Provider.ValueType().(tftypes.Object)
+	providerValues := nullValues(providerType)
+	fileType := providerType.AttributeTypes["file"].(tftypes.Object)
+	providerValues["file"] = tftypes.NewValue(fileType, map[string]tftypes.Value{"path": tftypes.NewValue(tftypes.String, filePath)})
+	configureResp, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{
+		Config: testDynamicValue(t, providerType, providerValues),
+	})
+	if err != nil || len(configureResp.Diagnostics) != 0 {
+		t.Fatalf("failed to configure the provider: %v %v", err, configureResp.Diagnostics)
+	}
+
+	leaseType := schemas.EphemeralResourceSchemas["tfipam_lease"].ValueType().(tftypes.Object)
+	leaseValues := nullValues(leaseType)
+	leaseValues["pool_name"] = tftypes.NewValue(tftypes.String, "pool")
+	leaseValues["prefix_length"] = tftypes.NewValue(tftypes.Number, 26)
+	openResp, err := server.OpenEphemeralResource(ctx, &tfprotov6.OpenEphemeralResourceRequest{
+		TypeName: "tfipam_lease",
+		Config:   testDynamicValue(t, leaseType, leaseValues),
+	})
+	if err != nil || len(openResp.Diagnostics) != 0 {
+		t.Fatalf("failed to open the lease: %v %v", err, openResp.Diagnostics)
+	}
+	// the file backend keeps the document in memory, read what the provider wrote
+	leases := func() []storage.Lease {
+		fs, err := storage.NewFileStorage(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool, err := fs.GetPool(ctx, "pool")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pool.Leases
+	}
+	if got := leases(); len(got) != 1 || got[0].CIDR != "10.0.0.0/26" {
+		t.Fatalf("expected a lease of 10.0.0.0/26 to be stored, got %v", got)
+	}
+
+	closeResp, err := server.CloseEphemeralResource(ctx, &tfprotov6.CloseEphemeralResourceRequest{
+		TypeName: "tfipam_lease",
+		Private:  openResp.Private,
+	})
+	if err != nil || len(closeResp.Diagnostics) != 0 {
+		t.Fatalf("failed to close the lease: %v %v", err, closeResp.Diagnostics)
+	}
+	if got := leases(); len(got) != 0 {
+		t.Fatalf("expected the lease to be released, got %v", got)
+	}
+}
+
+// nullValues returns a null value for every attribute of the object type.
+func nullValues(objectType tftypes.Object) map[string]tftypes.Value {
+	values := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attrType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attrType, nil)
+	}
+	return values
+}
+
+func testDynamicValue(t *testing.T, objectType tftypes.Object, values map[string]tftypes.Value) *tfprotov6.DynamicValue {
+	t.Helper()
+	value, err := tfprotov6.NewDynamicValue(objectType, tftypes.NewValue(objectType, values))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &value
+}
+
+func TestLeases(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/24"}})
+
+	a, err := p.openLease(ctx, "pool", 26, "", time.Hour)
+	if err != nil || a.CIDR != "10.0.0.0/26" {
+		t.Fatalf("expected 10.0.0.0/26, got %s (%v)", a.CIDR, err)
+	}
+	b, err := p.openLease(ctx, "pool", 26, "", time.Hour)
+	if err != nil || b.CIDR != "10.0.0.64/26" || b.ID == a.ID {
+		t.Fatalf("expected a second lease of 10.0.0.64/26, got %s %s (%v)", b.ID, b.CIDR, err)
+	}
+
+	// allocations skip leased CIDRs
+	space, _ := p.loadPool(ctx, "pool", "alloc")
+	if got, _, _ := space.allocate(26, ""); got.String() != "10.0.0.128/26" {
+		t.Fatalf("expected the allocation to skip the leases, got %s", got)
+	}
+
+	renewed, err := p.renewLease(ctx, "pool", a.ID, 2*time.Hour)
+	if err != nil || !renewed.ExpiresAt.After(a.ExpiresAt) {
+		t.Fatalf("expected the renewed lease to expire later, got %s (%v)", renewed.ExpiresAt, err)
+	}
+
+	// closing frees the CIDR, and closing again is a no-op
+	if err := p.closeLease(ctx, "pool", a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.closeLease(ctx, "pool", a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.renewLease(ctx, "pool", a.ID, time.Hour); err == nil {
+		t.Fatal("expected renewing a released lease to fail")
+	}
+	space, _ = p.loadPool(ctx, "pool", "alloc")
+	if got, _, _ := space.allocate(26, ""); got.String() != "10.0.0.0/26" {
+		t.Fatalf("expected the released CIDR to be free, got %s", got)
+	}
+
+	// an expired lease of a crashed run is free again and can't be renewed
+	pool, _ := p.storage.GetPool(ctx, "pool")
+	pool.Leases[0].ExpiresAt = time.Now().Add(-time.Minute)
+	if err := p.storage.SavePool(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.renewLease(ctx, "pool", b.ID, time.Hour); err == nil {
+		t.Fatal("expected renewing an expired lease to fail")
+	}
+	c, err := p.openLease(ctx, "pool", 25, "", time.Hour)
+	if err != nil || c.CIDR != "10.0.0.0/25" {
+		t.Fatalf("expected the expired lease to be reclaimed, got %s (%v)", c.CIDR, err)
+	}
+	if pool, _ := p.storage.GetPool(ctx, "pool"); len(pool.Leases) != 1 || pool.Leases[0].ID != c.ID {
+		t.Fatalf("expected only the new lease to be stored, got %v", pool.Leases)
+	}
+
+	if err := p.closeLease(ctx, "missing", c.ID); err != nil {
+		t.Fatalf("expected closing a lease of a deleted pool to succeed, got %v", err)
+	}
+}
+
+func testAccLeaseEphemeralResourceConfig(poolName string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name  = %[1]q
+  cidrs = ["10.50.0.0/16"]
+}
+
+ephemeral "tfipam_lease" "test" {
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+  ttl           = "10m"
+}
+
+provider "echo" {
+  data = ephemeral.tfipam_lease.test
+}
+
+resource "echo" "test" {}
+`, poolName)
+}
@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+// listFilter selects the pools or allocations returned by the list data sources.
+// Unset fields match everything.
+type listFilter struct {
+	nameRegex     *regexp.Regexp
+	within        netip.Prefix
+	prefixLength  int
+	addressFamily string
+	tags          map[string]string
+}
+
+// newListFilter builds a filter from the data source configuration. nameAttribute
+// is the attribute holding the regular expression, which differs between the data sources.
+func newListFilter(ctx context.Context, nameAttribute string, nameRegex, cidr types.String, prefixLength types.Int64, addressFamily types.String, tags types.Map) (listFilter, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	f := listFilter{
+		prefixLength:  -1,
+		addressFamily: addressFamily.ValueString(),
+	}
+
+	if nameRegex.ValueString() != "" {
+		re, err := regexp.Compile(nameRegex.ValueString())
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root(nameAttribute),
+				"Invalid Regular Expression",
+				fmt.Sprintf("'%s' is not a valid regular expression: %s", nameRegex.ValueString(), err),
+			)
+		}
+		f.nameRegex = re
+	}
+
+	if cidr.ValueString() != "" {
+		prefix, err := netip.ParsePrefix(cidr.ValueString())
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("cidr"),
+				"Invalid CIDR",
+				fmt.Sprintf("CIDR '%s' is not valid: %s", cidr.ValueString(), err),
+			)
+		}
+		f.within = prefix.Masked()
+	}
+
+	if !prefixLength.IsNull() && !prefixLength.IsUnknown() {
+		f.prefixLength = int(prefixLength.ValueInt64())
+	}
+
+	diags.Append(tags.ElementsAs(ctx, &f.tags, true)...)
+	return f, diags
+}
+
+// matchName reports whether the name matches the regular expression.
+func (f listFilter) matchName(name string) bool {
+	return f.nameRegex == nil || f.nameRegex.MatchString(name)
+}
+
+// matchMetadata reports whether the metadata has all of the tags.
+func (f listFilter) matchMetadata(metadata storage.Metadata) bool {
+	return metadata.HasTags(f.tags)
+}
+
+// matchCIDR reports whether the CIDR lies within the filter CIDR and has the
+// prefix length and address family.
+func (f listFilter) matchCIDR(cidr string) bool {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return false
+	}
+
+	if f.within.IsValid() && (f.within.Bits() > prefix.Bits() || !f.within.Contains(prefix.Addr())) {
+		return false
+	}
+	if f.prefixLength >= 0 && prefix.Bits() != f.prefixLength {
+		return false
+	}
+	if f.addressFamily != "" && f.addressFamily != ipam.AddressFamily(prefix.Addr()) {
+		return false
+	}
+	return true
+}
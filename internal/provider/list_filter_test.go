@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestListFilter(t *testing.T) {
+	ctx := context.Background()
+	tags, _ := types.MapValueFrom(ctx, types.StringType, map[string]string{"env": "prod"})
+
+	tests := []struct {
+		name          string
+		cidr          types.String
+		prefixLength  types.Int64
+		addressFamily types.String
+		tags          types.Map
+		matches       map[string]bool
+		metadata      storage.Metadata
+		wantMetadata  bool
+	}{
+		{
+			name:         "no filters",
+			cidr:         types.StringNull(),
+			prefixLength: types.Int64Null(),
+			tags:         types.MapNull(types.StringType),
+			matches: map[string]bool{
+				"10.0.0.0/24": true,
+				"fd00::/64":   true,
+				"invalid":     false,
+			},
+			wantMetadata: true,
+		},
+		{
+			name:         "within cidr",
+			cidr:         types.StringValue("10.0.0.0/16"),
+			prefixLength: types.Int64Null(),
+			tags:         types.MapNull(types.StringType),
+			matches: map[string]bool{
+				"10.0.4.0/24": true,
+				"10.0.0.0/16": true,
+				"10.0.0.0/8":  false,
+				"10.1.0.0/24": false,
+				"fd00::/64":   false,
+			},
+			wantMetadata: true,
+		},
+		{
+			name:          "prefix length and family",
+			cidr:          types.StringNull(),
+			prefixLength:  types.Int64Value(64),
+			addressFamily: types.StringValue("ipv6"),
+			tags:          types.MapNull(types.StringType),
+			matches: map[string]bool{
+				"fd00::/64":    true,
+				"fd00::/56":    false,
+				"10.0.0.0/24":  false,
+				"10.0.0.64/26": false,
+			},
+			wantMetadata: true,
+		},
+		{
+			name:         "tags",
+			cidr:         types.StringNull(),
+			prefixLength: types.Int64Null(),
+			tags:         tags,
+			matches: map[string]bool{
+				"10.0.0.0/24": true,
+			},
+			metadata:     storage.Metadata{Tags: map[string]string{"env": "dev"}},
+			wantMetadata: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, diags := newListFilter(ctx, "name_regex", types.StringValue("^a"), tt.cidr, tt.prefixLength, tt.addressFamily, tt.tags)
+			if diags.HasError() {
+				t.Fatalf("newListFilter() diagnostics: %v", diags)
+			}
+			for cidr, want := range tt.matches {
+				if got := f.matchCIDR(cidr); got != want {
+					t.Errorf("matchCIDR(%s) = %v, want %v", cidr, got, want)
+				}
+			}
+			if got := f.matchMetadata(tt.metadata); got != tt.wantMetadata {
+				t.Errorf("matchMetadata() = %v, want %v", got, tt.wantMetadata)
+			}
+			if !f.matchName("alpha") || f.matchName("beta") {
+				t.Errorf("matchName() doesn't apply the regular expression")
+			}
+		})
+	}
+}
+
+func TestListFilter_Invalid(t *testing.T) {
+	ctx := context.Background()
+
+	_, diags := newListFilter(ctx, "id_regex", types.StringValue("("), types.StringValue("10.0.0.0/33"), types.Int64Null(), types.StringNull(), types.MapNull(types.StringType))
+	if diags.ErrorsCount() != 2 {
+		t.Fatalf("newListFilter() errors = %d, want 2: %v", diags.ErrorsCount(), diags)
+	}
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ datasource.DataSource = &LookupDataSource{}
+
+func NewLookupDataSource() datasource.DataSource {
+	return &LookupDataSource{}
+}
+
+type LookupDataSource struct {
+	provider *IpamProvider
+}
+
+type LookupDataSourceModel struct {
+	Query                    types.String                     `tfsdk:"query"`
+	CIDR                     types.String                     `tfsdk:"cidr"`
+	Status                   types.String                     `tfsdk:"status"`
+	PoolNames                []string                         `tfsdk:"pool_names"`
+	Allocation               *AllocationsDataSourceEntryModel `tfsdk:"allocation"`
+	AddressID                types.String                     `tfsdk:"address_id"`
+	OverlappingAllocationIDs []string                         `tfsdk:"overlapping_allocation_ids"`
+}
+
+func (d *LookupDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_lookup"
+}
+
+func (d *LookupDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Lookup data source for finding the pools and allocation an IP address or CIDR belongs to",
+
+		Attributes: map[string]schema.Attribute{
+			"query": schema.StringAttribute{
+				MarkdownDescription: "IP address or CIDR to look up",
+				Required:            true,
+			},
+			"cidr": schema.StringAttribute{
+				MarkdownDescription: "The looked up CIDR, a /32 or /128 for an IP address, with host bits cleared",
+				Computed:            true,
+			},
+			"status": schema.StringAttribute{
+				MarkdownDescription: "`allocated` if an allocation covers the CIDR, `reserved` if a reserved range of a pool covers it, `partial` if it overlaps allocations or reserved ranges without lying within one, `free` if it lies within a pool and overlaps neither, and `unmanaged` if no pool contains it",
+				Computed:            true,
+			},
+			"pool_names": schema.ListAttribute{
+				MarkdownDescription: "Names of the pools with a CIDR containing the CIDR, sorted by name. Nested pools list the parent as well as the child pools",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+			"allocation": schema.SingleNestedAttribute{
+				MarkdownDescription: "The most specific allocation covering the CIDR, null if there is none",
+				Computed:            true,
+				Attributes:          allocationsDataSourceEntryAttributes(),
+			},
+			"address_id": schema.StringAttribute{
+				MarkdownDescription: "ID of the `tfipam_address` holding the looked up IP address, null if there is none",
+				Computed:            true,
+			},
+			"overlapping_allocation_ids": schema.ListAttribute{
+				MarkdownDescription: "IDs of the allocations overlapping a `partial` CIDR, sorted by ID",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+		},
+	}
+}
+
+func (d *LookupDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.provider = provider
+}
+
+func (d *LookupDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data LookupDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	cidr, err := ipam.ParsePrefixOrAddr(data.Query.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("query"),
+			"Invalid Query",
+			err.Error(),
+		)
+		return
+	}
+
+	snap, err := storage.TakeSnapshot(ctx, d.provider.storage)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Look Up CIDR",
+			fmt.Sprintf("Could not read pools and allocations from storage: %s", err),
+		)
+		return
+	}
+	result := storage.Lookup(snap, cidr)
+
+	data.CIDR = types.StringValue(result.CIDR)
+	data.Status = types.StringValue(result.Status)
+
+	data.PoolNames = []string{}
+	for _, pool := range result.Pools {
+		data.PoolNames = append(data.PoolNames, pool.Name)
+	}
+
+	data.Allocation = nil
+	if result.Allocation != nil {
+		entry, diags := newAllocationsDataSourceEntry(ctx, *result.Allocation)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.Allocation = &entry
+	}
+
+	data.AddressID = types.StringNull()
+	if result.Address != nil {
+		data.AddressID = types.StringValue(result.Address.ID)
+	}
+
+	data.OverlappingAllocationIDs = []string{}
+	for _, alloc := range result.Overlapping {
+		data.OverlappingAllocationIDs = append(data.OverlappingAllocationIDs, alloc.ID)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestAccLookupDataSource_Basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLookupDataSourceConfig("lookup-pool", "cidrhost(tfipam_allocation.test.allocated_cidr, 10)"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.tfipam_lookup.test", tfjsonpath.New("status"), knownvalue.StringExact("allocated")),
+					statecheck.ExpectKnownValue("data.tfipam_lookup.test", tfjsonpath.New("cidr"), knownvalue.StringExact("10.40.0.10/32")),
+					statecheck.ExpectKnownValue(
+						"data.tfipam_lookup.test",
+						tfjsonpath.New("pool_names"),
+						knownvalue.ListExact([]knownvalue.Check{knownvalue.StringExact("lookup-pool")}),
+					),
+					statecheck.ExpectKnownValue("data.tfipam_lookup.test", tfjsonpath.New("allocation").AtMapKey("id"), knownvalue.StringExact("lookup-pool-alloc")),
+					statecheck.ExpectKnownValue("data.tfipam_lookup.test", tfjsonpath.New("allocation").AtMapKey("owner"), knownvalue.StringExact("web-team")),
+					statecheck.ExpectKnownValue("data.tfipam_lookup.test", tfjsonpath.New("address_id"), knownvalue.Null()),
+				},
+			},
+			{
+				Config: testAccLookupDataSourceConfig("lookup-pool", `"10.40.200.0/24"`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.tfipam_lookup.test", tfjsonpath.New("status"), knownvalue.StringExact("free")),
+					statecheck.ExpectKnownValue("data.tfipam_lookup.test", tfjsonpath.New("allocation"), knownvalue.Null()),
+				},
+			},
+			{
+				Config: testAccLookupDataSourceConfig("lookup-pool", `"192.0.2.1"`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.tfipam_lookup.test", tfjsonpath.New("status"), knownvalue.StringExact("unmanaged")),
+					statecheck.ExpectKnownValue("data.tfipam_lookup.test", tfjsonpath.New("pool_names"), knownvalue.ListSizeExact(0)),
+				},
+			},
+		},
+	})
+}
+
+func TestAccLookupDataSource_InvalidQuery(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "tfipam_lookup" "test" {
+  query = "10.0.0.300"
+}
+`,
+				ExpectError: regexp.MustCompile("Invalid Query"),
+			},
+		},
+	})
+}
+
+func TestLookup(t *testing.T) {
+	snap := &storage.Snapshot{
+		Pools: []storage.Pool{
+			{Name: "region", CIDRs: []string{"10.0.0.0/8"}},
+			{Name: "vpc", CIDRs: []string{"10.1.0.0/16"}, ParentPool: "region", ReservedCIDRs: []string{"10.1.255.0/24"}},
+		},
+		Allocations: []storage.Allocation{
+			{ID: "subnet", PoolName: "vpc", AllocatedCIDR: "10.1.0.0/24", PrefixLength: 24},
+			{ID: "v6", PoolName: "v6", AllocatedCIDR: "fd00::/64", PrefixLength: 64},
+		},
+		Addresses: []storage.Address{
+			{ID: "web", AllocationID: "subnet", Address: "10.1.0.10"},
+		},
+	}
+
+	tests := []struct {
+		query      string
+		status     string
+		pools      string
+		allocation string
+		address    string
+	}{
+		// string prefixes don't matter, 10.1.0.100 is not in 10.1.0.10
+		{"10.1.0.100", storage.LookupAllocated, "[region vpc]", "subnet", ""},
+		{"10.1.0.10", storage.LookupAllocated, "[region vpc]", "subnet", "web"},
+		{"10.1.0.128/25", storage.LookupAllocated, "[region vpc]", "subnet", ""},
+		{"10.1.255.7", storage.LookupReserved, "[region vpc]", "", ""},
+		{"10.1.0.0/16", storage.LookupPartial, "[region vpc]", "", ""},
+		{"10.2.0.1", storage.LookupFree, "[region]", "", ""},
+		{"11.0.0.1", storage.LookupUnmanaged, "[]", "", ""},
+		{"fd00::1", storage.LookupAllocated, "[]", "v6", ""},
+		{"::ffff:10.1.0.10", storage.LookupAllocated, "[region vpc]", "subnet", "web"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			cidr, err := ipam.ParsePrefixOrAddr(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := storage.Lookup(snap, cidr)
+
+			var pools []string
+			for _, pool := range result.Pools {
+				pools = append(pools, pool.Name)
+			}
+			var allocation, address string
+			if result.Allocation != nil {
+				allocation = result.Allocation.ID
+			}
+			if result.Address != nil {
+				address = result.Address.ID
+			}
+
+			if result.Status != tt.status || fmt.Sprint(pools) != tt.pools || allocation != tt.allocation || address != tt.address {
+				t.Errorf("got status %s, pools %v, allocation %q, address %q", result.Status, pools, allocation, address)
+			}
+		})
+	}
+}
+
+func testAccLookupDataSourceConfig(poolName, query string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name  = %[1]q
+  cidrs = ["10.40.0.0/16"]
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "%[1]s-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+  owner         = "web-team"
+}
+
+data "tfipam_lookup" "test" {
+  query      = %[2]s
+  depends_on = [tfipam_allocation.test]
+}
+`, poolName, query)
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	datasourceschema "github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+// MetadataModel holds the description, owner and tags of a pool or allocation.
+// It is embedded in the pool and allocation resource and data source models.
+type MetadataModel struct {
+	Description types.String `tfsdk:"description"`
+	Owner       types.String `tfsdk:"owner"`
+	Tags        types.Map    `tfsdk:"tags"`
+}
+
+// metadataDescriptions are formatted with the kind of object, "pool" or "allocation".
+var metadataDescriptions = map[string]string{
+	"description": "Description of what the %s is for",
+	"owner":       "Team or person responsible for the %s",
+	"tags":        "Tags of the %s, e.g. to filter lists of them",
+}
+
+// metadataResourceAttributes returns the metadata attributes of a resource. They
+// only describe the object, so they change in place.
+func metadataResourceAttributes(kind string) map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"description": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: fmt.Sprintf(metadataDescriptions["description"], kind),
+		},
+		"owner": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: fmt.Sprintf(metadataDescriptions["owner"], kind),
+		},
+		"tags": schema.MapAttribute{
+			ElementType:         types.StringType,
+			Optional:            true,
+			MarkdownDescription: fmt.Sprintf(metadataDescriptions["tags"], kind),
+		},
+	}
+}
+
+// metadataDataSourceAttributes returns the computed metadata attributes of a data source.
+func metadataDataSourceAttributes(kind string) map[string]datasourceschema.Attribute {
+	return map[string]datasourceschema.Attribute{
+		"description": datasourceschema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: fmt.Sprintf(metadataDescriptions["description"], kind),
+		},
+		"owner": datasourceschema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: fmt.Sprintf(metadataDescriptions["owner"], kind),
+		},
+		"tags": datasourceschema.MapAttribute{
+			ElementType:         types.StringType,
+			Computed:            true,
+			MarkdownDescription: fmt.Sprintf(metadataDescriptions["tags"], kind),
+		},
+	}
+}
+
+// newMetadataModel converts stored metadata, with null for a missing description or owner.
+func newMetadataModel(ctx context.Context, metadata storage.Metadata) (MetadataModel, diag.Diagnostics) {
+	tags, diags := types.MapValueFrom(ctx, types.StringType, metadata.Tags)
+	return MetadataModel{
+		Description: optionalString(metadata.Description),
+		Owner:       optionalString(metadata.Owner),
+		Tags:        tags,
+	}, diags
+}
+
+// sync returns the model updated to the stored metadata. Values that are empty
+// in storage keep the configured null or empty value, so reading doesn't show a diff.
+func (m MetadataModel) sync(ctx context.Context, metadata storage.Metadata) (MetadataModel, diag.Diagnostics) {
+	synced, diags := newMetadataModel(ctx, metadata)
+	if metadata.Description == "" && m.Description.ValueString() == "" {
+		synced.Description = m.Description
+	}
+	if metadata.Owner == "" && m.Owner.ValueString() == "" {
+		synced.Owner = m.Owner
+	}
+	if len(metadata.Tags) == 0 && len(m.Tags.Elements()) == 0 {
+		synced.Tags = m.Tags
+	}
+	return synced, diags
+}
+
+// toStorage converts the model to metadata for storage.
+func (m MetadataModel) toStorage(ctx context.Context) (storage.Metadata, diag.Diagnostics) {
+	var tags map[string]string
+	diags := m.Tags.ElementsAs(ctx, &tags, true)
+	return storage.Metadata{
+		Description: m.Description.ValueString(),
+		Owner:       m.Owner.ValueString(),
+		Tags:        tags,
+	}, diags
+}
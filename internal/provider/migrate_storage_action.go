@@ -0,0 +1,215 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/action"
+	"github.com/hashicorp/terraform-plugin-framework/action/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ action.Action = &MigrateStorageAction{}
+var _ action.ActionWithConfigure = &MigrateStorageAction{}
+
+func NewMigrateStorageAction() action.Action {
+	return &MigrateStorageAction{}
+}
+
+type MigrateStorageAction struct {
+	provider *IpamProvider
+}
+
+type MigrateStorageActionModel struct {
+	Destination StorageBackendModel `tfsdk:"destination"`
+	DryRun      types.Bool          `tfsdk:"dry_run"`
+	Overwrite   types.Bool          `tfsdk:"overwrite"`
+}
+
+// StorageBackendModel describes a storage backend outside of the provider configuration.
+type StorageBackendModel struct {
+	StorageType           types.String `tfsdk:"storage_type"`
+	FilePath              types.String `tfsdk:"file_path"`
+	AzureConnectionString types.String `tfsdk:"azure_connection_string"`
+	AzureContainerName    types.String `tfsdk:"azure_container_name"`
+	AzureBlobName         types.String `tfsdk:"azure_blob_name"`
+	S3Region              types.String `tfsdk:"s3_region"`
+	S3BucketName          types.String `tfsdk:"s3_bucket_name"`
+	S3ObjectKey           types.String `tfsdk:"s3_object_key"`
+	S3AccessKeyID         types.String `tfsdk:"s3_access_key_id"`
+	S3SecretAccessKey     types.String `tfsdk:"s3_secret_access_key"`
+	S3SessionToken        types.String `tfsdk:"s3_session_token"`
+}
+
+func (m StorageBackendModel) storageConfig() *storage.Config {
+	return &storage.Config{
+		Type:                  m.StorageType.ValueString(),
+		FilePath:              m.FilePath.ValueString(),
+		AzureConnectionString: m.AzureConnectionString.ValueString(),
+		AzureContainerName:    m.AzureContainerName.ValueString(),
+		AzureBlobName:         m.AzureBlobName.ValueString(),
+		S3Region:              m.S3Region.ValueString(),
+		S3BucketName:          m.S3BucketName.ValueString(),
+		S3ObjectKey:           m.S3ObjectKey.ValueString(),
+		S3AccessKeyID:         m.S3AccessKeyID.ValueString(),
+		S3SecretAccessKey:     m.S3SecretAccessKey.ValueString(),
+		S3SessionToken:        m.S3SessionToken.ValueString(),
+	}
+}
+
+func (a *MigrateStorageAction) Metadata(ctx context.Context, req action.MetadataRequest, resp *action.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_migrate_storage"
+}
+
+func (a *MigrateStorageAction) Schema(ctx context.Context, req action.SchemaRequest, resp *action.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Copies all pools, allocations, addresses and the audit log from the provider's storage backend to another backend",
+
+		Attributes: map[string]schema.Attribute{
+			"destination": schema.SingleNestedAttribute{
+				Required:            true,
+				MarkdownDescription: "Storage backend to migrate to. Takes the same settings as the provider configuration.",
+				Attributes: map[string]schema.Attribute{
+					"storage_type": schema.StringAttribute{
+						Required:            true,
+						MarkdownDescription: "Storage backend type. Supported values: 'file', 'azure_blob', 'aws_s3'",
+						Validators: []validator.String{
+							stringvalidator.OneOf("file", "azure_blob", "aws_s3"),
+						},
+					},
+					"file_path": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Path to storage file for 'file' storage backend",
+					},
+					// action attributes can't be sensitive, write-only keeps credentials out of plan output
+					"azure_connection_string": schema.StringAttribute{
+						Optional:            true,
+						WriteOnly:           true,
+						MarkdownDescription: "Connection string for Azure Blob Storage",
+					},
+					"azure_container_name": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Container name for Azure Blob Storage",
+					},
+					"azure_blob_name": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Blob name for Azure Blob Storage. Defaults to 'ipam-storage.json'",
+					},
+					"s3_region": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "AWS region for S3 bucket",
+					},
+					"s3_bucket_name": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "S3 bucket name",
+					},
+					"s3_object_key": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "S3 object key (file path). Defaults to 'ipam-storage.json'",
+					},
+					"s3_access_key_id": schema.StringAttribute{
+						Optional:            true,
+						WriteOnly:           true,
+						MarkdownDescription: "AWS Access Key ID. Uses default AWS credential chain if not provided.",
+					},
+					"s3_secret_access_key": schema.StringAttribute{
+						Optional:            true,
+						WriteOnly:           true,
+						MarkdownDescription: "AWS Secret Access Key. Required if s3_access_key_id is provided.",
+					},
+					"s3_session_token": schema.StringAttribute{
+						Optional:            true,
+						WriteOnly:           true,
+						MarkdownDescription: "AWS Session Token for temporary credentials",
+					},
+				},
+			},
+			"dry_run": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Only report the changes the migration would make. Defaults to false.",
+			},
+			"overwrite": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Migrate into a destination that already holds data, replacing pools, allocations and addresses with different content. Defaults to false, which fails the migration when the destination isn't empty.",
+			},
+		},
+	}
+}
+
+func (a *MigrateStorageAction) Configure(ctx context.Context, req action.ConfigureRequest, resp *action.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Action Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	a.provider = provider
+}
+
+func (a *MigrateStorageAction) Invoke(ctx context.Context, req action.InvokeRequest, resp *action.InvokeResponse) {
+	var data MigrateStorageActionModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	dst, err := storage.Factory(ctx, data.Destination.storageConfig())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Storage Initialization Failed",
+			fmt.Sprintf("Failed to initialize destination storage backend: %s", err),
+		)
+		return
+	}
+	defer dst.Close()
+
+	opts := storage.MigrateOptions{
+		DryRun:    data.DryRun.ValueBool(),
+		Overwrite: data.Overwrite.ValueBool(),
+	}
+
+	result, err := storage.Migrate(ctx, a.provider.storage, dst, opts)
+	if result != nil {
+		for _, change := range result.Changes {
+			if change.Action != storage.ChangeUnchanged {
+				resp.SendProgress(action.InvokeProgressEvent{Message: change.String()})
+			}
+		}
+		for _, issue := range result.Issues {
+			resp.Diagnostics.AddError("Storage Validation Failed", issue.String())
+		}
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Storage Migration Failed",
+			fmt.Sprintf("Could not migrate storage: %s", err),
+		)
+		return
+	}
+
+	resp.SendProgress(action.InvokeProgressEvent{
+		Message: fmt.Sprintf("%d created, %d updated, %d unchanged, %d conflicting, %d audit record(s) copied (dry run: %t, verified: %t)",
+			result.Count(storage.ChangeCreate), result.Count(storage.ChangeUpdate),
+			result.Count(storage.ChangeUnchanged), result.Count(storage.ChangeConflict),
+			result.AuditRecords, opts.DryRun, result.Verified),
+	})
+
+	tflog.Trace(ctx, "invoked migrate storage action", map[string]any{
+		"destination": data.Destination.StorageType.ValueString(),
+		"dry_run":     opts.DryRun,
+		"applied":     result.Applied,
+	})
+}
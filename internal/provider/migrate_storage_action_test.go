@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestAccMigrateStorageAction_File(t *testing.T) {
+	destination := filepath.Join(t.TempDir(), "migrated.json")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_14_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccMigrateStorageActionConfig("migrate-pool", destination),
+				Check:  testAccCheckMigratedAllocation(destination, "migrate-alloc"),
+			},
+		},
+	})
+}
+
+// testAccCheckMigratedAllocation verifies the allocation was copied to the destination file.
+func testAccCheckMigratedAllocation(path, allocID string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("destination storage was not written: %w", err)
+		}
+
+		var doc struct {
+			Allocations map[string]storage.Allocation `json:"allocations"`
+		}
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return err
+		}
+		if _, ok := doc.Allocations[allocID]; !ok {
+			return fmt.Errorf("allocation %s missing from destination storage", allocID)
+		}
+		return nil
+	}
+}
+
+// testAccMigrateStorageActionConfig generates a config that migrates to a file once the allocation exists.
+func testAccMigrateStorageActionConfig(poolName, destination string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name  = %[1]q
+  cidrs = ["10.0.0.0/16"]
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "migrate-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 24
+}
+
+action "tfipam_migrate_storage" "test" {
+  config {
+    destination = {
+      storage_type = "file"
+      file_path    = %[2]q
+    }
+  }
+}
+
+resource "terraform_data" "trigger" {
+  input = tfipam_allocation.test.allocated_cidr
+
+  lifecycle {
+    action_trigger {
+      events  = [after_create]
+      actions = [action.tfipam_migrate_storage.test]
+    }
+  }
+}
+`, poolName, destination)
+}
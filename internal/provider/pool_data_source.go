@@ -3,6 +3,8 @@ package provider
 import (
 	"context"
 	"fmt"
+	"maps"
+	"terraform-provider-tfipam/internal/provider/ipam"
 	"terraform-provider-tfipam/internal/provider/storage"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
@@ -21,8 +23,19 @@ type PoolDataSource struct {
 }
 
 type PoolDataSourceModel struct {
-	Name  types.String `tfsdk:"name"`
-	CIDRs types.List   `tfsdk:"cidrs"`
+	Name               types.String `tfsdk:"name"`
+	CIDRs              types.List   `tfsdk:"cidrs"`
+	ReservedCIDRs      types.List   `tfsdk:"reserved_cidrs"`
+	AllocationStrategy types.String `tfsdk:"allocation_strategy"`
+	GatewayOffset      types.Int64  `tfsdk:"gateway_offset"`
+	ReservedOffsets    types.List   `tfsdk:"reserved_offsets"`
+	ReclaimExpired     types.Bool   `tfsdk:"reclaim_expired"`
+	ParentPool         types.String `tfsdk:"parent_pool"`
+	ChildPools         types.List   `tfsdk:"child_pools"`
+	TotalAddresses     types.String `tfsdk:"total_addresses"`
+	AllocatedAddresses types.String `tfsdk:"allocated_addresses"`
+
+	MetadataModel
 }
 
 func (d *PoolDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
@@ -43,8 +56,49 @@ func (d *PoolDataSource) Schema(ctx context.Context, req datasource.SchemaReques
 				Computed:            true,
 				ElementType:         types.StringType,
 			},
+			"reserved_cidrs": schema.ListAttribute{
+				MarkdownDescription: "CIDR blocks within the pool that are never handed out to allocations",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+			"allocation_strategy": schema.StringAttribute{
+				MarkdownDescription: "Default allocation strategy of the pool, null when the pool uses 'first_fit'",
+				Computed:            true,
+			},
+			"gateway_offset": schema.Int64Attribute{
+				MarkdownDescription: "Offset of the gateway address of allocations from the pool, negative offsets count back from the last usable address",
+				Computed:            true,
+			},
+			"reserved_offsets": schema.ListAttribute{
+				MarkdownDescription: "Offsets within every allocation from the pool that are never handed out as host addresses",
+				Computed:            true,
+				ElementType:         types.Int64Type,
+			},
+			"reclaim_expired": schema.BoolAttribute{
+				MarkdownDescription: "Whether the CIDRs of expired allocations are handed out again",
+				Computed:            true,
+			},
+			"parent_pool": schema.StringAttribute{
+				MarkdownDescription: "Name of the pool the CIDR of this pool was allocated from, null for a top-level pool",
+				Computed:            true,
+			},
+			"child_pools": schema.ListAttribute{
+				MarkdownDescription: "Names of the pools allocated from this pool, sorted by name",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+			"total_addresses": schema.StringAttribute{
+				MarkdownDescription: "Number of addresses in the pool CIDRs, as a decimal string since IPv6 pools exceed 64 bits",
+				Computed:            true,
+			},
+			"allocated_addresses": schema.StringAttribute{
+				MarkdownDescription: "Number of addresses allocated from the pool, rolled up from all pools below it, as a decimal string",
+				Computed:            true,
+			},
 		},
 	}
+
+	maps.Copy(resp.Schema.Attributes, metadataDataSourceAttributes("pool"))
 }
 
 func (d *PoolDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
@@ -95,5 +149,58 @@ func (d *PoolDataSource) Read(ctx context.Context, req datasource.ReadRequest, r
 	}
 	data.CIDRs = cidrs
 
+	reserved, diag := types.ListValueFrom(ctx, types.StringType, pool.ReservedCIDRs)
+	resp.Diagnostics.Append(diag...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ReservedCIDRs = reserved
+	data.AllocationStrategy = optionalString(pool.AllocationStrategy)
+	data.ReclaimExpired = types.BoolValue(pool.ReclaimExpired)
+	data.GatewayOffset = types.Int64Value(gatewayOffset(pool))
+
+	offsets, diag := types.ListValueFrom(ctx, types.Int64Type, pool.ReservedOffsets)
+	resp.Diagnostics.Append(diag...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ReservedOffsets = offsets
+
+	metadata, diags := newMetadataModel(ctx, pool.Metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.MetadataModel = metadata
+	data.ParentPool = optionalString(pool.ParentPool)
+
+	// utilization rolls up through the child pools
+	snap, err := storage.TakeSnapshot(ctx, d.provider.storage)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Read Pool",
+			fmt.Sprintf("Could not read child pools from storage: %s", err),
+		)
+		return
+	}
+
+	childNames := []string{}
+	for _, child := range storage.ChildPools(snap.Pools, pool.Name) {
+		childNames = append(childNames, child.Name)
+	}
+	children, diag := types.ListValueFrom(ctx, types.StringType, childNames)
+	resp.Diagnostics.Append(diag...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ChildPools = children
+
+	var allocated []string
+	for _, alloc := range storage.SubtreeAllocations(snap, pool.Name) {
+		allocated = append(allocated, alloc.AllocatedCIDR)
+	}
+	data.TotalAddresses = types.StringValue(ipam.AddressCount(ipam.ParseCIDRs(pool.CIDRs)).String())
+	data.AllocatedAddresses = types.StringValue(ipam.AddressCount(ipam.ParseCIDRs(allocated)).String())
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -3,22 +3,32 @@ package provider
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net"
+	"net/netip"
 	"strings"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
+	"terraform-provider-tfipam/internal/provider/ipam"
 	"terraform-provider-tfipam/internal/provider/storage"
 )
 
 var _ resource.Resource = &PoolResource{}
 var _ resource.ResourceWithImportState = &PoolResource{}
+var _ resource.ResourceWithValidateConfig = &PoolResource{}
+var _ resource.ResourceWithModifyPlan = &PoolResource{}
 
 func NewPoolResource() resource.Resource {
 	return &PoolResource{}
@@ -29,8 +39,19 @@ type PoolResource struct {
 }
 
 type PoolResourceModel struct {
-	Name  types.String `tfsdk:"name"`
-	CIDRs types.List   `tfsdk:"cidrs"`
+	Name               types.String `tfsdk:"name"`
+	CIDRs              types.List   `tfsdk:"cidrs"`
+	ParentPool         types.String `tfsdk:"parent_pool"`
+	PrefixLength       types.Int64  `tfsdk:"prefix_length"`
+	ReservedCIDRs      types.List   `tfsdk:"reserved_cidrs"`
+	AllocationStrategy types.String `tfsdk:"allocation_strategy"`
+	GatewayOffset      types.Int64  `tfsdk:"gateway_offset"`
+	ReservedOffsets    types.List   `tfsdk:"reserved_offsets"`
+	ReclaimExpired     types.Bool   `tfsdk:"reclaim_expired"`
+
+	AllowOrphanedAllocations types.Bool `tfsdk:"allow_orphaned_allocations"`
+
+	MetadataModel
 }
 
 func (r *PoolResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -51,11 +72,60 @@ func (r *PoolResource) Schema(ctx context.Context, req resource.SchemaRequest, r
 			},
 			"cidrs": schema.ListAttribute{
 				ElementType:         types.StringType,
-				Required:            true,
-				MarkdownDescription: "List of CIDR blocks in the pool",
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "List of CIDR blocks in the pool. Computed when the pool is carved out of `parent_pool`.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"parent_pool": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Name of the pool to allocate the CIDR of this pool from, instead of setting `cidrs`. The CIDR is picked with the parent's `allocation_strategy`, and the parent can't be deleted while it has child pools.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"prefix_length": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Prefix length of the CIDR allocated from `parent_pool`. Required with `parent_pool`.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"reserved_cidrs": schema.ListAttribute{
+				ElementType:         types.StringType,
+				Optional:            true,
+				MarkdownDescription: "CIDR blocks within the pool that are never handed out to allocations, e.g. addresses reserved by the cloud provider or ranges kept for future use",
+			},
+			"gateway_offset": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Offset of the gateway address of allocations from the pool, counted from the network address. Negative offsets count back from the last usable address, so `-1` is the last usable address. Defaults to `1`, the first usable address.",
+			},
+			"reserved_offsets": schema.ListAttribute{
+				ElementType:         types.Int64Type,
+				Optional:            true,
+				MarkdownDescription: "Offsets within every allocation from the pool that are never handed out as host addresses by `tfipam_address`, counted like `gateway_offset`. The gateway address is always skipped as well.",
+			},
+			"allow_orphaned_allocations": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Allow changing `cidrs` so that existing allocations are no longer within the pool. Instead of failing the plan, those allocations are kept and marked as orphaned in storage. Defaults to `false`.",
+			},
+			"reclaim_expired": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Hand out the CIDRs of allocations whose `expires_at` has passed again. The expired allocations are removed from storage, with an audit record, once a new allocation, lease or child pool is taken from the pool. Without it expired allocations keep their CIDR and are reported as warnings until they are reclaimed with `tfipam reclaim`. Defaults to `false`.",
+			},
+			"allocation_strategy": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "How allocations pick a free block from the pool. Supported values: 'first_fit' (default, lowest free address searching the CIDRs in order), 'best_fit' (smallest free block that fits), 'last_fit' (highest free address), 'random', 'sequential' (continue after the last allocation and only reuse released blocks once the pool wraps around). Can be overridden per allocation.",
+				Validators: []validator.String{
+					stringvalidator.OneOf(ipam.Strategies()...),
+				},
 			},
 		},
 	}
+
+	maps.Copy(resp.Schema.Attributes, metadataResourceAttributes("pool"))
 }
 
 func (r *PoolResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
@@ -83,35 +153,105 @@ func (r *PoolResource) Create(ctx context.Context, req resource.CreateRequest, r
 		return
 	}
 
-	// validate cidrs
 	var cidrs []string
-	resp.Diagnostics.Append(data.CIDRs.ElementsAs(ctx, &cidrs, false)...)
-	if resp.Diagnostics.HasError() {
-		return
-	}
+	var parent *poolSpace
+	var parentStrategy ipam.Strategy
+	var carved netip.Prefix
+
+	if data.ParentPool.IsNull() {
+		// validate cidrs
+		resp.Diagnostics.Append(data.CIDRs.ElementsAs(ctx, &cidrs, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
 
-	for _, cidr := range cidrs {
-		if _, _, err := net.ParseCIDR(cidr); err != nil {
-			resp.Diagnostics.AddError(
-				"Invalid CIDR",
-				fmt.Sprintf("CIDR '%s' is not valid: %s", cidr, err),
+		for _, cidr := range cidrs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				resp.Diagnostics.AddError(
+					"Invalid CIDR",
+					fmt.Sprintf("CIDR '%s' is not valid: %s", cidr, err),
+				)
+				return
+			}
+		}
+	} else {
+		// carve the pool out of the parent the same way an allocation is
+		var err error
+		parent, err = r.provider.loadPool(ctx, data.ParentPool.ValueString(), "")
+		if err == nil {
+			carved, parentStrategy, err = parent.allocate(int(data.PrefixLength.ValueInt64()), "")
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("parent_pool"),
+				"Allocation Failed",
+				fmt.Sprintf("Unable to allocate CIDR for pool %s from parent pool %s: %s", data.Name.ValueString(), data.ParentPool.ValueString(), err),
 			)
 			return
 		}
+
+		cidrs = []string{carved.String()}
+		cidrsList, diag := types.ListValueFrom(ctx, types.StringType, cidrs)
+		resp.Diagnostics.Append(diag...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.CIDRs = cidrsList
+	}
+
+	var reserved []string
+	resp.Diagnostics.Append(data.ReservedCIDRs.ElementsAs(ctx, &reserved, true)...)
+	resp.Diagnostics.Append(validateReservedCIDRs(cidrs, reserved)...)
+
+	var reservedOffsets []int64
+	resp.Diagnostics.Append(data.ReservedOffsets.ElementsAs(ctx, &reservedOffsets, true)...)
+
+	metadata, diags := data.MetadataModel.toStorage(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
 	// save pool to storage
 	pool := &storage.Pool{
-		Name:  data.Name.ValueString(),
-		CIDRs: cidrs,
+		Name:               data.Name.ValueString(),
+		CIDRs:              cidrs,
+		ParentPool:         data.ParentPool.ValueString(),
+		ReservedCIDRs:      reserved,
+		GatewayOffset:      data.GatewayOffset.ValueInt64Pointer(),
+		ReservedOffsets:    reservedOffsets,
+		Metadata:           metadata,
+		AllocationStrategy: data.AllocationStrategy.ValueString(),
+		ReclaimExpired:     data.ReclaimExpired.ValueBool(),
 	}
 
-	if err := r.provider.storage.SavePool(ctx, pool); err != nil {
-		resp.Diagnostics.AddError(
-			"Failed to Save Pool",
-			fmt.Sprintf("Could not save pool to storage: %s", err),
-		)
-		return
+	if parent == nil {
+		if err := r.provider.storage.SavePool(ctx, pool); err != nil {
+			resp.Diagnostics.AddError(
+				"Failed to Save Pool",
+				fmt.Sprintf("Could not save pool to storage: %s", err),
+			)
+			return
+		}
+	} else {
+		// the pool is saved in the same write that removes the expired
+		// allocations of the parent and moves its cursor
+		batch := &storage.Batch{SavePools: []storage.Pool{*pool}}
+		reclaimed, err := r.provider.reclaimBatch(ctx, parent, batch)
+		if err == nil {
+			if parentStrategy == ipam.Sequential {
+				batch.SetCursor(parent.pool.Name, carved.String())
+			}
+			err = r.provider.storage.Apply(ctx, batch)
+		}
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Failed to Save Pool",
+				fmt.Sprintf("Could not save pool to storage: %s", err),
+			)
+			return
+		}
+		logReclaimed(ctx, reclaimed)
 	}
 
 	tflog.Trace(ctx, "created pool resource", map[string]interface{}{
@@ -150,6 +290,38 @@ func (r *PoolResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		return
 	}
 	data.CIDRs = cidrs
+	data.ParentPool = optionalString(pool.ParentPool)
+	data.PrefixLength = childPrefixLength(pool)
+	data.AllocationStrategy = optionalString(pool.AllocationStrategy)
+	data.GatewayOffset = types.Int64PointerValue(pool.GatewayOffset)
+	if pool.ReclaimExpired || !data.ReclaimExpired.IsNull() {
+		data.ReclaimExpired = types.BoolValue(pool.ReclaimExpired)
+	}
+
+	if len(pool.ReservedCIDRs) > 0 || !data.ReservedCIDRs.IsNull() {
+		reserved, diag := types.ListValueFrom(ctx, types.StringType, pool.ReservedCIDRs)
+		resp.Diagnostics.Append(diag...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.ReservedCIDRs = reserved
+	}
+
+	if len(pool.ReservedOffsets) > 0 || !data.ReservedOffsets.IsNull() {
+		offsets, diag := types.ListValueFrom(ctx, types.Int64Type, pool.ReservedOffsets)
+		resp.Diagnostics.Append(diag...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.ReservedOffsets = offsets
+	}
+
+	metadata, diags := data.MetadataModel.sync(ctx, pool.Metadata)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.MetadataModel = metadata
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -179,12 +351,54 @@ func (r *PoolResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		}
 	}
 
-	// TODO: Check for allocations that would be invalidated by CIDR changes to the pool
+	var reserved []string
+	resp.Diagnostics.Append(data.ReservedCIDRs.ElementsAs(ctx, &reserved, true)...)
+	resp.Diagnostics.Append(validateReservedCIDRs(cidrs, reserved)...)
+
+	var reservedOffsets []int64
+	resp.Diagnostics.Append(data.ReservedOffsets.ElementsAs(ctx, &reservedOffsets, true)...)
+
+	metadata, diags := data.MetadataModel.toStorage(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// check again at apply time, allocations may have been made since the plan
+	allocations, outside, diags := r.checkAllocations(ctx, data.Name.ValueString(), cidrs, data.AllowOrphanedAllocations.ValueBool())
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// existing allocations stay where they are, new reservations only apply to future allocations
+	for _, alloc := range allocations {
+		if cidr := overlappingCIDR(alloc.AllocatedCIDR, reserved); cidr != "" {
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root("reserved_cidrs"),
+				"Reserved CIDR Overlaps Allocation",
+				fmt.Sprintf("Reserved CIDR %s overlaps allocation %s (%s). The allocation is kept, but the range won't be handed out again once it is released.", cidr, alloc.ID, alloc.AllocatedCIDR),
+			)
+		}
+	}
 
 	// Update pool in storage
 	pool := &storage.Pool{
-		Name:  data.Name.ValueString(),
-		CIDRs: cidrs,
+		Name:               data.Name.ValueString(),
+		CIDRs:              cidrs,
+		ParentPool:         data.ParentPool.ValueString(),
+		ReservedCIDRs:      reserved,
+		GatewayOffset:      data.GatewayOffset.ValueInt64Pointer(),
+		ReservedOffsets:    reservedOffsets,
+		Metadata:           metadata,
+		AllocationStrategy: data.AllocationStrategy.ValueString(),
+		ReclaimExpired:     data.ReclaimExpired.ValueBool(),
+	}
+
+	// keep the position of the sequential strategy and CIDRs held for leases
+	if existing, err := r.provider.storage.GetPool(ctx, pool.Name); err == nil {
+		pool.Cursor = existing.Cursor
+		pool.Leases = existing.Leases
 	}
 
 	if err := r.provider.storage.SavePool(ctx, pool); err != nil {
@@ -195,6 +409,26 @@ func (r *PoolResource) Update(ctx context.Context, req resource.UpdateRequest, r
 		return
 	}
 
+	// mark allocations outside of the new CIDRs as orphaned, and clear the mark
+	// from allocations the pool contains again
+	orphaned := make(map[string]bool, len(outside))
+	for _, alloc := range outside {
+		orphaned[alloc.ID] = true
+	}
+	for _, alloc := range allocations {
+		if alloc.Orphaned == orphaned[alloc.ID] {
+			continue
+		}
+		alloc.Orphaned = orphaned[alloc.ID]
+		if err := r.provider.storage.SaveAllocation(ctx, &alloc); err != nil {
+			resp.Diagnostics.AddError(
+				"Failed to Update Allocation",
+				fmt.Sprintf("Could not mark allocation %s as orphaned: %s", alloc.ID, err),
+			)
+			return
+		}
+	}
+
 	tflog.Trace(ctx, "updated pool resource", map[string]interface{}{
 		"name": data.Name.ValueString(),
 	})
@@ -212,6 +446,28 @@ func (r *PoolResource) Delete(ctx context.Context, req resource.DeleteRequest, r
 
 	poolName := data.Name.ValueString()
 
+	// child pools were allocated from this pool and would be left without a parent
+	pools, err := r.provider.storage.ListPools(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Check Child Pools",
+			fmt.Sprintf("Could not check for child pools: %s", err),
+		)
+		return
+	}
+
+	if children := storage.ChildPools(pools, poolName); len(children) > 0 {
+		names := make([]string, 0, len(children))
+		for _, child := range children {
+			names = append(names, child.Name)
+		}
+		resp.Diagnostics.AddError(
+			"Cannot Delete Pool",
+			fmt.Sprintf("Pool %s has %d child pools (%s). Please delete all child pools before deleting the pool.", poolName, len(children), strings.Join(names, ", ")),
+		)
+		return
+	}
+
 	// check for active allocations in storage
 	allocations, err := r.provider.storage.ListAllocationsByPool(ctx, poolName)
 	if err != nil {
@@ -277,6 +533,19 @@ func (r *PoolResource) ImportState(ctx context.Context, req resource.ImportState
 		CIDRs: cidrs,
 	}
 
+	// keep settings of a pool that already exists in storage
+	if existing, err := r.provider.storage.GetPool(ctx, name); err == nil {
+		pool.ParentPool = existing.ParentPool
+		pool.ReservedCIDRs = existing.ReservedCIDRs
+		pool.AllocationStrategy = existing.AllocationStrategy
+		pool.GatewayOffset = existing.GatewayOffset
+		pool.ReservedOffsets = existing.ReservedOffsets
+		pool.ReclaimExpired = existing.ReclaimExpired
+		pool.Metadata = existing.Metadata
+		pool.Cursor = existing.Cursor
+		pool.Leases = existing.Leases
+	}
+
 	if err := r.provider.storage.SavePool(ctx, pool); err != nil {
 		resp.Diagnostics.AddError(
 			"Failed to Import Pool",
@@ -292,4 +561,287 @@ func (r *PoolResource) ImportState(ctx context.Context, req resource.ImportState
 		return
 	}
 	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("cidrs"), cidrsList)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("allocation_strategy"), optionalString(pool.AllocationStrategy))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("gateway_offset"), types.Int64PointerValue(pool.GatewayOffset))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("parent_pool"), optionalString(pool.ParentPool))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("prefix_length"), childPrefixLength(pool))...)
+	if pool.ReclaimExpired {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("reclaim_expired"), true)...)
+	}
+	if len(pool.ReservedCIDRs) > 0 {
+		reservedList, diag := types.ListValueFrom(ctx, types.StringType, pool.ReservedCIDRs)
+		resp.Diagnostics.Append(diag...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("reserved_cidrs"), reservedList)...)
+	}
+	if len(pool.ReservedOffsets) > 0 {
+		offsetsList, diag := types.ListValueFrom(ctx, types.Int64Type, pool.ReservedOffsets)
+		resp.Diagnostics.Append(diag...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("reserved_offsets"), offsetsList)...)
+	}
+
+	metadata, diags := newMetadataModel(ctx, pool.Metadata)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("description"), metadata.Description)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("owner"), metadata.Owner)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("tags"), metadata.Tags)...)
+}
+
+func (r *PoolResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// only updates can move the pool away from its allocations
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() || r.provider == nil {
+		return
+	}
+
+	var data PoolResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Name.IsUnknown() || data.CIDRs.IsUnknown() || data.AllowOrphanedAllocations.IsUnknown() {
+		return
+	}
+
+	var cidrs []string
+	resp.Diagnostics.Append(data.CIDRs.ElementsAs(ctx, &cidrs, true)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, _, diags := r.checkAllocations(ctx, data.Name.ValueString(), cidrs, data.AllowOrphanedAllocations.ValueBool())
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *PoolResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data PoolResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.ParentPool.IsNull() {
+		if data.CIDRs.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("cidrs"),
+				"Missing Pool CIDRs",
+				"Either cidrs or parent_pool and prefix_length must be set.",
+			)
+		}
+		if !data.PrefixLength.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("prefix_length"),
+				"Missing Parent Pool",
+				"prefix_length can only be set together with parent_pool.",
+			)
+		}
+
+		// child pools only get their CIDRs at apply time, top-level pools can be checked now
+		cidrs, cidrsKnown := knownStrings(ctx, data.CIDRs)
+		reserved, reservedKnown := knownStrings(ctx, data.ReservedCIDRs)
+		if cidrsKnown && reservedKnown && len(cidrs) > 0 {
+			resp.Diagnostics.Append(validateReservedCIDRs(cidrs, reserved)...)
+		}
+		return
+	}
+
+	if !data.CIDRs.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("cidrs"),
+			"Conflicting Pool CIDRs",
+			"cidrs can't be set together with parent_pool, the CIDR of a child pool is allocated from the parent.",
+		)
+	}
+
+	if data.PrefixLength.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("prefix_length"),
+			"Missing Prefix Length",
+			"prefix_length must be set together with parent_pool.",
+		)
+	} else if !data.PrefixLength.IsUnknown() && (data.PrefixLength.ValueInt64() < 0 || data.PrefixLength.ValueInt64() > 128) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("prefix_length"),
+			"Invalid Prefix Length",
+			fmt.Sprintf("Prefix length must be between 0 and 128, got %d", data.PrefixLength.ValueInt64()),
+		)
+	}
+
+	if !data.ParentPool.IsUnknown() && !data.Name.IsUnknown() && data.ParentPool.ValueString() == data.Name.ValueString() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("parent_pool"),
+			"Invalid Parent Pool",
+			fmt.Sprintf("Pool %s can't be its own parent.", data.Name.ValueString()),
+		)
+	}
+}
+
+// checkAllocations returns the allocations of the pool and those of them that
+// are not within the CIDRs. Allocations that were not orphaned before are an
+// error unless allowOrphaned is set, and child pools outside the CIDRs are
+// always an error.
+func (r *PoolResource) checkAllocations(ctx context.Context, poolName string, cidrs []string, allowOrphaned bool) ([]storage.Allocation, []storage.Allocation, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	allocations, err := r.provider.storage.ListAllocationsByPool(ctx, poolName)
+	if err != nil {
+		diags.AddError(
+			"Failed to Check Allocations",
+			fmt.Sprintf("Could not check for allocations: %s", err),
+		)
+		return nil, nil, diags
+	}
+
+	pools, err := r.provider.storage.ListPools(ctx)
+	if err != nil {
+		diags.AddError(
+			"Failed to Check Child Pools",
+			fmt.Sprintf("Could not check for child pools: %s", err),
+		)
+		return nil, nil, diags
+	}
+
+	prefixes := ipam.ParseCIDRs(cidrs)
+
+	var outside []storage.Allocation
+	var newlyOutside []string
+	for _, alloc := range allocations {
+		if cidrWithin(alloc.AllocatedCIDR, prefixes) {
+			continue
+		}
+		outside = append(outside, alloc)
+		if !alloc.Orphaned {
+			newlyOutside = append(newlyOutside, fmt.Sprintf("%s (%s)", alloc.ID, alloc.AllocatedCIDR))
+		}
+	}
+
+	if len(newlyOutside) > 0 {
+		if allowOrphaned {
+			diags.AddAttributeWarning(
+				path.Root("cidrs"),
+				"Allocations Will Be Orphaned",
+				fmt.Sprintf("The new CIDRs of pool %s no longer contain %d allocations, which will be marked as orphaned: %s",
+					poolName, len(newlyOutside), strings.Join(newlyOutside, ", ")),
+			)
+		} else {
+			diags.AddAttributeError(
+				path.Root("cidrs"),
+				"Allocations Outside Pool",
+				fmt.Sprintf("The new CIDRs of pool %s no longer contain %d allocations: %s. Delete or move the allocations first, or set allow_orphaned_allocations to keep them as orphaned allocations.",
+					poolName, len(newlyOutside), strings.Join(newlyOutside, ", ")),
+			)
+		}
+	}
+
+	var childrenOutside []string
+	for _, child := range storage.ChildPools(pools, poolName) {
+		for _, cidr := range child.CIDRs {
+			if !cidrWithin(cidr, prefixes) {
+				childrenOutside = append(childrenOutside, fmt.Sprintf("%s (%s)", child.Name, cidr))
+			}
+		}
+	}
+	if len(childrenOutside) > 0 {
+		diags.AddAttributeError(
+			path.Root("cidrs"),
+			"Child Pools Outside Pool",
+			fmt.Sprintf("The new CIDRs of pool %s no longer contain child pools: %s. Delete the child pools first.",
+				poolName, strings.Join(childrenOutside, ", ")),
+		)
+	}
+
+	return allocations, outside, diags
+}
+
+// cidrWithin reports whether the CIDR is fully contained in one of the prefixes.
+func cidrWithin(cidr string, prefixes []netip.Prefix) bool {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return false
+	}
+	for _, p := range prefixes {
+		if p.Bits() <= prefix.Bits() && p.Contains(prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// childPrefixLength returns the prefix length a child pool was allocated with, or null for a top-level pool.
+func childPrefixLength(pool *storage.Pool) types.Int64 {
+	if pool.ParentPool == "" || len(pool.CIDRs) == 0 {
+		return types.Int64Null()
+	}
+	prefix, err := netip.ParsePrefix(pool.CIDRs[0])
+	if err != nil {
+		return types.Int64Null()
+	}
+	return types.Int64Value(int64(prefix.Bits()))
+}
+
+// knownStrings returns the elements of a list of strings, or false if the list
+// or any of its elements is unknown.
+func knownStrings(ctx context.Context, list types.List) ([]string, bool) {
+	if list.IsUnknown() {
+		return nil, false
+	}
+	for _, element := range list.Elements() {
+		if element.IsUnknown() {
+			return nil, false
+		}
+	}
+
+	var values []string
+	if diags := list.ElementsAs(ctx, &values, true); diags.HasError() {
+		return nil, false
+	}
+	return values, true
+}
+
+// validateReservedCIDRs checks that every reserved CIDR is valid and lies within one of the pool CIDRs.
+func validateReservedCIDRs(cidrs, reserved []string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	poolPrefixes := ipam.ParseCIDRs(cidrs)
+	for _, cidr := range reserved {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("reserved_cidrs"),
+				"Invalid Reserved CIDR",
+				fmt.Sprintf("CIDR '%s' is not valid: %s", cidr, err),
+			)
+			continue
+		}
+
+		if !cidrWithin(prefix.String(), poolPrefixes) {
+			diags.AddAttributeError(
+				path.Root("reserved_cidrs"),
+				"Reserved CIDR Outside Pool",
+				fmt.Sprintf("Reserved CIDR '%s' is not within any of the pool CIDRs %s", cidr, strings.Join(cidrs, ", ")),
+			)
+		}
+	}
+
+	return diags
+}
+
+// overlappingCIDR returns the first of the CIDRs that overlaps cidr, or an empty string.
+func overlappingCIDR(cidr string, cidrs []string) string {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return ""
+	}
+	for _, other := range cidrs {
+		if otherPrefix, err := netip.ParsePrefix(other); err == nil && otherPrefix.Overlaps(prefix) {
+			return other
+		}
+	}
+	return ""
 }
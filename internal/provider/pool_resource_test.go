@@ -1,16 +1,24 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
 	"github.com/hashicorp/terraform-plugin-testing/statecheck"
 	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+
+	"terraform-provider-tfipam/internal/provider/storage"
 )
 
 // testAccProtoV6ProviderFactories are used to instantiate a provider during
@@ -166,6 +174,183 @@ func TestAccPoolResource_UpdateCIDRs(t *testing.T) {
 	})
 }
 
+func TestAccPoolResource_AllocationStrategy(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccPoolResourceConfigStrategy("strategy-update-pool", "best_fit"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"tfipam_pool.test",
+						tfjsonpath.New("allocation_strategy"),
+						knownvalue.StringExact("best_fit"),
+					),
+				},
+			},
+			// Update in place
+			{
+				Config: testAccPoolResourceConfigStrategy("strategy-update-pool", "sequential"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"tfipam_pool.test",
+						tfjsonpath.New("allocation_strategy"),
+						knownvalue.StringExact("sequential"),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccPoolResource_ReservedCIDRs(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccPoolResourceConfigReserved("reserved-pool", []string{"10.0.0.0/26", "10.0.0.64/28"}),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"tfipam_pool.test",
+						tfjsonpath.New("reserved_cidrs"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("10.0.0.0/26"),
+							knownvalue.StringExact("10.0.0.64/28"),
+						}),
+					),
+					// the first free /28 after the reserved ranges
+					statecheck.ExpectKnownValue(
+						"tfipam_allocation.test",
+						tfjsonpath.New("allocated_cidr"),
+						knownvalue.StringExact("10.0.0.80/28"),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccPoolResource_ReservedCIDROutsidePool(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccPoolResourceConfigReserved("reserved-outside-pool", []string{"10.1.0.0/26"}),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("Reserved CIDR Outside Pool"),
+			},
+		},
+	})
+}
+
+func TestAccPoolResource_ParentPool(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccPoolResourceConfigHierarchy("hierarchy"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"tfipam_pool.vpc",
+						tfjsonpath.New("cidrs"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("10.0.0.0/20"),
+						}),
+					),
+					// the subnet comes from the VPC, the region allocation skips the VPC
+					statecheck.ExpectKnownValue(
+						"tfipam_allocation.subnet",
+						tfjsonpath.New("allocated_cidr"),
+						knownvalue.StringExact("10.0.0.0/24"),
+					),
+					statecheck.ExpectKnownValue(
+						"tfipam_allocation.region",
+						tfjsonpath.New("allocated_cidr"),
+						knownvalue.StringExact("10.0.16.0/24"),
+					),
+					statecheck.ExpectKnownValue(
+						"data.tfipam_pool.region",
+						tfjsonpath.New("child_pools"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("hierarchy-vpc"),
+						}),
+					),
+					statecheck.ExpectKnownValue(
+						"data.tfipam_pool.region",
+						tfjsonpath.New("allocated_addresses"),
+						knownvalue.StringExact("512"),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccPoolResource_ParentPoolWithCIDRs(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "tfipam_pool" "test" {
+  name          = "conflicting-child"
+  cidrs         = ["10.0.0.0/24"]
+  parent_pool   = "some-parent"
+  prefix_length = 24
+}
+`,
+				ExpectError: regexp.MustCompile("Conflicting Pool CIDRs"),
+			},
+		},
+	})
+}
+
+func TestAccPoolResource_Metadata(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccPoolResourceConfigMetadata("metadata-pool", "Production VPCs", "prod"),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_pool.test", tfjsonpath.New("description"), knownvalue.StringExact("Production VPCs")),
+					statecheck.ExpectKnownValue("tfipam_pool.test", tfjsonpath.New("owner"), knownvalue.StringExact("network-team")),
+					statecheck.ExpectKnownValue("tfipam_pool.test", tfjsonpath.New("tags"), knownvalue.MapExact(map[string]knownvalue.Check{
+						"env": knownvalue.StringExact("prod"),
+					})),
+				},
+			},
+			// metadata changes in place
+			{
+				Config: testAccPoolResourceConfigMetadata("metadata-pool", "Staging VPCs", "staging"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("tfipam_pool.test", plancheck.ResourceActionUpdate),
+					},
+				},
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("tfipam_pool.test", tfjsonpath.New("description"), knownvalue.StringExact("Staging VPCs")),
+					statecheck.ExpectKnownValue("tfipam_pool.test", tfjsonpath.New("tags"), knownvalue.MapExact(map[string]knownvalue.Check{
+						"env": knownvalue.StringExact("staging"),
+					})),
+				},
+			},
+			{
+				ResourceName:                         "tfipam_pool.test",
+				ImportState:                          true,
+				ImportStateVerify:                    true,
+				ImportStateId:                        "metadata-pool:10.0.0.0/16",
+				ImportStateVerifyIdentifierAttribute: "name",
+			},
+		},
+	})
+}
+
 func TestAccPoolResource_InvalidCIDR(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
@@ -305,6 +490,33 @@ func TestAccPoolResource_WithAllocations(t *testing.T) {
 	})
 }
 
+func TestAccPoolResource_RemoveCIDRWithAllocations(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccPoolResourceConfigOrphans("orphan-pool", []string{"10.0.0.0/24", "10.1.0.0/24"}, false),
+			},
+			// the allocation holds 10.0.0.0/28, so the plan fails
+			{
+				Config:      testAccPoolResourceConfigOrphans("orphan-pool", []string{"10.1.0.0/24"}, false),
+				ExpectError: regexp.MustCompile(`(?s)Allocations Outside Pool.*orphan-pool-alloc \(10\.0\.0\.0/28\)`),
+			},
+			{
+				Config: testAccPoolResourceConfigOrphans("orphan-pool", []string{"10.1.0.0/24"}, true),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.tfipam_allocation.test",
+						tfjsonpath.New("orphaned"),
+						knownvalue.Bool(true),
+					),
+				},
+			},
+		},
+	})
+}
+
 func TestAccPoolResource_IPv6(t *testing.T) {
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
@@ -400,3 +612,204 @@ resource "tfipam_allocation" "test" {
 }
 `, name, cidrsConfig)
 }
+
+// testAccPoolResourceConfigStrategy generates a pool with an allocation strategy.
+func testAccPoolResourceConfigStrategy(name, strategy string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name                = %[1]q
+  cidrs               = ["10.0.0.0/24"]
+  allocation_strategy = %[2]q
+}
+`, name, strategy)
+}
+
+func testAccPoolResourceConfigReserved(name string, reserved []string) string {
+	reservedConfig := ""
+	for _, cidr := range reserved {
+		reservedConfig += fmt.Sprintf("    %q,\n", cidr)
+	}
+
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name  = %[1]q
+  cidrs = ["10.0.0.0/24"]
+  reserved_cidrs = [
+%[2]s  ]
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "reserved-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 28
+}
+`, name, reservedConfig)
+}
+
+func testAccPoolResourceConfigHierarchy(prefix string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "region" {
+  name  = "%[1]s-region"
+  cidrs = ["10.0.0.0/16"]
+}
+
+resource "tfipam_pool" "vpc" {
+  name          = "%[1]s-vpc"
+  parent_pool   = tfipam_pool.region.name
+  prefix_length = 20
+}
+
+resource "tfipam_allocation" "subnet" {
+  id            = "%[1]s-subnet"
+  pool_name     = tfipam_pool.vpc.name
+  prefix_length = 24
+}
+
+resource "tfipam_allocation" "region" {
+  id            = "%[1]s-region-alloc"
+  pool_name     = tfipam_pool.region.name
+  prefix_length = 24
+
+  depends_on = [tfipam_pool.vpc]
+}
+
+data "tfipam_pool" "region" {
+  name = tfipam_pool.region.name
+
+  depends_on = [tfipam_allocation.subnet, tfipam_allocation.region]
+}
+`, prefix)
+}
+
+func testAccPoolResourceConfigOrphans(name string, cidrs []string, allowOrphaned bool) string {
+	cidrsConfig := ""
+	for _, cidr := range cidrs {
+		cidrsConfig += fmt.Sprintf("    %q,\n", cidr)
+	}
+
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name = %[1]q
+  cidrs = [
+%[2]s  ]
+  allow_orphaned_allocations = %[3]t
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "%[1]s-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = 28
+}
+
+data "tfipam_allocation" "test" {
+  id        = tfipam_allocation.test.id
+  pool_name = tfipam_pool.test.name
+
+  depends_on = [tfipam_pool.test]
+}
+`, name, cidrsConfig, allowOrphaned)
+}
+
+func testAccPoolResourceConfigMetadata(name, description, env string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name        = %[1]q
+  cidrs       = ["10.0.0.0/16"]
+  description = %[2]q
+  owner       = "network-team"
+  tags = {
+    env = %[3]q
+  }
+}
+`, name, description, env)
+}
+
+func TestPoolResource_ValidateReservedCIDRs(t *testing.T) {
+	ctx := context.Background()
+	r := &PoolResource{}
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	list := func(values ...any) tftypes.Value {
+		elements := make([]tftypes.Value, len(values))
+		for i, v := range values {
+			elements[i] = tftypes.NewValue(tftypes.String, v)
+		}
+		return tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, elements)
+	}
+
+	tests := []struct {
+		name     string
+		cidrs    tftypes.Value
+		reserved tftypes.Value
+		want     string
+	}{
+		{"within pool", list("10.0.0.0/24"), list("10.0.0.0/26"), ""},
+		{"outside pool", list("10.0.0.0/24"), list("10.1.0.0/26"), "Reserved CIDR Outside Pool"},
+		{"invalid", list("10.0.0.0/24"), list("10.0.0.0/33"), "Invalid Reserved CIDR"},
+		{"unknown reservation", list("10.0.0.0/24"), list(tftypes.UnknownValue), ""},
+		{"unknown CIDRs", tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, tftypes.UnknownValue), list("10.1.0.0/26"), ""},
+	}
+	for _, tt := range tests {
+		values := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+		for name, attrType := range objectType.AttributeTypes {
+			values[name] = tftypes.NewValue(attrType, nil)
+		}
+		values["name"] = tftypes.NewValue(tftypes.String, "pool")
+		values["cidrs"] = tt.cidrs
+		values["reserved_cidrs"] = tt.reserved
+
+		config := tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)}
+		var resp fwresource.ValidateConfigResponse
+		r.ValidateConfig(ctx, fwresource.ValidateConfigRequest{Config: config}, &resp)
+
+		switch {
+		case tt.want == "" && resp.Diagnostics.HasError():
+			t.Errorf("%s: expected no errors, got %v", tt.name, resp.Diagnostics)
+		case tt.want != "" && (len(resp.Diagnostics) != 1 || resp.Diagnostics[0].Summary() != tt.want):
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, resp.Diagnostics)
+		}
+	}
+}
+
+func TestPoolResource_CreateChildReclaimsExpired(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "parent", CIDRs: []string{"10.0.0.0/24"}, AllocationStrategy: "sequential", ReclaimExpired: true})
+	past := time.Now().Add(-time.Hour)
+	if err := p.storage.SaveAllocation(ctx, &storage.Allocation{ID: "expired", PoolName: "parent", AllocatedCIDR: "10.0.0.0/25", PrefixLength: 25, ExpiresAt: &past}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &PoolResource{provider: p}
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+
+	values := nullValues(objectType)
+	values["name"] = tftypes.NewValue(tftypes.String, "child")
+	values["parent_pool"] = tftypes.NewValue(tftypes.String, "parent")
+	values["prefix_length"] = tftypes.NewValue(tftypes.Number, 25)
+	values["cidrs"] = tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, tftypes.UnknownValue)
+	req := fwresource.CreateRequest{Plan: tfsdk.Plan{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)}}
+	resp := fwresource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, nil)}}
+	r.Create(ctx, req, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatal(resp.Diagnostics)
+	}
+
+	// the child takes the CIDR of the expired allocation, which is removed in the same write
+	child, err := p.storage.GetPool(ctx, "child")
+	if err != nil || len(child.CIDRs) != 1 || child.CIDRs[0] != "10.0.0.0/25" {
+		t.Fatalf("expected the child pool at 10.0.0.0/25, got %v (%v)", child, err)
+	}
+	if _, err := p.storage.GetAllocation(ctx, "expired"); err != storage.ErrNotFound {
+		t.Fatalf("expected the expired allocation to be removed, got %v", err)
+	}
+	if parent, _ := p.storage.GetPool(ctx, "parent"); parent.Cursor != "10.0.0.0/25" {
+		t.Errorf("expected the parent cursor to move to 10.0.0.0/25, got %q", parent.Cursor)
+	}
+	if records, _ := p.storage.ListAuditRecords(ctx); len(records) != 1 || records[0].AllocationID != "expired" {
+		t.Errorf("expected an audit record of the reclaimed allocation, got %v", records)
+	}
+}
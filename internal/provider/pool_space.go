@@ -0,0 +1,170 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+// poolSpace is a pool read from storage together with everything that takes
+// space in it, and the free-list built from that.
+type poolSpace struct {
+	pool        *storage.Pool
+	allocations []storage.Allocation
+	children    []storage.Pool
+	allocator   *ipam.Allocator
+
+	// expired are the expired allocations of a pool that reclaims them, which
+	// are left out of allocations and the free-list
+	expired []storage.Allocation
+}
+
+// plannedCIDR is the CIDR shown in the plan of a new allocation.
+type plannedCIDR struct {
+	poolName string
+	cidr     netip.Prefix
+}
+
+// loadPool reads the pool, its allocations and its child pools and builds the
+// pool's free-list. Reserved ranges, the CIDRs of child pools, unexpired leases
+// and the CIDRs planned by this provider for allocations other than allocationID
+// count as occupied.
+// Expired allocations only count when the pool doesn't reclaim them.
+//
+// The free-list is rebuilt on every call rather than cached, so every allocation,
+// lease, preview and utilization read pays for building it, which grows with
+// the number of occupied blocks (see ipam.NewAllocator).
+func (p *IpamProvider) loadPool(ctx context.Context, poolName string, allocationID string) (*poolSpace, error) {
+	return p.loadPoolReleasing(ctx, poolName, allocationID, nil)
+}
+
+// loadPoolReleasing is loadPool for callers that remove the released
+// allocations in the same storage write as they allocate, so their CIDRs are
+// free already.
+func (p *IpamProvider) loadPoolReleasing(ctx context.Context, poolName string, allocationID string, released []string) (*poolSpace, error) {
+	space, err := storage.LoadPoolSpace(ctx, p.storage, poolName, released)
+	if err != nil {
+		return nil, err
+	}
+
+	return &poolSpace{
+		pool:        space.Pool,
+		allocations: space.Allocations,
+		children:    space.Children,
+		allocator:   space.Allocator(p.plannedCIDRs(poolName, allocationID)...),
+		expired:     space.Expired,
+	}, nil
+}
+
+// reclaimExpired removes the expired allocations left out of the pool space, so
+// a CIDR taken from the space isn't also held by an expired allocation.
+func (p *IpamProvider) reclaimExpired(ctx context.Context, s *poolSpace) error {
+	if len(s.expired) == 0 {
+		return nil
+	}
+
+	reclaimed, err := storage.ReclaimExpired(ctx, p.storage, storage.ReclaimOptions{PoolName: s.pool.Name})
+	if err != nil {
+		return fmt.Errorf("failed to reclaim expired allocations: %w", err)
+	}
+
+	logReclaimed(ctx, reclaimed)
+	return nil
+}
+
+// reclaimBatch adds the removal of the expired allocations left out of the pool
+// space to the batch, for callers that allocate from the space in the same
+// write. It returns the allocations that are removed once the batch is applied.
+func (p *IpamProvider) reclaimBatch(ctx context.Context, s *poolSpace, batch *storage.Batch) ([]storage.Allocation, error) {
+	if len(s.expired) == 0 {
+		return nil, nil
+	}
+
+	reclaimed, reclaim, err := storage.ReclaimBatch(ctx, p.storage, storage.ReclaimOptions{PoolName: s.pool.Name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reclaim expired allocations: %w", err)
+	}
+	batch.Add(reclaim)
+	return reclaimed, nil
+}
+
+// logReclaimed logs the expired allocations that were removed from storage.
+func logReclaimed(ctx context.Context, reclaimed []storage.Allocation) {
+	for _, alloc := range reclaimed {
+		tflog.Info(ctx, "reclaimed expired allocation", map[string]any{
+			"id":             alloc.ID,
+			"pool_name":      alloc.PoolName,
+			"allocated_cidr": alloc.AllocatedCIDR,
+		})
+	}
+}
+
+// allocate takes a free block of the prefix length chosen by the strategy,
+// falling back to the pool's strategy and then to first fit. The returned
+// strategy is the one that was used.
+func (s *poolSpace) allocate(prefixLength int, strategy ipam.Strategy) (netip.Prefix, ipam.Strategy, error) {
+	if strategy == "" {
+		strategy = ipam.Strategy(s.pool.AllocationStrategy)
+	}
+
+	candidate, ok := s.allocator.AllocateWith(prefixLength, strategy)
+	if !ok {
+		return netip.Prefix{}, strategy, fmt.Errorf("no available CIDR blocks of size /%d in pool %s", prefixLength, s.pool.Name)
+	}
+	return candidate, strategy, nil
+}
+
+// plannedCIDRs returns the CIDRs planned by this provider for new allocations
+// from the pool, except the one of allocationID.
+func (p *IpamProvider) plannedCIDRs(poolName string, allocationID string) []string {
+	p.plannedMu.Lock()
+	defer p.plannedMu.Unlock()
+
+	var cidrs []string
+	for id, planned := range p.planned {
+		if id != allocationID && planned.poolName == poolName {
+			cidrs = append(cidrs, planned.cidr.String())
+		}
+	}
+	return cidrs
+}
+
+// setPlanned remembers the CIDR shown in the plan of a new allocation, so
+// allocations planned after it get other CIDRs. Nothing is written to storage.
+func (p *IpamProvider) setPlanned(allocationID string, poolName string, cidr netip.Prefix) {
+	p.plannedMu.Lock()
+	defer p.plannedMu.Unlock()
+
+	if p.planned == nil {
+		p.planned = make(map[string]plannedCIDR)
+	}
+	p.planned[allocationID] = plannedCIDR{poolName: poolName, cidr: cidr}
+}
+
+// forgetPlanned drops the planned CIDR of an allocation once it was created,
+// or failed to be.
+func (p *IpamProvider) forgetPlanned(allocationID string) {
+	p.plannedMu.Lock()
+	defer p.plannedMu.Unlock()
+
+	delete(p.planned, allocationID)
+}
+
+// recordAllocation moves the sequential cursor of the pool to the CIDR that was
+// allocated from it. The pool is only saved for the sequential strategy.
+func (p *IpamProvider) recordAllocation(ctx context.Context, pool *storage.Pool, strategy ipam.Strategy, allocated netip.Prefix) error {
+	if strategy != ipam.Sequential {
+		return nil
+	}
+
+	pool.Cursor = allocated.String()
+	if err := p.storage.SavePool(ctx, pool); err != nil {
+		return fmt.Errorf("failed to save pool: %w", err)
+	}
+	return nil
+}
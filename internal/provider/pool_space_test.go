@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func testPoolSpaceProvider(t *testing.T, pool *storage.Pool) *IpamProvider {
+	t.Helper()
+
+	s, err := storage.NewFileStorage(filepath.Join(t.TempDir(), "ipam.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SavePool(context.Background(), pool); err != nil {
+		t.Fatal(err)
+	}
+	return &IpamProvider{storage: s}
+}
+
+func TestPlannedAllocations(t *testing.T) {
+	ctx := context.Background()
+	p := testPoolSpaceProvider(t, &storage.Pool{Name: "pool", CIDRs: []string{"10.0.0.0/24"}})
+	before, err := storage.TakeSnapshot(ctx, p.storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// plan a, then b must not get the same CIDR
+	space, err := p.loadPool(ctx, "pool", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	planned, _, err := space.allocate(26, "")
+	if err != nil || planned.String() != "10.0.0.0/26" {
+		t.Fatalf("expected 10.0.0.0/26, got %s (%v)", planned, err)
+	}
+	p.setPlanned("a", "pool", planned)
+
+	space, _ = p.loadPool(ctx, "pool", "b")
+	if got, _, _ := space.allocate(26, ""); got.String() != "10.0.0.64/26" {
+		t.Fatalf("expected b to skip the CIDR planned for a, got %s", got)
+	}
+
+	// the CIDR is still free for a itself, and other pools are not affected
+	space, _ = p.loadPool(ctx, "pool", "a")
+	if !space.allocator.Claim(planned) {
+		t.Fatalf("expected %s to be free for a", planned)
+	}
+	if cidrs := p.plannedCIDRs("other", ""); len(cidrs) != 0 {
+		t.Fatalf("expected no planned CIDRs in another pool, got %v", cidrs)
+	}
+
+	// planning doesn't write to storage
+	after, err := storage.TakeSnapshot(ctx, p.storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Fatalf("expected planning not to change storage, got %+v", after)
+	}
+
+	p.forgetPlanned("a")
+	space, _ = p.loadPool(ctx, "pool", "b")
+	if got, _, _ := space.allocate(26, ""); got.String() != "10.0.0.0/26" {
+		t.Fatalf("expected the CIDR to be free once a was created, got %s", got)
+	}
+}
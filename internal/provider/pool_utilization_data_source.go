@@ -0,0 +1,217 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ datasource.DataSource = &PoolUtilizationDataSource{}
+
+func NewPoolUtilizationDataSource() datasource.DataSource {
+	return &PoolUtilizationDataSource{}
+}
+
+type PoolUtilizationDataSource struct {
+	provider *IpamProvider
+}
+
+type PoolUtilizationDataSourceModel struct {
+	Name               types.String               `tfsdk:"name"`
+	TotalAddresses     types.String               `tfsdk:"total_addresses"`
+	AllocatedAddresses types.String               `tfsdk:"allocated_addresses"`
+	ReservedAddresses  types.String               `tfsdk:"reserved_addresses"`
+	FreeAddresses      types.String               `tfsdk:"free_addresses"`
+	UtilizationPercent types.Float64              `tfsdk:"utilization_percent"`
+	CIDRs              []PoolUtilizationCIDRModel `tfsdk:"cidrs"`
+}
+
+type PoolUtilizationCIDRModel struct {
+	CIDR                    types.String     `tfsdk:"cidr"`
+	TotalAddresses          types.String     `tfsdk:"total_addresses"`
+	FreeAddresses           types.String     `tfsdk:"free_addresses"`
+	LargestFreeCIDR         types.String     `tfsdk:"largest_free_cidr"`
+	LargestFreePrefixLength types.Int64      `tfsdk:"largest_free_prefix_length"`
+	FreeBlocks              map[string]int64 `tfsdk:"free_blocks"`
+}
+
+func (d *PoolUtilizationDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_pool_utilization"
+}
+
+func (d *PoolUtilizationDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "IPAM pool utilization data source for checking how full a pool is and how large a block can still be allocated from it",
+
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Name of the IP pool",
+				Required:            true,
+			},
+			"total_addresses": schema.StringAttribute{
+				MarkdownDescription: "Number of addresses in the pool CIDRs, as a decimal string since IPv6 pools exceed 64 bits",
+				Computed:            true,
+			},
+			"allocated_addresses": schema.StringAttribute{
+				MarkdownDescription: "Number of addresses allocated from the pool, rolled up from all pools below it, as a decimal string",
+				Computed:            true,
+			},
+			"reserved_addresses": schema.StringAttribute{
+				MarkdownDescription: "Number of addresses in the pool's `reserved_cidrs`, as a decimal string",
+				Computed:            true,
+			},
+			"free_addresses": schema.StringAttribute{
+				MarkdownDescription: "Number of addresses that are neither allocated, reserved nor part of a child pool, as a decimal string",
+				Computed:            true,
+			},
+			"utilization_percent": schema.Float64Attribute{
+				MarkdownDescription: "Percentage of the pool's addresses that are not free",
+				Computed:            true,
+			},
+			"cidrs": schema.ListNestedAttribute{
+				MarkdownDescription: "Free space of each pool CIDR, in the order of the pool's `cidrs`",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"cidr": schema.StringAttribute{
+							MarkdownDescription: "The pool CIDR",
+							Computed:            true,
+						},
+						"total_addresses": schema.StringAttribute{
+							MarkdownDescription: "Number of addresses in the CIDR, not counting space shared with an earlier pool CIDR, as a decimal string",
+							Computed:            true,
+						},
+						"free_addresses": schema.StringAttribute{
+							MarkdownDescription: "Number of free addresses in the CIDR, as a decimal string",
+							Computed:            true,
+						},
+						"largest_free_cidr": schema.StringAttribute{
+							MarkdownDescription: "The largest free block of the CIDR, the lowest one if several are equally large, null if the CIDR is full",
+							Computed:            true,
+						},
+						"largest_free_prefix_length": schema.Int64Attribute{
+							MarkdownDescription: "Prefix length of the largest block that can still be allocated from the CIDR, null if the CIDR is full",
+							Computed:            true,
+						},
+						"free_blocks": schema.MapAttribute{
+							MarkdownDescription: "Number of free blocks keyed by prefix length. Free space is counted in the largest aligned blocks, so a free /23 counts as one /23, not as two /24s",
+							Computed:            true,
+							ElementType:         types.Int64Type,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *PoolUtilizationDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.provider = provider
+}
+
+func (d *PoolUtilizationDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data PoolUtilizationDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// free space is what the allocator would hand out, so leases and allocations planned in the same run count as taken
+	space, err := d.provider.loadPool(ctx, data.Name.ValueString(), "")
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Read Pool Utilization",
+			fmt.Sprintf("Could not read pool %s from storage: %s", data.Name.ValueString(), err),
+		)
+		return
+	}
+
+	// allocated addresses roll up through the child pools, like on the pool data source
+	snap, err := storage.TakeSnapshot(ctx, d.provider.storage)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to Read Pool Utilization",
+			fmt.Sprintf("Could not read child pools from storage: %s", err),
+		)
+		return
+	}
+	var allocated []string
+	for _, alloc := range storage.SubtreeAllocations(snap, space.pool.Name) {
+		allocated = append(allocated, alloc.AllocatedCIDR)
+	}
+
+	poolCIDRs := ipam.ParseCIDRs(space.pool.CIDRs)
+	total := ipam.AddressCount(poolCIDRs)
+	free := new(big.Int)
+
+	data.CIDRs = []PoolUtilizationCIDRModel{}
+	counted := new(big.Int)
+	for i, cidr := range space.allocator.FreeSpace() {
+		// space shared with an earlier pool CIDR is counted there
+		upTo := ipam.AddressCount(poolCIDRs[:i+1])
+		owned := new(big.Int).Sub(upTo, counted)
+		counted = upTo
+		free.Add(free, cidr.Free)
+
+		blocks := make(map[string]int64, len(cidr.Blocks))
+		for bits, count := range cidr.Blocks {
+			blocks[strconv.Itoa(bits)] = int64(count)
+		}
+
+		entry := PoolUtilizationCIDRModel{
+			CIDR:                    types.StringValue(cidr.CIDR.String()),
+			TotalAddresses:          types.StringValue(owned.String()),
+			FreeAddresses:           types.StringValue(cidr.Free.String()),
+			LargestFreeCIDR:         types.StringNull(),
+			LargestFreePrefixLength: types.Int64Null(),
+			FreeBlocks:              blocks,
+		}
+		if cidr.Largest.IsValid() {
+			entry.LargestFreeCIDR = types.StringValue(cidr.Largest.String())
+			entry.LargestFreePrefixLength = types.Int64Value(int64(cidr.Largest.Bits()))
+		}
+		data.CIDRs = append(data.CIDRs, entry)
+	}
+
+	data.TotalAddresses = types.StringValue(total.String())
+	data.AllocatedAddresses = types.StringValue(ipam.AddressCount(ipam.ParseCIDRs(allocated)).String())
+	data.ReservedAddresses = types.StringValue(ipam.AddressCount(ipam.ParseCIDRs(space.pool.ReservedCIDRs)).String())
+	data.FreeAddresses = types.StringValue(free.String())
+	data.UtilizationPercent = types.Float64Value(utilizationPercent(total, free))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// utilizationPercent returns the percentage of the total that is not free. The
+// division is done on big floats since IPv6 counts don't fit in a float64 exactly.
+func utilizationPercent(total, free *big.Int) float64 {
+	if total.Sign() == 0 {
+		return 0
+	}
+	used := new(big.Float).SetInt(new(big.Int).Sub(total, free))
+	used.Mul(used, big.NewFloat(100))
+	percent, _ := used.Quo(used, new(big.Float).SetInt(total)).Float64()
+	return percent
+}
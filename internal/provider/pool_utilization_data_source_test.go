@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+)
+
+func TestAccPoolUtilizationDataSource_Basic(t *testing.T) {
+	cidr := tfjsonpath.New("cidrs").AtSliceIndex(0)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// 10.0.0.0/28 is reserved and the allocation takes 10.0.0.64/26
+				Config: testAccPoolUtilizationDataSourceConfig("util-pool", "10.0.0.0/24", 26),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("data.tfipam_pool_utilization.test", tfjsonpath.New("total_addresses"), knownvalue.StringExact("256")),
+					statecheck.ExpectKnownValue("data.tfipam_pool_utilization.test", tfjsonpath.New("allocated_addresses"), knownvalue.StringExact("64")),
+					statecheck.ExpectKnownValue("data.tfipam_pool_utilization.test", tfjsonpath.New("reserved_addresses"), knownvalue.StringExact("16")),
+					statecheck.ExpectKnownValue("data.tfipam_pool_utilization.test", tfjsonpath.New("free_addresses"), knownvalue.StringExact("176")),
+					statecheck.ExpectKnownValue("data.tfipam_pool_utilization.test", tfjsonpath.New("utilization_percent"), knownvalue.Float64Exact(31.25)),
+					statecheck.ExpectKnownValue("data.tfipam_pool_utilization.test", cidr.AtMapKey("largest_free_cidr"), knownvalue.StringExact("10.0.0.128/25")),
+					statecheck.ExpectKnownValue("data.tfipam_pool_utilization.test", cidr.AtMapKey("largest_free_prefix_length"), knownvalue.Int64Exact(25)),
+					statecheck.ExpectKnownValue(
+						"data.tfipam_pool_utilization.test",
+						cidr.AtMapKey("free_blocks"),
+						knownvalue.MapExact(map[string]knownvalue.Check{
+							"25": knownvalue.Int64Exact(1),
+							"27": knownvalue.Int64Exact(1),
+							"28": knownvalue.Int64Exact(1),
+						}),
+					),
+				},
+			},
+		},
+	})
+}
+
+func TestAccPoolUtilizationDataSource_IPv6(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccPoolUtilizationDataSourceConfig("util-pool-v6", "fd00::/48", 64),
+				ConfigStateChecks: []statecheck.StateCheck{
+					// 2^80 addresses don't fit in 64 bits
+					statecheck.ExpectKnownValue("data.tfipam_pool_utilization.test", tfjsonpath.New("total_addresses"), knownvalue.StringExact("1208925819614629174706176")),
+					statecheck.ExpectKnownValue("data.tfipam_pool_utilization.test", tfjsonpath.New("allocated_addresses"), knownvalue.StringExact("18446744073709551616")),
+					statecheck.ExpectKnownValue("data.tfipam_pool_utilization.test", tfjsonpath.New("cidrs").AtSliceIndex(0).AtMapKey("largest_free_prefix_length"), knownvalue.Int64Exact(49)),
+				},
+			},
+		},
+	})
+}
+
+func TestUtilizationPercent(t *testing.T) {
+	total, _ := new(big.Int).SetString("1208925819614629174706176", 10)
+	half := new(big.Int).Rsh(total, 1)
+
+	tests := []struct {
+		total, free *big.Int
+		want        float64
+	}{
+		{big.NewInt(256), big.NewInt(256), 0},
+		{big.NewInt(256), big.NewInt(176), 31.25},
+		{big.NewInt(256), big.NewInt(0), 100},
+		{total, half, 50},
+		{big.NewInt(0), big.NewInt(0), 0},
+	}
+	for _, tt := range tests {
+		if got := utilizationPercent(tt.total, tt.free); got != tt.want {
+			t.Errorf("utilizationPercent(%s, %s) = %v, want %v", tt.total, tt.free, got, tt.want)
+		}
+	}
+}
+
+// testAccPoolUtilizationDataSourceConfig generates a pool with one allocation and
+// the utilization data source reading it. IPv4 pools reserve their first /28.
+func testAccPoolUtilizationDataSourceConfig(poolName, cidr string, prefixLength int) string {
+	reserved := "[]"
+	if prefixLength <= 32 {
+		reserved = `["10.0.0.0/28"]`
+	}
+	return fmt.Sprintf(`
+resource "tfipam_pool" "test" {
+  name           = %[1]q
+  cidrs          = [%[2]q]
+  reserved_cidrs = %[4]s
+}
+
+resource "tfipam_allocation" "test" {
+  id            = "%[1]s-alloc"
+  pool_name     = tfipam_pool.test.name
+  prefix_length = %[3]d
+}
+
+data "tfipam_pool_utilization" "test" {
+  name       = tfipam_pool.test.name
+  depends_on = [tfipam_allocation.test]
+}
+`, poolName, cidr, prefixLength, reserved)
+}
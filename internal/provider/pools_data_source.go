@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ datasource.DataSource = &PoolsDataSource{}
+
+func NewPoolsDataSource() datasource.DataSource {
+	return &PoolsDataSource{}
+}
+
+type PoolsDataSource struct {
+	provider *IpamProvider
+}
+
+type PoolsDataSourceModel struct {
+	ParentPool    types.String `tfsdk:"parent_pool"`
+	NameRegex     types.String `tfsdk:"name_regex"`
+	CIDR          types.String `tfsdk:"cidr"`
+	PrefixLength  types.Int64  `tfsdk:"prefix_length"`
+	AddressFamily types.String `tfsdk:"address_family"`
+	Tags          types.Map    `tfsdk:"tags"`
+
+	Names []string                    `tfsdk:"names"`
+	Pools []PoolsDataSourceEntryModel `tfsdk:"pools"`
+}
+
+type PoolsDataSourceEntryModel struct {
+	Name               types.String `tfsdk:"name"`
+	CIDRs              []string     `tfsdk:"cidrs"`
+	ParentPool         types.String `tfsdk:"parent_pool"`
+	AllocationStrategy types.String `tfsdk:"allocation_strategy"`
+
+	MetadataModel
+}
+
+func (d *PoolsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_pools"
+}
+
+func (d *PoolsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	entry := map[string]schema.Attribute{
+		"name": schema.StringAttribute{
+			MarkdownDescription: "Name of the IP pool",
+			Computed:            true,
+		},
+		"cidrs": schema.ListAttribute{
+			MarkdownDescription: "CIDR blocks in the pool",
+			Computed:            true,
+			ElementType:         types.StringType,
+		},
+		"parent_pool": schema.StringAttribute{
+			MarkdownDescription: "Name of the pool the CIDR of this pool was allocated from, null for a top-level pool",
+			Computed:            true,
+		},
+		"allocation_strategy": schema.StringAttribute{
+			MarkdownDescription: "Default allocation strategy of the pool, null when the pool uses 'first_fit'",
+			Computed:            true,
+		},
+	}
+	maps.Copy(entry, metadataDataSourceAttributes("pool"))
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "IPAM pools data source for listing pools, sorted by name. All filters are optional and must all match.",
+
+		Attributes: map[string]schema.Attribute{
+			"parent_pool": schema.StringAttribute{
+				MarkdownDescription: "Only list pools allocated from this pool",
+				Optional:            true,
+			},
+			"name_regex": schema.StringAttribute{
+				MarkdownDescription: "Only list pools whose name matches this regular expression",
+				Optional:            true,
+			},
+			"cidr": schema.StringAttribute{
+				MarkdownDescription: "Only list pools with a CIDR that lies within this CIDR",
+				Optional:            true,
+			},
+			"prefix_length": schema.Int64Attribute{
+				MarkdownDescription: "Only list pools with a CIDR of this prefix length",
+				Optional:            true,
+			},
+			"address_family": schema.StringAttribute{
+				MarkdownDescription: "Only list pools with a CIDR of this address family, `ipv4` or `ipv6`",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf("ipv4", "ipv6"),
+				},
+			},
+			"tags": schema.MapAttribute{
+				MarkdownDescription: "Only list pools that have all of these tags",
+				Optional:            true,
+				ElementType:         types.StringType,
+			},
+			"names": schema.ListAttribute{
+				MarkdownDescription: "Names of the matching pools",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+			"pools": schema.ListNestedAttribute{
+				MarkdownDescription: "The matching pools",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: entry,
+				},
+			},
+		},
+	}
+}
+
+func (d *PoolsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.provider = provider
+}
+
+func (d *PoolsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data PoolsDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	filter, diags := newListFilter(ctx, "name_regex", data.NameRegex, data.CIDR, data.PrefixLength, data.AddressFamily, data.Tags)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	pools, err := d.provider.storage.ListPools(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to List Pools",
+			fmt.Sprintf("Could not list pools from storage: %s", err),
+		)
+		return
+	}
+
+	// storage returns pools in map order
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+
+	data.Names = []string{}
+	data.Pools = []PoolsDataSourceEntryModel{}
+	for _, pool := range pools {
+		if !data.ParentPool.IsNull() && pool.ParentPool != data.ParentPool.ValueString() {
+			continue
+		}
+		if !filter.matchName(pool.Name) || !slices.ContainsFunc(pool.CIDRs, filter.matchCIDR) || !filter.matchMetadata(pool.Metadata) {
+			continue
+		}
+
+		metadata, diags := newMetadataModel(ctx, pool.Metadata)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		data.Names = append(data.Names, pool.Name)
+		data.Pools = append(data.Pools, PoolsDataSourceEntryModel{
+			Name:               types.StringValue(pool.Name),
+			CIDRs:              pool.CIDRs,
+			ParentPool:         optionalString(pool.ParentPool),
+			AllocationStrategy: optionalString(pool.AllocationStrategy),
+			MetadataModel:      metadata,
+		})
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+)
+
+func TestAccPoolsDataSource_Basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccPoolsDataSourceConfig(""),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.tfipam_pools.test",
+						tfjsonpath.New("names"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("list-pool-a"),
+							knownvalue.StringExact("list-pool-b"),
+							knownvalue.StringExact("list-pool-c"),
+						}),
+					),
+					statecheck.ExpectKnownValue(
+						"data.tfipam_pools.test",
+						tfjsonpath.New("pools").AtSliceIndex(1).AtMapKey("cidrs"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("fd00:1::/48"),
+						}),
+					),
+				},
+			},
+			{
+				Config: testAccPoolsDataSourceConfig(`address_family = "ipv4"`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.tfipam_pools.test",
+						tfjsonpath.New("names"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("list-pool-a"),
+							knownvalue.StringExact("list-pool-c"),
+						}),
+					),
+				},
+			},
+			{
+				Config: testAccPoolsDataSourceConfig(`cidr = "10.80.0.0/12"`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.tfipam_pools.test",
+						tfjsonpath.New("names"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("list-pool-a"),
+						}),
+					),
+				},
+			},
+			{
+				Config: testAccPoolsDataSourceConfig(`tags = { team = "net" }`),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue(
+						"data.tfipam_pools.test",
+						tfjsonpath.New("names"),
+						knownvalue.ListExact([]knownvalue.Check{
+							knownvalue.StringExact("list-pool-c"),
+						}),
+					),
+				},
+			},
+		},
+	})
+}
+
+// testAccPoolsDataSourceConfig generates three pools and a list data source selecting
+// them by name, with the given extra filter.
+func testAccPoolsDataSourceConfig(filter string) string {
+	return fmt.Sprintf(`
+resource "tfipam_pool" "c" {
+  name  = "list-pool-c"
+  cidrs = ["192.168.0.0/16"]
+  tags  = { team = "net" }
+}
+
+resource "tfipam_pool" "a" {
+  name  = "list-pool-a"
+  cidrs = ["10.80.0.0/16", "172.16.0.0/12"]
+}
+
+resource "tfipam_pool" "b" {
+  name  = "list-pool-b"
+  cidrs = ["fd00:1::/48"]
+}
+
+data "tfipam_pools" "test" {
+  name_regex = "^list-pool-"
+  %[1]s
+
+  depends_on = [tfipam_pool.a, tfipam_pool.b, tfipam_pool.c]
+}
+`, filter)
+}
@@ -3,14 +3,20 @@ package provider
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/action"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
@@ -21,6 +27,7 @@ var _ provider.Provider = &IpamProvider{}
 var _ provider.ProviderWithFunctions = &IpamProvider{}
 var _ provider.ProviderWithEphemeralResources = &IpamProvider{}
 var _ provider.ProviderWithActions = &IpamProvider{}
+var _ provider.ProviderWithConfigValidators = &IpamProvider{}
 
 type IpamProvider struct {
 	// version is set to the provider version on release, "dev" when the
@@ -30,6 +37,19 @@ type IpamProvider struct {
 
 	// storage backend for persistent state
 	storage storage.Storage
+
+	// planMu serializes planning allocations, so allocations planned in the
+	// same run get different CIDRs
+	planMu sync.Mutex
+
+	// planned holds the CIDRs shown in the plans of new allocations until they
+	// are created, by allocation ID. It only lives as long as the provider
+	// process, planning never writes to storage.
+	plannedMu sync.Mutex
+	planned   map[string]plannedCIDR
+
+	// addressMu serializes picking host addresses from allocations
+	addressMu sync.Mutex
 }
 
 // provider data model.
@@ -45,6 +65,31 @@ type IpamProviderModel struct {
 	S3AccessKeyID         types.String `tfsdk:"s3_access_key_id"`
 	S3SecretAccessKey     types.String `tfsdk:"s3_secret_access_key"`
 	S3SessionToken        types.String `tfsdk:"s3_session_token"`
+	CacheTTL              types.String `tfsdk:"cache_ttl"`
+
+	// storage backend blocks, at most one may be configured
+	File      *FileBackendModel      `tfsdk:"file"`
+	S3        *S3BackendModel        `tfsdk:"s3"`
+	AzureBlob *AzureBlobBackendModel `tfsdk:"azure_blob"`
+}
+
+type FileBackendModel struct {
+	Path types.String `tfsdk:"path"`
+}
+
+type S3BackendModel struct {
+	Region          types.String `tfsdk:"region"`
+	BucketName      types.String `tfsdk:"bucket_name"`
+	ObjectKey       types.String `tfsdk:"object_key"`
+	AccessKeyID     types.String `tfsdk:"access_key_id"`
+	SecretAccessKey types.String `tfsdk:"secret_access_key"`
+	SessionToken    types.String `tfsdk:"session_token"`
+}
+
+type AzureBlobBackendModel struct {
+	ConnectionString types.String `tfsdk:"connection_string"`
+	ContainerName    types.String `tfsdk:"container_name"`
+	BlobName         types.String `tfsdk:"blob_name"`
 }
 
 func (p *IpamProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -58,53 +103,139 @@ func (p *IpamProvider) Schema(ctx context.Context, req provider.SchemaRequest, r
 		Attributes: map[string]schema.Attribute{
 			"storage_type": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "Storage backend type. Supported values: 'file' (default), 'azure_blob' (Azure Blob Storage), 'aws_s3' (AWS S3)",
+				MarkdownDescription: "Storage backend type. Supported values: 'file' (default), 'azure_blob' (Azure Blob Storage), 'aws_s3' (AWS S3). Can also be set with the `TFIPAM_STORAGE_TYPE` environment variable.",
+				DeprecationMessage:  "Configure the storage backend with a 'file', 's3' or 'azure_blob' block instead. This attribute will be removed in the next major version of the provider.",
+				Validators: []validator.String{
+					stringvalidator.OneOf("file", "azure_blob", "aws_s3"),
+				},
 			},
 			"file_path": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "Path to storage file for 'file' storage backend. Required for 'file' backend. Defaults to '.terraform/ipam-storage.json'",
+				MarkdownDescription: "Path to storage file for 'file' storage backend. Required for 'file' backend. Defaults to '.terraform/ipam-storage.json'. Can also be set with the `TFIPAM_FILE_PATH` environment variable.",
+				DeprecationMessage:  "Use 'path' in the 'file' block instead. This attribute will be removed in the next major version of the provider.",
 			},
 			"azure_connection_string": schema.StringAttribute{
 				Optional:            true,
 				Sensitive:           true,
-				MarkdownDescription: "Connection string for Azure Blob Storage. Required for 'azure_blob' backend.",
+				MarkdownDescription: "Connection string for Azure Blob Storage. Required for 'azure_blob' backend. Can also be set with the `TFIPAM_AZURE_CONNECTION_STRING` environment variable.",
+				DeprecationMessage:  "Use 'connection_string' in the 'azure_blob' block instead. This attribute will be removed in the next major version of the provider.",
 			},
 			"azure_container_name": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "Container name for Azure Blob Storage. Required for 'azure_blob' backend.",
+				MarkdownDescription: "Container name for Azure Blob Storage. Required for 'azure_blob' backend. Can also be set with the `TFIPAM_AZURE_CONTAINER_NAME` environment variable.",
+				DeprecationMessage:  "Use 'container_name' in the 'azure_blob' block instead. This attribute will be removed in the next major version of the provider.",
 			},
 			"azure_blob_name": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "Blob name for Azure Blob Storage. Defaults to 'ipam-storage.json'",
+				MarkdownDescription: "Blob name for Azure Blob Storage. Defaults to 'ipam-storage.json'. Can also be set with the `TFIPAM_AZURE_BLOB_NAME` environment variable.",
+				DeprecationMessage:  "Use 'blob_name' in the 'azure_blob' block instead. This attribute will be removed in the next major version of the provider.",
 			},
 			"s3_region": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "AWS region for S3 bucket. Required for 'aws_s3' backend.",
+				MarkdownDescription: "AWS region for S3 bucket. Required for 'aws_s3' backend. Can also be set with the `TFIPAM_S3_REGION` environment variable.",
+				DeprecationMessage:  "Use 'region' in the 's3' block instead. This attribute will be removed in the next major version of the provider.",
 			},
 			"s3_bucket_name": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "S3 bucket name. Required for 'aws_s3' backend.",
+				MarkdownDescription: "S3 bucket name. Required for 'aws_s3' backend. Can also be set with the `TFIPAM_S3_BUCKET_NAME` environment variable.",
+				DeprecationMessage:  "Use 'bucket_name' in the 's3' block instead. This attribute will be removed in the next major version of the provider.",
 			},
 			"s3_object_key": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "S3 object key (file path). Defaults to 'ipam-storage.json'",
+				MarkdownDescription: "S3 object key (file path). Defaults to 'ipam-storage.json'. Can also be set with the `TFIPAM_S3_OBJECT_KEY` environment variable.",
+				DeprecationMessage:  "Use 'object_key' in the 's3' block instead. This attribute will be removed in the next major version of the provider.",
 			},
 			"s3_access_key_id": schema.StringAttribute{
 				Optional:            true,
 				Sensitive:           true,
-				MarkdownDescription: "AWS Access Key ID. Optional - uses default AWS credential chain if not provided.",
+				MarkdownDescription: "AWS Access Key ID. Optional - uses default AWS credential chain if not provided. Can also be set with the `TFIPAM_S3_ACCESS_KEY_ID` environment variable.",
+				DeprecationMessage:  "Use 'access_key_id' in the 's3' block instead. This attribute will be removed in the next major version of the provider.",
 			},
 			"s3_secret_access_key": schema.StringAttribute{
 				Optional:            true,
 				Sensitive:           true,
-				MarkdownDescription: "AWS Secret Access Key. Required if s3_access_key_id is provided.",
+				MarkdownDescription: "AWS Secret Access Key. Required if s3_access_key_id is provided. Can also be set with the `TFIPAM_S3_SECRET_ACCESS_KEY` environment variable.",
+				DeprecationMessage:  "Use 'secret_access_key' in the 's3' block instead. This attribute will be removed in the next major version of the provider.",
 			},
 			"s3_session_token": schema.StringAttribute{
 				Optional:            true,
 				Sensitive:           true,
-				MarkdownDescription: "AWS Session Token. Optional - for temporary credentials.",
+				MarkdownDescription: "AWS Session Token. Optional - for temporary credentials. Can also be set with the `TFIPAM_S3_SESSION_TOKEN` environment variable.",
+				DeprecationMessage:  "Use 'session_token' in the 's3' block instead. This attribute will be removed in the next major version of the provider.",
+			},
+			"cache_ttl": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "How long the 'azure_blob' and 'aws_s3' backends serve reads from memory before checking the remote ETag for changes, as a Go duration (e.g. '30s', '5m'). Defaults to '0s', which revalidates on every read. Writes always revalidate. Can also be set with the `TFIPAM_CACHE_TTL` environment variable.",
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"file": schema.SingleNestedBlock{
+				MarkdownDescription: "Store data in a local JSON file. This is the default when no storage block is configured.",
+				Attributes: map[string]schema.Attribute{
+					"path": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Path to the storage file. Defaults to '.terraform/ipam-storage.json'. Can also be set with the `TFIPAM_FILE_PATH` environment variable.",
+					},
+				},
+			},
+			"s3": schema.SingleNestedBlock{
+				MarkdownDescription: "Store data in an AWS S3 bucket.",
+				Attributes: map[string]schema.Attribute{
+					"region": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "AWS region of the S3 bucket. Required unless set with the `TFIPAM_S3_REGION` environment variable.",
+					},
+					"bucket_name": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "S3 bucket name. Required unless set with the `TFIPAM_S3_BUCKET_NAME` environment variable.",
+					},
+					"object_key": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "S3 object key (file path). Defaults to 'ipam-storage.json'. Can also be set with the `TFIPAM_S3_OBJECT_KEY` environment variable.",
+					},
+					"access_key_id": schema.StringAttribute{
+						Optional:            true,
+						Sensitive:           true,
+						MarkdownDescription: "AWS Access Key ID. Uses the default AWS credential chain if not provided. Can also be set with the `TFIPAM_S3_ACCESS_KEY_ID` environment variable.",
+					},
+					"secret_access_key": schema.StringAttribute{
+						Optional:            true,
+						Sensitive:           true,
+						MarkdownDescription: "AWS Secret Access Key. Required if access_key_id is provided. Can also be set with the `TFIPAM_S3_SECRET_ACCESS_KEY` environment variable.",
+					},
+					"session_token": schema.StringAttribute{
+						Optional:            true,
+						Sensitive:           true,
+						MarkdownDescription: "AWS Session Token for temporary credentials. Can also be set with the `TFIPAM_S3_SESSION_TOKEN` environment variable.",
+					},
+				},
+			},
+			"azure_blob": schema.SingleNestedBlock{
+				MarkdownDescription: "Store data in an Azure Blob Storage container.",
+				Attributes: map[string]schema.Attribute{
+					"connection_string": schema.StringAttribute{
+						Optional:            true,
+						Sensitive:           true,
+						MarkdownDescription: "Connection string for the storage account. Required unless set with the `TFIPAM_AZURE_CONNECTION_STRING` environment variable.",
+					},
+					"container_name": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Blob container name. Required unless set with the `TFIPAM_AZURE_CONTAINER_NAME` environment variable.",
+					},
+					"blob_name": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Blob name. Defaults to 'ipam-storage.json'. Can also be set with the `TFIPAM_AZURE_BLOB_NAME` environment variable.",
+					},
+				},
+			},
+		},
+	}
+}
+
+func (p *IpamProvider) ConfigValidators(ctx context.Context) []provider.ConfigValidator {
+	return []provider.ConfigValidator{
+		storageBlocksValidator{},
+		legacyStorageAttributesValidator{},
 	}
 }
 
@@ -118,49 +249,10 @@ func (p *IpamProvider) Configure(ctx context.Context, req provider.ConfigureRequ
 
 	// set up storage backend
 	if p.storage == nil {
-		storageType := "file"
-		if !data.StorageType.IsNull() && !data.StorageType.IsUnknown() {
-			storageType = data.StorageType.ValueString()
-		}
-
-		storageConfig := &storage.Config{
-			Type: storageType,
-		}
-
-		// File backend config
-		if !data.FilePath.IsNull() && !data.FilePath.IsUnknown() {
-			storageConfig.FilePath = data.FilePath.ValueString()
-		}
-
-		// Azure backend config
-		if !data.AzureConnectionString.IsNull() && !data.AzureConnectionString.IsUnknown() {
-			storageConfig.AzureConnectionString = data.AzureConnectionString.ValueString()
-		}
-		if !data.AzureContainerName.IsNull() && !data.AzureContainerName.IsUnknown() {
-			storageConfig.AzureContainerName = data.AzureContainerName.ValueString()
-		}
-		if !data.AzureBlobName.IsNull() && !data.AzureBlobName.IsUnknown() {
-			storageConfig.AzureBlobName = data.AzureBlobName.ValueString()
-		}
-
-		// S3 backend config
-		if !data.S3Region.IsNull() && !data.S3Region.IsUnknown() {
-			storageConfig.S3Region = data.S3Region.ValueString()
-		}
-		if !data.S3BucketName.IsNull() && !data.S3BucketName.IsUnknown() {
-			storageConfig.S3BucketName = data.S3BucketName.ValueString()
-		}
-		if !data.S3ObjectKey.IsNull() && !data.S3ObjectKey.IsUnknown() {
-			storageConfig.S3ObjectKey = data.S3ObjectKey.ValueString()
-		}
-		if !data.S3AccessKeyID.IsNull() && !data.S3AccessKeyID.IsUnknown() {
-			storageConfig.S3AccessKeyID = data.S3AccessKeyID.ValueString()
-		}
-		if !data.S3SecretAccessKey.IsNull() && !data.S3SecretAccessKey.IsUnknown() {
-			storageConfig.S3SecretAccessKey = data.S3SecretAccessKey.ValueString()
-		}
-		if !data.S3SessionToken.IsNull() && !data.S3SessionToken.IsUnknown() {
-			storageConfig.S3SessionToken = data.S3SessionToken.ValueString()
+		storageConfig, diags := newStorageConfig(data)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
 		}
 
 		var err error
@@ -174,43 +266,245 @@ func (p *IpamProvider) Configure(ctx context.Context, req provider.ConfigureRequ
 		}
 
 		tflog.Debug(ctx, "Storage backend initialized", map[string]any{
-			"type": storageConfig.Type,
+			"type":      storageConfig.Type,
+			"cache_ttl": storageConfig.CacheTTL.String(),
 		})
 	}
 
 	// Pass provider instance to resources so they can access storage
 	resp.ResourceData = p
 	resp.DataSourceData = p
+	resp.ActionData = p
+	resp.EphemeralResourceData = p
 
 	tflog.Debug(ctx, "Provider configured successfully", map[string]any{
 		"provider_ptr": fmt.Sprintf("%p", p),
 	})
 }
 
+// newStorageConfig resolves the storage backend configuration. Attributes set
+// in the provider block take precedence over the TFIPAM_* environment
+// variables, which take precedence over the backend defaults.
+func newStorageConfig(data IpamProviderModel) (*storage.Config, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	// read the environment first so anything set in the provider block overrides it
+	storageConfig := &storage.Config{}
+	if err := storageConfig.ApplyEnv(); err != nil {
+		diags.AddAttributeError(path.Root("cache_ttl"), "Invalid Cache TTL", err.Error())
+		return nil, diags
+	}
+
+	if !data.StorageType.IsNull() && !data.StorageType.IsUnknown() {
+		storageConfig.Type = data.StorageType.ValueString()
+	}
+	if storageConfig.Type == "" {
+		storageConfig.Type = "file"
+	}
+
+	// File backend config
+	if !data.FilePath.IsNull() && !data.FilePath.IsUnknown() {
+		storageConfig.FilePath = data.FilePath.ValueString()
+	}
+
+	// Azure backend config
+	if !data.AzureConnectionString.IsNull() && !data.AzureConnectionString.IsUnknown() {
+		storageConfig.AzureConnectionString = data.AzureConnectionString.ValueString()
+	}
+	if !data.AzureContainerName.IsNull() && !data.AzureContainerName.IsUnknown() {
+		storageConfig.AzureContainerName = data.AzureContainerName.ValueString()
+	}
+	if !data.AzureBlobName.IsNull() && !data.AzureBlobName.IsUnknown() {
+		storageConfig.AzureBlobName = data.AzureBlobName.ValueString()
+	}
+
+	// S3 backend config
+	if !data.S3Region.IsNull() && !data.S3Region.IsUnknown() {
+		storageConfig.S3Region = data.S3Region.ValueString()
+	}
+	if !data.S3BucketName.IsNull() && !data.S3BucketName.IsUnknown() {
+		storageConfig.S3BucketName = data.S3BucketName.ValueString()
+	}
+	if !data.S3ObjectKey.IsNull() && !data.S3ObjectKey.IsUnknown() {
+		storageConfig.S3ObjectKey = data.S3ObjectKey.ValueString()
+	}
+	if !data.S3AccessKeyID.IsNull() && !data.S3AccessKeyID.IsUnknown() {
+		storageConfig.S3AccessKeyID = data.S3AccessKeyID.ValueString()
+	}
+	if !data.S3SecretAccessKey.IsNull() && !data.S3SecretAccessKey.IsUnknown() {
+		storageConfig.S3SecretAccessKey = data.S3SecretAccessKey.ValueString()
+	}
+	if !data.S3SessionToken.IsNull() && !data.S3SessionToken.IsUnknown() {
+		storageConfig.S3SessionToken = data.S3SessionToken.ValueString()
+	}
+
+	// Cache config
+	if !data.CacheTTL.IsNull() && !data.CacheTTL.IsUnknown() {
+		ttl, err := time.ParseDuration(data.CacheTTL.ValueString())
+		if err != nil || ttl < 0 {
+			diags.AddAttributeError(
+				path.Root("cache_ttl"),
+				"Invalid Cache TTL",
+				fmt.Sprintf("cache_ttl must be a non-negative duration such as '30s' or '5m', got '%s'", data.CacheTTL.ValueString()),
+			)
+			return nil, diags
+		}
+		storageConfig.CacheTTL = ttl
+	}
+
+	// Storage blocks, only one of them can be configured
+	switch {
+	case data.File != nil:
+		storageConfig.Type = "file"
+		if !data.File.Path.IsNull() && !data.File.Path.IsUnknown() {
+			storageConfig.FilePath = data.File.Path.ValueString()
+		}
+	case data.S3 != nil:
+		storageConfig.Type = "aws_s3"
+		if !data.S3.Region.IsNull() && !data.S3.Region.IsUnknown() {
+			storageConfig.S3Region = data.S3.Region.ValueString()
+		}
+		if !data.S3.BucketName.IsNull() && !data.S3.BucketName.IsUnknown() {
+			storageConfig.S3BucketName = data.S3.BucketName.ValueString()
+		}
+		if !data.S3.ObjectKey.IsNull() && !data.S3.ObjectKey.IsUnknown() {
+			storageConfig.S3ObjectKey = data.S3.ObjectKey.ValueString()
+		}
+		if !data.S3.AccessKeyID.IsNull() && !data.S3.AccessKeyID.IsUnknown() {
+			storageConfig.S3AccessKeyID = data.S3.AccessKeyID.ValueString()
+		}
+		if !data.S3.SecretAccessKey.IsNull() && !data.S3.SecretAccessKey.IsUnknown() {
+			storageConfig.S3SecretAccessKey = data.S3.SecretAccessKey.ValueString()
+		}
+		if !data.S3.SessionToken.IsNull() && !data.S3.SessionToken.IsUnknown() {
+			storageConfig.S3SessionToken = data.S3.SessionToken.ValueString()
+		}
+	case data.AzureBlob != nil:
+		storageConfig.Type = "azure_blob"
+		if !data.AzureBlob.ConnectionString.IsNull() && !data.AzureBlob.ConnectionString.IsUnknown() {
+			storageConfig.AzureConnectionString = data.AzureBlob.ConnectionString.ValueString()
+		}
+		if !data.AzureBlob.ContainerName.IsNull() && !data.AzureBlob.ContainerName.IsUnknown() {
+			storageConfig.AzureContainerName = data.AzureBlob.ContainerName.ValueString()
+		}
+		if !data.AzureBlob.BlobName.IsNull() && !data.AzureBlob.BlobName.IsUnknown() {
+			storageConfig.AzureBlobName = data.AzureBlob.BlobName.ValueString()
+		}
+	}
+
+	usesBlock := data.File != nil || data.S3 != nil || data.AzureBlob != nil
+	diags.Append(validateStorageConfig(storageConfig, usesBlock)...)
+	return storageConfig, diags
+}
+
+// storageRequirement is an attribute a storage backend can't work without.
+type storageRequirement struct {
+	attribute string
+	env       string
+	value     func(c *storage.Config) string
+}
+
+// storageBlocks maps each storage type to the block that configures it.
+var storageBlocks = map[string]string{
+	"file":       "file",
+	"aws_s3":     "s3",
+	"azure_blob": "azure_blob",
+}
+
+var storageRequirements = map[string][]storageRequirement{
+	"file": nil,
+	"azure_blob": {
+		{"connection_string", storage.EnvAzureConnectionString, func(c *storage.Config) string { return c.AzureConnectionString }},
+		{"container_name", storage.EnvAzureContainerName, func(c *storage.Config) string { return c.AzureContainerName }},
+	},
+	"aws_s3": {
+		{"region", storage.EnvS3Region, func(c *storage.Config) string { return c.S3Region }},
+		{"bucket_name", storage.EnvS3BucketName, func(c *storage.Config) string { return c.S3BucketName }},
+	},
+}
+
+// validateStorageConfig reports every attribute the chosen storage type is missing.
+// When the backend was configured with a block the errors point at that block.
+func validateStorageConfig(storageConfig *storage.Config, usesBlock bool) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	requirements, ok := storageRequirements[storageConfig.Type]
+	if !ok {
+		diags.AddAttributeError(
+			path.Root("storage_type"),
+			"Invalid Storage Type",
+			fmt.Sprintf("storage_type must be one of 'file', 'azure_blob' or 'aws_s3', got '%s'", storageConfig.Type),
+		)
+		return diags
+	}
+
+	block := storageBlocks[storageConfig.Type]
+	for _, requirement := range requirements {
+		if requirement.value(storageConfig) != "" {
+			continue
+		}
+
+		detail := fmt.Sprintf("The '%s' storage backend requires %s. Set it in the %s block or with the %s environment variable.",
+			storageConfig.Type, requirement.attribute, block, requirement.env)
+		if usesBlock {
+			diags.AddAttributeError(path.Root(block).AtName(requirement.attribute), "Missing Storage Configuration", detail)
+		} else {
+			diags.AddError("Missing Storage Configuration", detail)
+		}
+	}
+
+	return diags
+}
+
 func (p *IpamProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewPoolResource,
 		NewAllocationResource,
+		NewAllocationSetResource,
+		NewAllocationGroupResource,
+		NewAddressResource,
 	}
 }
 
 func (p *IpamProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
-	return []func() ephemeral.EphemeralResource{}
+	return []func() ephemeral.EphemeralResource{
+		NewLeaseEphemeralResource,
+	}
 }
 
 func (p *IpamProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewPoolDataSource,
 		NewAllocationDataSource,
+		NewPoolsDataSource,
+		NewAllocationsDataSource,
+		NewPoolUtilizationDataSource,
+		NewAvailableCIDRsDataSource,
+		NewLookupDataSource,
 	}
 }
 
 func (p *IpamProvider) Functions(ctx context.Context) []func() function.Function {
-	return []func() function.Function{}
+	return []func() function.Function{
+		NewCIDROverlapsFunction,
+		NewCIDRContainsFunction,
+		NewCIDRSubtractFunction,
+		NewCIDRSummarizeFunction,
+		NewRangeToCIDRsFunction,
+		NewCIDRSplitFunction,
+		NewCIDRLastAddressFunction,
+		NewCIDRSizeFunction,
+	}
 }
 
 func (p *IpamProvider) Actions(ctx context.Context) []func() action.Action {
-	return []func() action.Action{}
+	return []func() action.Action{
+		NewMigrateStorageAction,
+		NewReclaimExpiredAction,
+		NewCompactAction,
+		NewVerifyAction,
+		NewSnapshotAction,
+	}
 }
 
 func New(version string) func() provider.Provider {
@@ -220,3 +514,11 @@ func New(version string) func() provider.Provider {
 		}
 	}
 }
+
+// optionalString maps an empty string from storage to a null attribute value.
+func optionalString(value string) types.String {
+	if value == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(value)
+}
@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestNewStorageConfig_EnvironmentFallback(t *testing.T) {
+	t.Setenv(storage.EnvStorageType, "aws_s3")
+	t.Setenv(storage.EnvS3Region, "us-east-1")
+	t.Setenv(storage.EnvS3BucketName, "env-bucket")
+	t.Setenv(storage.EnvCacheTTL, "1m")
+
+	config, diags := newStorageConfig(IpamProviderModel{
+		S3BucketName: types.StringValue("hcl-bucket"),
+	})
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	if config.Type != "aws_s3" {
+		t.Errorf("expected storage type from environment, got %q", config.Type)
+	}
+	if config.S3Region != "us-east-1" {
+		t.Errorf("expected region from environment, got %q", config.S3Region)
+	}
+	if config.S3BucketName != "hcl-bucket" {
+		t.Errorf("expected provider block to take precedence, got %q", config.S3BucketName)
+	}
+	if config.CacheTTL.String() != "1m0s" {
+		t.Errorf("expected cache ttl from environment, got %s", config.CacheTTL)
+	}
+}
+
+func TestNewStorageConfig_DefaultsToFile(t *testing.T) {
+	t.Setenv(storage.EnvStorageType, "")
+
+	config, diags := newStorageConfig(IpamProviderModel{})
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if config.Type != "file" {
+		t.Errorf("expected file storage type, got %q", config.Type)
+	}
+}
+
+func TestNewStorageConfig_MissingAttributes(t *testing.T) {
+	t.Setenv(storage.EnvS3Region, "")
+	t.Setenv(storage.EnvS3BucketName, "")
+
+	_, diags := newStorageConfig(IpamProviderModel{
+		StorageType: types.StringValue("aws_s3"),
+	})
+	if diags.ErrorsCount() != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", diags.ErrorsCount(), diags)
+	}
+	for _, attr := range []string{storage.EnvS3Region, storage.EnvS3BucketName} {
+		found := false
+		for _, d := range diags.Errors() {
+			if strings.Contains(d.Detail(), attr) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected an error naming %s", attr)
+		}
+	}
+}
+
+func TestNewStorageConfig_InvalidStorageType(t *testing.T) {
+	_, diags := newStorageConfig(IpamProviderModel{
+		StorageType: types.StringValue("fiel"),
+	})
+	if !diags.HasError() || !strings.Contains(diags.Errors()[0].Summary(), "Invalid Storage Type") {
+		t.Fatalf("expected invalid storage type error, got %v", diags)
+	}
+}
+
+func TestNewStorageConfig_Blocks(t *testing.T) {
+	t.Setenv(storage.EnvStorageType, "file")
+	t.Setenv(storage.EnvS3Region, "us-east-1")
+
+	config, diags := newStorageConfig(IpamProviderModel{
+		S3: &S3BackendModel{
+			BucketName: types.StringValue("block-bucket"),
+		},
+	})
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if config.Type != "aws_s3" {
+		t.Errorf("expected the s3 block to select the backend, got %q", config.Type)
+	}
+	if config.S3BucketName != "block-bucket" || config.S3Region != "us-east-1" {
+		t.Errorf("expected bucket from block and region from environment, got %q and %q", config.S3BucketName, config.S3Region)
+	}
+}
+
+func TestNewStorageConfig_BlockMissingAttribute(t *testing.T) {
+	t.Setenv(storage.EnvAzureConnectionString, "")
+	t.Setenv(storage.EnvAzureContainerName, "")
+
+	_, diags := newStorageConfig(IpamProviderModel{
+		AzureBlob: &AzureBlobBackendModel{
+			ContainerName: types.StringValue("tfipam"),
+		},
+	})
+	if diags.ErrorsCount() != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", diags.ErrorsCount(), diags)
+	}
+	if !strings.Contains(diags.Errors()[0].Detail(), "connection_string") {
+		t.Errorf("expected the error to name connection_string, got %q", diags.Errors()[0].Detail())
+	}
+}
+
+func TestAccProvider_ConflictingStorageBlocks(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "tfipam" {
+  file {
+    path = "ipam-storage.json"
+  }
+
+  s3 {
+    region      = "us-east-1"
+    bucket_name = "my-tfipam-bucket"
+  }
+}
+
+resource "tfipam_pool" "test" {
+  name  = "conflicting_blocks"
+  cidrs = ["10.0.0.0/24"]
+}
+`,
+				ExpectError: regexp.MustCompile(`Conflicting Storage Blocks`),
+			},
+			{
+				Config: `
+provider "tfipam" {
+  file_path = "ipam-storage.json"
+
+  file {
+    path = "ipam-storage.json"
+  }
+}
+
+resource "tfipam_pool" "test" {
+  name  = "conflicting_blocks"
+  cidrs = ["10.0.0.0/24"]
+}
+`,
+				ExpectError: regexp.MustCompile(`Conflicting Storage Configuration`),
+			},
+		},
+	})
+}
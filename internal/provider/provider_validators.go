@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+// legacyStorageAttributes are the deprecated top-level attributes along with the
+// storage type they configure and their replacement in the storage block.
+var legacyStorageAttributes = []struct {
+	attribute      string
+	storageType    string
+	blockAttribute string
+}{
+	{"file_path", "file", "path"},
+	{"azure_connection_string", "azure_blob", "connection_string"},
+	{"azure_container_name", "azure_blob", "container_name"},
+	{"azure_blob_name", "azure_blob", "blob_name"},
+	{"s3_region", "aws_s3", "region"},
+	{"s3_bucket_name", "aws_s3", "bucket_name"},
+	{"s3_object_key", "aws_s3", "object_key"},
+	{"s3_access_key_id", "aws_s3", "access_key_id"},
+	{"s3_secret_access_key", "aws_s3", "secret_access_key"},
+	{"s3_session_token", "aws_s3", "session_token"},
+}
+
+// storageBlocksValidator makes the file, s3 and azure_blob blocks mutually
+// exclusive and checks that a deprecated storage_type agrees with the block.
+type storageBlocksValidator struct{}
+
+var _ provider.ConfigValidator = storageBlocksValidator{}
+
+func (v storageBlocksValidator) Description(ctx context.Context) string {
+	return "At most one of the file, s3 or azure_blob blocks can be configured"
+}
+
+func (v storageBlocksValidator) MarkdownDescription(ctx context.Context) string {
+	return "At most one of the `file`, `s3` or `azure_blob` blocks can be configured"
+}
+
+func (v storageBlocksValidator) ValidateProvider(ctx context.Context, req provider.ValidateConfigRequest, resp *provider.ValidateConfigResponse) {
+	configured := configuredStorageBlocks(ctx, req)
+	if len(configured) > 1 {
+		for _, block := range configured[1:] {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(block),
+				"Conflicting Storage Blocks",
+				fmt.Sprintf("Only one of the file, s3 or azure_blob blocks can be configured, found: %s", strings.Join(configured, ", ")),
+			)
+		}
+		return
+	}
+	if len(configured) == 0 {
+		return
+	}
+
+	var storageType types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("storage_type"), &storageType)...)
+	if storageType.IsNull() || storageType.IsUnknown() {
+		return
+	}
+
+	if storageBlocks[storageType.ValueString()] != configured[0] {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("storage_type"),
+			"Conflicting Storage Configuration",
+			fmt.Sprintf("storage_type '%s' does not match the %s block. Remove storage_type, the block selects the backend.", storageType.ValueString(), configured[0]),
+		)
+	}
+}
+
+// legacyStorageAttributesValidator rejects deprecated top-level storage
+// attributes alongside a storage block, and warns about attributes that
+// belong to a different backend than the selected storage_type.
+type legacyStorageAttributesValidator struct{}
+
+var _ provider.ConfigValidator = legacyStorageAttributesValidator{}
+
+func (v legacyStorageAttributesValidator) Description(ctx context.Context) string {
+	return "Deprecated storage attributes can't be combined with storage blocks and must match storage_type"
+}
+
+func (v legacyStorageAttributesValidator) MarkdownDescription(ctx context.Context) string {
+	return "Deprecated storage attributes can't be combined with storage blocks and must match `storage_type`"
+}
+
+func (v legacyStorageAttributesValidator) ValidateProvider(ctx context.Context, req provider.ValidateConfigRequest, resp *provider.ValidateConfigResponse) {
+	configured := configuredStorageBlocks(ctx, req)
+
+	var storageType types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("storage_type"), &storageType)...)
+	if storageType.IsUnknown() {
+		return
+	}
+
+	selected := storageType.ValueString()
+	if selected == "" {
+		selected = os.Getenv(storage.EnvStorageType)
+	}
+	if selected == "" {
+		selected = "file"
+	}
+
+	for _, legacy := range legacyStorageAttributes {
+		var value types.String
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root(legacy.attribute), &value)...)
+		if value.IsNull() {
+			continue
+		}
+
+		block := storageBlocks[legacy.storageType]
+		switch {
+		case len(configured) > 0:
+			resp.Diagnostics.AddAttributeError(
+				path.Root(legacy.attribute),
+				"Conflicting Storage Configuration",
+				fmt.Sprintf("%s can't be combined with the %s block. Set %s in the %s block instead.",
+					legacy.attribute, configured[0], legacy.blockAttribute, block),
+			)
+		case legacy.storageType != selected:
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root(legacy.attribute),
+				"Unused Storage Configuration",
+				fmt.Sprintf("%s only applies to the '%s' storage backend and is ignored because the storage type is '%s'.",
+					legacy.attribute, legacy.storageType, selected),
+			)
+		}
+	}
+}
+
+// configuredStorageBlocks returns the storage blocks present in the configuration, in schema order.
+func configuredStorageBlocks(ctx context.Context, req provider.ValidateConfigRequest) []string {
+	var configured []string
+	for _, block := range []string{"file", "s3", "azure_blob"} {
+		var value types.Object
+		req.Config.GetAttribute(ctx, path.Root(block), &value)
+		if !value.IsNull() {
+			configured = append(configured, block)
+		}
+	}
+	return configured
+}
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/action"
+	"github.com/hashicorp/terraform-plugin-framework/action/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ action.Action = &ReclaimExpiredAction{}
+var _ action.ActionWithConfigure = &ReclaimExpiredAction{}
+
+func NewReclaimExpiredAction() action.Action {
+	return &ReclaimExpiredAction{}
+}
+
+type ReclaimExpiredAction struct {
+	provider *IpamProvider
+}
+
+type ReclaimExpiredActionModel struct {
+	PoolName types.String `tfsdk:"pool_name"`
+	DryRun   types.Bool   `tfsdk:"dry_run"`
+}
+
+func (a *ReclaimExpiredAction) Metadata(ctx context.Context, req action.MetadataRequest, resp *action.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_reclaim_expired"
+}
+
+func (a *ReclaimExpiredAction) Schema(ctx context.Context, req action.SchemaRequest, resp *action.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Removes allocations whose `expires_at` has passed, together with their host addresses, and records each one in the storage audit log",
+
+		Attributes: map[string]schema.Attribute{
+			"pool_name": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Only reclaim expired allocations of this pool. Defaults to all pools, whether or not they set `reclaim_expired`.",
+			},
+			"dry_run": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Only report the expired allocations without removing them. Defaults to false.",
+			},
+		},
+	}
+}
+
+func (a *ReclaimExpiredAction) Configure(ctx context.Context, req action.ConfigureRequest, resp *action.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Action Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	a.provider = provider
+}
+
+func (a *ReclaimExpiredAction) Invoke(ctx context.Context, req action.InvokeRequest, resp *action.InvokeResponse) {
+	var data ReclaimExpiredActionModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	opts := storage.ReclaimOptions{
+		PoolName: data.PoolName.ValueString(),
+		DryRun:   data.DryRun.ValueBool(),
+	}
+
+	expired, err := storage.ReclaimExpired(ctx, a.provider.storage, opts)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Reclaim Failed",
+			fmt.Sprintf("Could not reclaim expired allocations: %s", err),
+		)
+		return
+	}
+
+	for _, alloc := range expired {
+		resp.SendProgress(action.InvokeProgressEvent{
+			Message: fmt.Sprintf("allocation %s of pool %s (%s) expired at %s", alloc.ID, alloc.PoolName, alloc.AllocatedCIDR, alloc.ExpiresAt.UTC().Format(time.RFC3339)),
+		})
+	}
+
+	resp.SendProgress(action.InvokeProgressEvent{
+		Message: fmt.Sprintf("%d expired allocation(s) (dry run: %t)", len(expired), opts.DryRun),
+	})
+
+	tflog.Trace(ctx, "invoked reclaim expired action", map[string]any{
+		"pool_name": opts.PoolName,
+		"dry_run":   opts.DryRun,
+		"expired":   len(expired),
+	})
+}
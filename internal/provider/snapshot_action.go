@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/action"
+	"github.com/hashicorp/terraform-plugin-framework/action/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ action.Action = &SnapshotAction{}
+var _ action.ActionWithConfigure = &SnapshotAction{}
+
+func NewSnapshotAction() action.Action {
+	return &SnapshotAction{}
+}
+
+type SnapshotAction struct {
+	provider *IpamProvider
+}
+
+type SnapshotActionModel struct {
+	Path   types.String `tfsdk:"path"`
+	Verify types.Bool   `tfsdk:"verify"`
+}
+
+func (a *SnapshotAction) Metadata(ctx context.Context, req action.MetadataRequest, resp *action.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_snapshot"
+}
+
+func (a *SnapshotAction) Schema(ctx context.Context, req action.SchemaRequest, resp *action.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Writes a point in time copy of all pools, allocations, addresses and the audit log to a local file",
+
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "File the snapshot is written to as JSON, in the same format as `tfipam export`. An existing file is replaced.",
+			},
+			"verify": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Fail without writing the snapshot when the stored data has integrity problems. Defaults to false.",
+			},
+		},
+	}
+}
+
+func (a *SnapshotAction) Configure(ctx context.Context, req action.ConfigureRequest, resp *action.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Action Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	a.provider = provider
+}
+
+func (a *SnapshotAction) Invoke(ctx context.Context, req action.InvokeRequest, resp *action.InvokeResponse) {
+	var data SnapshotActionModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	snap, err := storage.TakeSnapshot(ctx, a.provider.storage)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Storage Read Failed",
+			fmt.Sprintf("Could not read storage: %s", err),
+		)
+		return
+	}
+
+	if data.Verify.ValueBool() {
+		for _, issue := range storage.Verify(snap) {
+			resp.Diagnostics.AddError("Storage Validation Failed", issue.String())
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if err := snap.WriteFile(data.Path.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("path"),
+			"Snapshot Failed",
+			fmt.Sprintf("Could not write snapshot: %s", err),
+		)
+		return
+	}
+
+	resp.SendProgress(action.InvokeProgressEvent{
+		Message: fmt.Sprintf("wrote %d pools, %d allocations, %d addresses and %d audit records to %s",
+			len(snap.Pools), len(snap.Allocations), len(snap.Addresses), len(snap.AuditLog), data.Path.ValueString()),
+	})
+
+	tflog.Trace(ctx, "invoked snapshot action", map[string]any{
+		"path": data.Path.ValueString(),
+	})
+}
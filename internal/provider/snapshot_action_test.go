@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+func TestAccSnapshotAction_Basic(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "snapshots", "ipam.json")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_14_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: testAccMaintenanceActionConfig("snapshot-pool", "tfipam_snapshot", fmt.Sprintf(`path   = %q
+    verify = true`, snapshot)),
+				Check: testAccCheckSnapshotAllocation(snapshot, "snapshot-pool-alloc"),
+			},
+		},
+	})
+}
+
+// testAccCheckSnapshotAllocation verifies the allocation was written to the snapshot file.
+func testAccCheckSnapshotAllocation(path, allocID string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("snapshot was not written: %w", err)
+		}
+
+		var snap storage.Snapshot
+		if err := json.Unmarshal(raw, &snap); err != nil {
+			return err
+		}
+		for _, alloc := range snap.Allocations {
+			if alloc.ID == allocID {
+				return nil
+			}
+		}
+		return fmt.Errorf("allocation %s missing from snapshot", allocID)
+	}
+}
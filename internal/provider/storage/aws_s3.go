@@ -7,9 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -21,11 +24,14 @@ type S3Storage struct {
 	objectKey  string
 	mu         sync.RWMutex
 	data       *s3Data
+	cache      *documentCache
 }
 
 type s3Data struct {
 	Pools       map[string]*Pool       `json:"pools"`
 	Allocations map[string]*Allocation `json:"allocations"`
+	Addresses   map[string]*Address    `json:"addresses"`
+	AuditLog    []AuditRecord          `json:"audit_log,omitempty"`
 }
 
 // NewS3Storage creates a new AWS S3 Storage backend
@@ -34,8 +40,9 @@ type s3Data struct {
 // objectKey: S3 object key (path to the JSON file, e.g. "ipam-storage.json")
 // accessKeyID: AWS Access Key ID (optional, uses default credential chain if empty)
 // secretAccessKey: AWS Secret Access Key (optional, required if accessKeyID is provided)
-// sessionToken: AWS Session Token (optional, for temporary credentials).
-func NewS3Storage(region, bucketName, objectKey, accessKeyID, secretAccessKey, sessionToken string) (*S3Storage, error) {
+// sessionToken: AWS Session Token (optional, for temporary credentials)
+// cacheTTL: How long reads are served from memory before the object ETag is checked again.
+func NewS3Storage(region, bucketName, objectKey, accessKeyID, secretAccessKey, sessionToken string, cacheTTL time.Duration) (*S3Storage, error) {
 	if region == "" {
 		return nil, errors.New("aws region is required")
 	}
@@ -84,10 +91,8 @@ func NewS3Storage(region, bucketName, objectKey, accessKeyID, secretAccessKey, s
 		client:     client,
 		bucketName: bucketName,
 		objectKey:  objectKey,
-		data: &s3Data{
-			Pools:       make(map[string]*Pool),
-			Allocations: make(map[string]*Allocation),
-		},
+		data:       newS3Data(),
+		cache:      newDocumentCache("aws_s3", cacheTTL),
 	}
 
 	// try to load existing data. If object doesn't exist, it'll be created on first save
@@ -101,10 +106,17 @@ func NewS3Storage(region, bucketName, objectKey, accessKeyID, secretAccessKey, s
 	return s3s, nil
 }
 
-func (s3s *S3Storage) load(ctx context.Context) error {
-	s3s.mu.Lock()
-	defer s3s.mu.Unlock()
+func newS3Data() *s3Data {
+	return &s3Data{
+		Pools:       make(map[string]*Pool),
+		Allocations: make(map[string]*Allocation),
+		Addresses:   make(map[string]*Address),
+	}
+}
 
+// load downloads the storage object and replaces the in-memory document.
+// Callers must hold the write lock.
+func (s3s *S3Storage) load(ctx context.Context) error {
 	result, err := s3s.client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s3s.bucketName),
 		Key:    aws.String(s3s.objectKey),
@@ -119,28 +131,139 @@ func (s3s *S3Storage) load(ctx context.Context) error {
 		return fmt.Errorf("failed to read s3 object data: %w", err)
 	}
 
-	return json.Unmarshal(data, s3s.data)
+	doc := newS3Data()
+	if err := json.Unmarshal(data, doc); err != nil {
+		return err
+	}
+
+	s3s.data = doc
+	s3s.cache.update(aws.ToString(result.ETag))
+	return nil
+}
+
+// refresh revalidates the cached document before a read.
+func (s3s *S3Storage) refresh(ctx context.Context) error {
+	s3s.mu.Lock()
+	defer s3s.mu.Unlock()
+
+	return s3s.revalidate(ctx, false)
+}
+
+// revalidate makes sure the in-memory document matches the remote object. Unless
+// force is set, a document loaded within the cache TTL is used as is, otherwise
+// the object ETag is compared and the object is only downloaded when it changed.
+// Callers must hold the write lock.
+func (s3s *S3Storage) revalidate(ctx context.Context, force bool) error {
+	if !force && s3s.cache.valid() {
+		s3s.cache.hit(ctx, "ttl")
+		return nil
+	}
+
+	head, err := s3s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s3s.bucketName),
+		Key:    aws.String(s3s.objectKey),
+	})
+	if err != nil {
+		var nf *types.NotFound
+		if !errors.As(err, &nf) {
+			return fmt.Errorf("failed to check s3 object: %w", err)
+		}
+
+		// object hasn't been created yet or was removed by someone else
+		if s3s.cache.etag == "" {
+			s3s.cache.hit(ctx, "not_found")
+			s3s.cache.touch()
+			return nil
+		}
+		s3s.cache.miss(ctx, "deleted")
+		s3s.data = newS3Data()
+		s3s.cache.update("")
+		return nil
+	}
+
+	if s3s.cache.matches(aws.ToString(head.ETag)) {
+		s3s.cache.hit(ctx, "etag")
+		s3s.cache.touch()
+		return nil
+	}
+
+	s3s.cache.miss(ctx, "etag_changed")
+	return s3s.load(ctx)
+}
+
+// update applies change to the latest version of the document and writes it
+// back. When another writer changed the object since it was read, the object is
+// downloaded again and change is applied to it again, up to maxWriteAttempts.
+// change must not modify the document when it returns an error. Changes that
+// take space check it against the document they are applied to, so applying
+// them again never takes space another writer took in between.
+func (s3s *S3Storage) update(ctx context.Context, change func(doc *s3Data) error) error {
+	s3s.mu.Lock()
+	defer s3s.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		// always write against the latest version of the document
+		if err := s3s.revalidate(ctx, true); err != nil {
+			return err
+		}
+
+		if err := change(s3s.data); err != nil {
+			return err
+		}
+
+		err := s3s.save(ctx)
+		if err == nil {
+			return nil
+		}
+
+		// the in-memory document now differs from the remote one
+		s3s.data = newS3Data()
+		s3s.cache.invalidate()
+		if !errors.Is(err, ErrConflict) || attempt == maxWriteAttempts {
+			return err
+		}
+		s3s.cache.miss(ctx, "conflict")
+	}
 }
 
+// save uploads the document only if the object is still the version it was
+// loaded from, or doesn't exist yet if none was loaded. Callers must hold the
+// write lock.
 func (s3s *S3Storage) save(ctx context.Context) error {
 	data, err := json.MarshalIndent(s3s.data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal storage data: %w", err)
 	}
 
-	_, err = s3s.client.PutObject(ctx, &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(s3s.bucketName),
 		Key:    aws.String(s3s.objectKey),
 		Body:   bytes.NewReader(data),
-	})
+	}
+	if s3s.cache.etag != "" {
+		input.IfMatch = aws.String(s3s.cache.etag)
+	} else {
+		input.IfNoneMatch = aws.String("*")
+	}
+
+	result, err := s3s.client.PutObject(ctx, input)
 	if err != nil {
+		var re *awshttp.ResponseError
+		if errors.As(err, &re) && (re.HTTPStatusCode() == http.StatusPreconditionFailed || re.HTTPStatusCode() == http.StatusConflict) {
+			return fmt.Errorf("%w: s3 object %s was modified by another writer", ErrConflict, s3s.objectKey)
+		}
 		return fmt.Errorf("failed to upload s3 object: %w", err)
 	}
 
+	s3s.cache.update(aws.ToString(result.ETag))
 	return nil
 }
 
 func (s3s *S3Storage) GetPool(ctx context.Context, name string) (*Pool, error) {
+	if err := s3s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
 	s3s.mu.RLock()
 	defer s3s.mu.RUnlock()
 
@@ -155,6 +278,10 @@ func (s3s *S3Storage) GetPool(ctx context.Context, name string) (*Pool, error) {
 }
 
 func (s3s *S3Storage) ListPools(ctx context.Context) ([]Pool, error) {
+	if err := s3s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
 	s3s.mu.RLock()
 	defer s3s.mu.RUnlock()
 
@@ -168,29 +295,35 @@ func (s3s *S3Storage) ListPools(ctx context.Context) ([]Pool, error) {
 }
 
 func (s3s *S3Storage) SavePool(ctx context.Context, pool *Pool) error {
-	s3s.mu.Lock()
-	defer s3s.mu.Unlock()
+	return s3s.update(ctx, func(doc *s3Data) error {
+		if err := checkPool(doc.Pools, doc.Allocations, pool, nil); err != nil {
+			return err
+		}
 
-	// save a copy
-	poolCopy := *pool
-	s3s.data.Pools[pool.Name] = &poolCopy
+		// save a copy
+		poolCopy := *pool
+		doc.Pools[pool.Name] = &poolCopy
 
-	return s3s.save(ctx)
+		return nil
+	})
 }
 
 func (s3s *S3Storage) DeletePool(ctx context.Context, name string) error {
-	s3s.mu.Lock()
-	defer s3s.mu.Unlock()
-
-	if _, exists := s3s.data.Pools[name]; !exists {
-		return ErrNotFound
-	}
+	return s3s.update(ctx, func(doc *s3Data) error {
+		if _, exists := doc.Pools[name]; !exists {
+			return ErrNotFound
+		}
 
-	delete(s3s.data.Pools, name)
-	return s3s.save(ctx)
+		delete(doc.Pools, name)
+		return nil
+	})
 }
 
 func (s3s *S3Storage) GetAllocation(ctx context.Context, id string) (*Allocation, error) {
+	if err := s3s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
 	s3s.mu.RLock()
 	defer s3s.mu.RUnlock()
 
@@ -205,6 +338,10 @@ func (s3s *S3Storage) GetAllocation(ctx context.Context, id string) (*Allocation
 }
 
 func (s3s *S3Storage) ListAllocations(ctx context.Context) ([]Allocation, error) {
+	if err := s3s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
 	s3s.mu.RLock()
 	defer s3s.mu.RUnlock()
 
@@ -218,6 +355,10 @@ func (s3s *S3Storage) ListAllocations(ctx context.Context) ([]Allocation, error)
 }
 
 func (s3s *S3Storage) ListAllocationsByPool(ctx context.Context, poolName string) ([]Allocation, error) {
+	if err := s3s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
 	s3s.mu.RLock()
 	defer s3s.mu.RUnlock()
 
@@ -232,26 +373,162 @@ func (s3s *S3Storage) ListAllocationsByPool(ctx context.Context, poolName string
 }
 
 func (s3s *S3Storage) SaveAllocation(ctx context.Context, allocation *Allocation) error {
-	s3s.mu.Lock()
-	defer s3s.mu.Unlock()
+	return s3s.update(ctx, func(doc *s3Data) error {
+		if err := checkAllocations(doc.Pools, doc.Allocations, []Allocation{*allocation}, nil); err != nil {
+			return err
+		}
 
-	// save a copy
-	allocCopy := *allocation
-	s3s.data.Allocations[allocation.ID] = &allocCopy
+		// save a copy
+		allocCopy := *allocation
+		doc.Allocations[allocation.ID] = &allocCopy
 
-	return s3s.save(ctx)
+		return nil
+	})
 }
 
 func (s3s *S3Storage) DeleteAllocation(ctx context.Context, id string) error {
-	s3s.mu.Lock()
-	defer s3s.mu.Unlock()
+	return s3s.update(ctx, func(doc *s3Data) error {
+		if _, exists := doc.Allocations[id]; !exists {
+			return ErrNotFound
+		}
+
+		delete(doc.Allocations, id)
+		return nil
+	})
+}
+
+func (s3s *S3Storage) SaveAllocations(ctx context.Context, allocations []Allocation) error {
+	return s3s.update(ctx, func(doc *s3Data) error {
+		if err := checkAllocations(doc.Pools, doc.Allocations, allocations, nil); err != nil {
+			return err
+		}
+
+		for i := range allocations {
+			allocCopy := allocations[i]
+			doc.Allocations[allocCopy.ID] = &allocCopy
+		}
+
+		return nil
+	})
+}
+
+func (s3s *S3Storage) DeleteAllocations(ctx context.Context, ids []string) error {
+	return s3s.update(ctx, func(doc *s3Data) error {
+		for _, id := range ids {
+			delete(doc.Allocations, id)
+		}
+
+		return nil
+	})
+}
 
-	if _, exists := s3s.data.Allocations[id]; !exists {
-		return ErrNotFound
+func (s3s *S3Storage) Apply(ctx context.Context, batch *Batch) error {
+	return s3s.update(ctx, func(doc *s3Data) error {
+		auditLog, err := applyBatch(batch, doc.Pools, doc.Allocations, doc.Addresses, doc.AuditLog)
+		if err != nil {
+			return err
+		}
+		doc.AuditLog = auditLog
+
+		return nil
+	})
+}
+
+func (s3s *S3Storage) GetAddress(ctx context.Context, id string) (*Address, error) {
+	if err := s3s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	s3s.mu.RLock()
+	defer s3s.mu.RUnlock()
+
+	address, exists := s3s.data.Addresses[id]
+	if !exists {
+		return nil, ErrNotFound
 	}
 
-	delete(s3s.data.Allocations, id)
-	return s3s.save(ctx)
+	// return copy
+	addressCopy := *address
+	return &addressCopy, nil
+}
+
+func (s3s *S3Storage) ListAddresses(ctx context.Context) ([]Address, error) {
+	if err := s3s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	s3s.mu.RLock()
+	defer s3s.mu.RUnlock()
+
+	// return copies
+	addresses := make([]Address, 0, len(s3s.data.Addresses))
+	for _, address := range s3s.data.Addresses {
+		addresses = append(addresses, *address)
+	}
+
+	return addresses, nil
+}
+
+func (s3s *S3Storage) ListAddressesByAllocation(ctx context.Context, allocationID string) ([]Address, error) {
+	if err := s3s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	s3s.mu.RLock()
+	defer s3s.mu.RUnlock()
+
+	addresses := make([]Address, 0)
+	for _, address := range s3s.data.Addresses {
+		if address.AllocationID == allocationID {
+			addresses = append(addresses, *address)
+		}
+	}
+
+	return addresses, nil
+}
+
+func (s3s *S3Storage) SaveAddress(ctx context.Context, address *Address) error {
+	return s3s.update(ctx, func(doc *s3Data) error {
+		if err := checkAddress(doc.Addresses, address); err != nil {
+			return err
+		}
+
+		// save a copy
+		addressCopy := *address
+		doc.Addresses[address.ID] = &addressCopy
+
+		return nil
+	})
+}
+
+func (s3s *S3Storage) DeleteAddress(ctx context.Context, id string) error {
+	return s3s.update(ctx, func(doc *s3Data) error {
+		if _, exists := doc.Addresses[id]; !exists {
+			return ErrNotFound
+		}
+
+		delete(doc.Addresses, id)
+		return nil
+	})
+}
+
+func (s3s *S3Storage) AppendAuditRecords(ctx context.Context, records []AuditRecord) error {
+	return s3s.update(ctx, func(doc *s3Data) error {
+		doc.AuditLog = append(doc.AuditLog, records...)
+		return nil
+	})
+}
+
+func (s3s *S3Storage) ListAuditRecords(ctx context.Context) ([]AuditRecord, error) {
+	if err := s3s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	s3s.mu.RLock()
+	defer s3s.mu.RUnlock()
+
+	// return a copy
+	return append([]AuditRecord{}, s3s.data.AuditLog...), nil
 }
 
 func (s3s *S3Storage) Close() error {
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeS3 serves a single object and honours conditional writes like S3.
+type fakeS3 struct {
+	mu      sync.Mutex
+	body    []byte
+	version int
+	puts    int
+
+	// beforePut runs before a write is checked, to simulate another writer
+	beforePut func(f *fakeS3)
+}
+
+func (f *fakeS3) etag() string {
+	if f.body == nil {
+		return ""
+	}
+	return fmt.Sprintf(`"v%d"`, f.version)
+}
+
+func (f *fakeS3) write(body []byte) {
+	f.body = body
+	f.version++
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if f.body == nil {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				fmt.Fprint(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			}
+			return
+		}
+		w.Header().Set("ETag", f.etag())
+		if r.Method == http.MethodGet {
+			w.Write(f.body)
+		}
+	case http.MethodPut:
+		f.puts++
+		if f.beforePut != nil {
+			f.beforePut(f)
+		}
+		match, noneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+		if (match != "" && match != f.etag()) || (noneMatch == "*" && f.body != nil) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code></Error>`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		f.write(body)
+		w.Header().Set("ETag", f.etag())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// otherWriter stores a document holding only the named pool, as another
+// provider instance would.
+func (f *fakeS3) otherWriter(poolName string) {
+	doc := newS3Data()
+	if f.body != nil {
+		json.Unmarshal(f.body, doc)
+	}
+	doc.Pools[poolName] = &Pool{Name: poolName, CIDRs: []string{"10.1.0.0/16"}}
+	body, _ := json.Marshal(doc)
+	f.write(body)
+}
+
+func newTestS3Storage(t *testing.T, f *fakeS3) *S3Storage {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	return &S3Storage{
+		client: s3.New(s3.Options{
+			Region:                     "us-east-1",
+			BaseEndpoint:               aws.String(server.URL),
+			UsePathStyle:               true,
+			Credentials:                aws.AnonymousCredentials{},
+			RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+			ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+		}),
+		bucketName: "bucket",
+		objectKey:  "ipam-storage.json",
+		data:       newS3Data(),
+		cache:      newDocumentCache("aws_s3", 0),
+	}
+}
+
+func TestS3StorageConditionalWrite(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeS3{}
+	s := newTestS3Storage(t, f)
+
+	// the first write creates the object
+	if err := s.SavePool(ctx, &Pool{Name: "first", CIDRs: []string{"10.0.0.0/16"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// another writer changes the object between revalidation and upload, so
+	// the first upload is rejected and the write is applied to its version
+	f.beforePut = func(f *fakeS3) {
+		f.beforePut = nil
+		f.otherWriter("other")
+	}
+	puts := f.puts
+	if err := s.SavePool(ctx, &Pool{Name: "second", CIDRs: []string{"10.2.0.0/16"}}); err != nil {
+		t.Fatal(err)
+	}
+	if f.puts-puts != 2 {
+		t.Errorf("expected the write to be retried once, got %d uploads", f.puts-puts)
+	}
+
+	pools, err := s.ListPools(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools) != 3 {
+		t.Fatalf("expected the pools of both writers to be kept, got %v", pools)
+	}
+}
+
+func TestS3StorageConflict(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeS3{}
+	f.otherWriter("other")
+	s := newTestS3Storage(t, f)
+
+	// the object changes before every upload
+	f.beforePut = func(f *fakeS3) { f.otherWriter("other") }
+	err := s.SavePool(ctx, &Pool{Name: "mine", CIDRs: []string{"10.0.0.0/16"}})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if f.puts != maxWriteAttempts {
+		t.Errorf("expected %d uploads, got %d", maxWriteAttempts, f.puts)
+	}
+
+	// the failed write isn't visible
+	f.beforePut = nil
+	if _, err := s.GetPool(ctx, "mine"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the failed write to be discarded, got %v", err)
+	}
+	if _, err := s.GetPool(ctx, "other"); err != nil {
+		t.Fatalf("expected the other writer's pool, got %v", err)
+	}
+}
+
+func TestS3StorageOverlap(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeS3{}
+	s := newTestS3Storage(t, f)
+	if err := s.SavePool(ctx, &Pool{Name: "prod", CIDRs: []string{"10.0.0.0/16"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// another writer takes the same CIDR between revalidation and upload
+	f.beforePut = func(f *fakeS3) {
+		f.beforePut = nil
+		doc := newS3Data()
+		json.Unmarshal(f.body, doc)
+		doc.Allocations["theirs"] = &Allocation{ID: "theirs", PoolName: "prod", AllocatedCIDR: "10.0.0.0/24", PrefixLength: 24}
+		body, _ := json.Marshal(doc)
+		f.write(body)
+	}
+	err := s.SaveAllocation(ctx, &Allocation{ID: "mine", PoolName: "prod", AllocatedCIDR: "10.0.0.0/24", PrefixLength: 24})
+	if !errors.Is(err, ErrOverlap) {
+		t.Fatalf("expected ErrOverlap, got %v", err)
+	}
+	if _, err := s.GetAllocation(ctx, "mine"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the overlapping allocation not to be stored, got %v", err)
+	}
+
+	// space that is still free is taken on the retry
+	f.beforePut = func(f *fakeS3) {
+		f.beforePut = nil
+		f.otherWriter("other")
+	}
+	if err := s.SaveAllocation(ctx, &Allocation{ID: "mine", PoolName: "prod", AllocatedCIDR: "10.0.1.0/24", PrefixLength: 24}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestS3StorageCreateRace(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeS3{}
+	s := newTestS3Storage(t, f)
+
+	// another writer creates the object after it was found missing
+	f.beforePut = func(f *fakeS3) {
+		f.beforePut = nil
+		f.otherWriter("other")
+	}
+	if err := s.SavePool(ctx, &Pool{Name: "mine", CIDRs: []string{"10.0.0.0/16"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	pools, _ := s.ListPools(ctx)
+	if len(pools) != 2 {
+		t.Fatalf("expected the created object not to be overwritten, got %v", pools)
+	}
+}
@@ -8,8 +8,12 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
 )
 
@@ -19,18 +23,22 @@ type AzureBlobStorage struct {
 	blobName      string
 	mu            sync.RWMutex
 	data          *blobData
+	cache         *documentCache
 }
 
 type blobData struct {
 	Pools       map[string]*Pool       `json:"pools"`
 	Allocations map[string]*Allocation `json:"allocations"`
+	Addresses   map[string]*Address    `json:"addresses"`
+	AuditLog    []AuditRecord          `json:"audit_log,omitempty"`
 }
 
 // NewAzureBlobStorage creates a new Azure Blob Storage backend
 // connectionString: Azure Storage connection string
 // containerName: Name of the blob container
-// blobName: Name of the blob file (e.g. "ipam-storage.json").
-func NewAzureBlobStorage(connectionString, containerName, blobName string) (*AzureBlobStorage, error) {
+// blobName: Name of the blob file (e.g. "ipam-storage.json")
+// cacheTTL: How long reads are served from memory before the blob ETag is checked again.
+func NewAzureBlobStorage(connectionString, containerName, blobName string, cacheTTL time.Duration) (*AzureBlobStorage, error) {
 	if connectionString == "" {
 		return nil, errors.New("azure connection string is required")
 	}
@@ -50,10 +58,8 @@ func NewAzureBlobStorage(connectionString, containerName, blobName string) (*Azu
 		client:        client,
 		containerName: containerName,
 		blobName:      blobName,
-		data: &blobData{
-			Pools:       make(map[string]*Pool),
-			Allocations: make(map[string]*Allocation),
-		},
+		data:          newBlobData(),
+		cache:         newDocumentCache("azure_blob", cacheTTL),
 	}
 
 	// try to load existing data, if it doesn't exist it'll be created on first save
@@ -67,10 +73,17 @@ func NewAzureBlobStorage(connectionString, containerName, blobName string) (*Azu
 	return abs, nil
 }
 
-func (abs *AzureBlobStorage) load(ctx context.Context) error {
-	abs.mu.Lock()
-	defer abs.mu.Unlock()
+func newBlobData() *blobData {
+	return &blobData{
+		Pools:       make(map[string]*Pool),
+		Allocations: make(map[string]*Allocation),
+		Addresses:   make(map[string]*Address),
+	}
+}
 
+// load downloads the storage blob and replaces the in-memory document.
+// Callers must hold the write lock.
+func (abs *AzureBlobStorage) load(ctx context.Context) error {
 	downloadResponse, err := abs.client.DownloadStream(ctx, abs.containerName, abs.blobName, nil)
 	if err != nil {
 		return err
@@ -82,25 +95,142 @@ func (abs *AzureBlobStorage) load(ctx context.Context) error {
 		return fmt.Errorf("failed to read blob data: %w", err)
 	}
 
-	return json.Unmarshal(data, abs.data)
+	doc := newBlobData()
+	if err := json.Unmarshal(data, doc); err != nil {
+		return err
+	}
+
+	abs.data = doc
+	etag := ""
+	if downloadResponse.ETag != nil {
+		etag = string(*downloadResponse.ETag)
+	}
+	abs.cache.update(etag)
+	return nil
+}
+
+// refresh revalidates the cached document before a read.
+func (abs *AzureBlobStorage) refresh(ctx context.Context) error {
+	abs.mu.Lock()
+	defer abs.mu.Unlock()
+
+	return abs.revalidate(ctx, false)
+}
+
+// revalidate makes sure the in-memory document matches the remote blob. Unless
+// force is set, a document loaded within the cache TTL is used as is, otherwise
+// the blob ETag is compared and the blob is only downloaded when it changed.
+// Callers must hold the write lock.
+func (abs *AzureBlobStorage) revalidate(ctx context.Context, force bool) error {
+	if !force && abs.cache.valid() {
+		abs.cache.hit(ctx, "ttl")
+		return nil
+	}
+
+	blobClient := abs.client.ServiceClient().NewContainerClient(abs.containerName).NewBlobClient(abs.blobName)
+	props, err := blobClient.GetProperties(ctx, nil)
+	if err != nil {
+		if !bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return fmt.Errorf("failed to check storage blob: %w", err)
+		}
+
+		// blob hasn't been created yet or was removed by someone else
+		if abs.cache.etag == "" {
+			abs.cache.hit(ctx, "not_found")
+			abs.cache.touch()
+			return nil
+		}
+		abs.cache.miss(ctx, "deleted")
+		abs.data = newBlobData()
+		abs.cache.update("")
+		return nil
+	}
+
+	if props.ETag != nil && abs.cache.matches(string(*props.ETag)) {
+		abs.cache.hit(ctx, "etag")
+		abs.cache.touch()
+		return nil
+	}
+
+	abs.cache.miss(ctx, "etag_changed")
+	return abs.load(ctx)
 }
 
+// update applies change to the latest version of the document and writes it
+// back. When another writer changed the blob since it was read, the blob is
+// downloaded again and change is applied to it again, up to maxWriteAttempts.
+// change must not modify the document when it returns an error. Changes that
+// take space check it against the document they are applied to, so applying
+// them again never takes space another writer took in between.
+func (abs *AzureBlobStorage) update(ctx context.Context, change func(doc *blobData) error) error {
+	abs.mu.Lock()
+	defer abs.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		// always write against the latest version of the document
+		if err := abs.revalidate(ctx, true); err != nil {
+			return err
+		}
+
+		if err := change(abs.data); err != nil {
+			return err
+		}
+
+		err := abs.save(ctx)
+		if err == nil {
+			return nil
+		}
+
+		// the in-memory document now differs from the remote one
+		abs.data = newBlobData()
+		abs.cache.invalidate()
+		if !errors.Is(err, ErrConflict) || attempt == maxWriteAttempts {
+			return err
+		}
+		abs.cache.miss(ctx, "conflict")
+	}
+}
+
+// save uploads the document only if the blob is still the version it was
+// loaded from, or doesn't exist yet if none was loaded. Callers must hold the
+// write lock.
 func (abs *AzureBlobStorage) save(ctx context.Context) error {
 	data, err := json.MarshalIndent(abs.data, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal storage data: %w", err)
 	}
 
-	_, err = abs.client.UploadStream(ctx, abs.containerName, abs.blobName,
-		bytes.NewReader(data), nil)
+	conditions := &blob.ModifiedAccessConditions{}
+	if abs.cache.etag != "" {
+		conditions.IfMatch = to.Ptr(azcore.ETag(abs.cache.etag))
+	} else {
+		conditions.IfNoneMatch = to.Ptr(azcore.ETagAny)
+	}
+
+	result, err := abs.client.UploadStream(ctx, abs.containerName, abs.blobName,
+		bytes.NewReader(data), &azblob.UploadStreamOptions{
+			AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: conditions},
+		})
 	if err != nil {
+		if bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+			return fmt.Errorf("%w: blob %s was modified by another writer", ErrConflict, abs.blobName)
+		}
 		return fmt.Errorf("failed to upload blob: %w", err)
 	}
 
+	etag := ""
+	if result.ETag != nil {
+		etag = string(*result.ETag)
+	}
+	abs.cache.update(etag)
 	return nil
 }
 
 func (abs *AzureBlobStorage) GetPool(ctx context.Context, name string) (*Pool, error) {
+	if err := abs.refresh(ctx); err != nil {
+		return nil, err
+	}
+
 	abs.mu.RLock()
 	defer abs.mu.RUnlock()
 
@@ -115,6 +245,10 @@ func (abs *AzureBlobStorage) GetPool(ctx context.Context, name string) (*Pool, e
 }
 
 func (abs *AzureBlobStorage) ListPools(ctx context.Context) ([]Pool, error) {
+	if err := abs.refresh(ctx); err != nil {
+		return nil, err
+	}
+
 	abs.mu.RLock()
 	defer abs.mu.RUnlock()
 
@@ -128,29 +262,35 @@ func (abs *AzureBlobStorage) ListPools(ctx context.Context) ([]Pool, error) {
 }
 
 func (abs *AzureBlobStorage) SavePool(ctx context.Context, pool *Pool) error {
-	abs.mu.Lock()
-	defer abs.mu.Unlock()
+	return abs.update(ctx, func(doc *blobData) error {
+		if err := checkPool(doc.Pools, doc.Allocations, pool, nil); err != nil {
+			return err
+		}
 
-	// save a copy
-	poolCopy := *pool
-	abs.data.Pools[pool.Name] = &poolCopy
+		// save a copy
+		poolCopy := *pool
+		doc.Pools[pool.Name] = &poolCopy
 
-	return abs.save(ctx)
+		return nil
+	})
 }
 
 func (abs *AzureBlobStorage) DeletePool(ctx context.Context, name string) error {
-	abs.mu.Lock()
-	defer abs.mu.Unlock()
-
-	if _, exists := abs.data.Pools[name]; !exists {
-		return ErrNotFound
-	}
+	return abs.update(ctx, func(doc *blobData) error {
+		if _, exists := doc.Pools[name]; !exists {
+			return ErrNotFound
+		}
 
-	delete(abs.data.Pools, name)
-	return abs.save(ctx)
+		delete(doc.Pools, name)
+		return nil
+	})
 }
 
 func (abs *AzureBlobStorage) GetAllocation(ctx context.Context, id string) (*Allocation, error) {
+	if err := abs.refresh(ctx); err != nil {
+		return nil, err
+	}
+
 	abs.mu.RLock()
 	defer abs.mu.RUnlock()
 
@@ -165,6 +305,10 @@ func (abs *AzureBlobStorage) GetAllocation(ctx context.Context, id string) (*All
 }
 
 func (abs *AzureBlobStorage) ListAllocations(ctx context.Context) ([]Allocation, error) {
+	if err := abs.refresh(ctx); err != nil {
+		return nil, err
+	}
+
 	abs.mu.RLock()
 	defer abs.mu.RUnlock()
 
@@ -178,6 +322,10 @@ func (abs *AzureBlobStorage) ListAllocations(ctx context.Context) ([]Allocation,
 }
 
 func (abs *AzureBlobStorage) ListAllocationsByPool(ctx context.Context, poolName string) ([]Allocation, error) {
+	if err := abs.refresh(ctx); err != nil {
+		return nil, err
+	}
+
 	abs.mu.RLock()
 	defer abs.mu.RUnlock()
 
@@ -192,25 +340,161 @@ func (abs *AzureBlobStorage) ListAllocationsByPool(ctx context.Context, poolName
 }
 
 func (abs *AzureBlobStorage) SaveAllocation(ctx context.Context, allocation *Allocation) error {
-	abs.mu.Lock()
-	defer abs.mu.Unlock()
+	return abs.update(ctx, func(doc *blobData) error {
+		if err := checkAllocations(doc.Pools, doc.Allocations, []Allocation{*allocation}, nil); err != nil {
+			return err
+		}
 
-	allocCopy := *allocation
-	abs.data.Allocations[allocation.ID] = &allocCopy
+		allocCopy := *allocation
+		doc.Allocations[allocation.ID] = &allocCopy
 
-	return abs.save(ctx)
+		return nil
+	})
 }
 
 func (abs *AzureBlobStorage) DeleteAllocation(ctx context.Context, id string) error {
-	abs.mu.Lock()
-	defer abs.mu.Unlock()
+	return abs.update(ctx, func(doc *blobData) error {
+		if _, exists := doc.Allocations[id]; !exists {
+			return ErrNotFound
+		}
+
+		delete(doc.Allocations, id)
+		return nil
+	})
+}
+
+func (abs *AzureBlobStorage) SaveAllocations(ctx context.Context, allocations []Allocation) error {
+	return abs.update(ctx, func(doc *blobData) error {
+		if err := checkAllocations(doc.Pools, doc.Allocations, allocations, nil); err != nil {
+			return err
+		}
+
+		for i := range allocations {
+			allocCopy := allocations[i]
+			doc.Allocations[allocCopy.ID] = &allocCopy
+		}
+
+		return nil
+	})
+}
+
+func (abs *AzureBlobStorage) DeleteAllocations(ctx context.Context, ids []string) error {
+	return abs.update(ctx, func(doc *blobData) error {
+		for _, id := range ids {
+			delete(doc.Allocations, id)
+		}
+
+		return nil
+	})
+}
 
-	if _, exists := abs.data.Allocations[id]; !exists {
-		return ErrNotFound
+func (abs *AzureBlobStorage) Apply(ctx context.Context, batch *Batch) error {
+	return abs.update(ctx, func(doc *blobData) error {
+		auditLog, err := applyBatch(batch, doc.Pools, doc.Allocations, doc.Addresses, doc.AuditLog)
+		if err != nil {
+			return err
+		}
+		doc.AuditLog = auditLog
+
+		return nil
+	})
+}
+
+func (abs *AzureBlobStorage) GetAddress(ctx context.Context, id string) (*Address, error) {
+	if err := abs.refresh(ctx); err != nil {
+		return nil, err
 	}
 
-	delete(abs.data.Allocations, id)
-	return abs.save(ctx)
+	abs.mu.RLock()
+	defer abs.mu.RUnlock()
+
+	address, exists := abs.data.Addresses[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	// return copy
+	addressCopy := *address
+	return &addressCopy, nil
+}
+
+func (abs *AzureBlobStorage) ListAddresses(ctx context.Context) ([]Address, error) {
+	if err := abs.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	abs.mu.RLock()
+	defer abs.mu.RUnlock()
+
+	// return copies
+	addresses := make([]Address, 0, len(abs.data.Addresses))
+	for _, address := range abs.data.Addresses {
+		addresses = append(addresses, *address)
+	}
+
+	return addresses, nil
+}
+
+func (abs *AzureBlobStorage) ListAddressesByAllocation(ctx context.Context, allocationID string) ([]Address, error) {
+	if err := abs.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	abs.mu.RLock()
+	defer abs.mu.RUnlock()
+
+	addresses := make([]Address, 0)
+	for _, address := range abs.data.Addresses {
+		if address.AllocationID == allocationID {
+			addresses = append(addresses, *address)
+		}
+	}
+
+	return addresses, nil
+}
+
+func (abs *AzureBlobStorage) SaveAddress(ctx context.Context, address *Address) error {
+	return abs.update(ctx, func(doc *blobData) error {
+		if err := checkAddress(doc.Addresses, address); err != nil {
+			return err
+		}
+
+		// save a copy
+		addressCopy := *address
+		doc.Addresses[address.ID] = &addressCopy
+
+		return nil
+	})
+}
+
+func (abs *AzureBlobStorage) DeleteAddress(ctx context.Context, id string) error {
+	return abs.update(ctx, func(doc *blobData) error {
+		if _, exists := doc.Addresses[id]; !exists {
+			return ErrNotFound
+		}
+
+		delete(doc.Addresses, id)
+		return nil
+	})
+}
+
+func (abs *AzureBlobStorage) AppendAuditRecords(ctx context.Context, records []AuditRecord) error {
+	return abs.update(ctx, func(doc *blobData) error {
+		doc.AuditLog = append(doc.AuditLog, records...)
+		return nil
+	})
+}
+
+func (abs *AzureBlobStorage) ListAuditRecords(ctx context.Context) ([]AuditRecord, error) {
+	if err := abs.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	abs.mu.RLock()
+	defer abs.mu.RUnlock()
+
+	// return a copy
+	return append([]AuditRecord{}, abs.data.AuditLog...), nil
 }
 
 func (abs *AzureBlobStorage) Close() error {
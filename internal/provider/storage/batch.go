@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Batch is a set of changes that Apply writes to the storage document at once,
+// so either all of them are stored or none is.
+type Batch struct {
+	// DeleteAllocations and DeleteAddresses are removed first, IDs that don't
+	// exist are skipped.
+	DeleteAllocations []string
+	DeleteAddresses   []string
+
+	// CreateAllocations are new allocations. The batch fails with ErrExists if
+	// one of the IDs is still taken after the deletes.
+	CreateAllocations []Allocation
+
+	// SaveAllocations replace the allocations with the same ID. The batch
+	// fails with ErrOverlap if a created or saved allocation takes space that
+	// is still taken after the deletes.
+	SaveAllocations []Allocation
+
+	// SavePools replace the pools with the same name or add them. The batch
+	// fails with ErrOverlap if a saved child pool takes space of its parent
+	// that is still taken after the deletes.
+	SavePools []Pool
+
+	// SaveAddresses replace the addresses with the same ID or add them. The
+	// batch fails with ErrOverlap if another address of the same allocation
+	// holds a saved address.
+	SaveAddresses []Address
+
+	// Cursors move the sequential cursor of pools, by pool name. Pools that
+	// don't exist are skipped.
+	Cursors map[string]string
+
+	// DeleteLeases removes leases by their ID, by pool name. Pools and leases
+	// that don't exist are skipped.
+	DeleteLeases map[string][]string
+
+	// AuditRecords are appended to the audit log.
+	AuditRecords []AuditRecord
+}
+
+// applyBatch applies the batch to the maps of a storage document and returns the
+// new audit log. Conflicts are checked before anything is changed, so a failed
+// batch leaves the document as it was.
+func applyBatch(b *Batch, pools map[string]*Pool, allocations map[string]*Allocation, addresses map[string]*Address, auditLog []AuditRecord) ([]AuditRecord, error) {
+	deleted := make(map[string]bool, len(b.DeleteAllocations))
+	for _, id := range b.DeleteAllocations {
+		deleted[id] = true
+	}
+	created := make(map[string]bool, len(b.CreateAllocations))
+	for _, alloc := range b.CreateAllocations {
+		if _, exists := allocations[alloc.ID]; (exists && !deleted[alloc.ID]) || created[alloc.ID] {
+			return auditLog, fmt.Errorf("allocation %s: %w", alloc.ID, ErrExists)
+		}
+		created[alloc.ID] = true
+	}
+	saved := maps.Clone(pools)
+	for i := range b.SavePools {
+		if err := checkPool(saved, allocations, &b.SavePools[i], deleted); err != nil {
+			return auditLog, err
+		}
+		poolCopy := b.SavePools[i]
+		saved[poolCopy.Name] = &poolCopy
+	}
+	if err := checkAllocations(saved, allocations, append(slices.Clone(b.CreateAllocations), b.SaveAllocations...), deleted); err != nil {
+		return auditLog, err
+	}
+	savedAddresses := maps.Clone(addresses)
+	for _, id := range b.DeleteAddresses {
+		delete(savedAddresses, id)
+	}
+	for i := range b.SaveAddresses {
+		if err := checkAddress(savedAddresses, &b.SaveAddresses[i]); err != nil {
+			return auditLog, err
+		}
+		addressCopy := b.SaveAddresses[i]
+		savedAddresses[addressCopy.ID] = &addressCopy
+	}
+
+	for _, id := range b.DeleteAddresses {
+		delete(addresses, id)
+	}
+	for _, id := range b.DeleteAllocations {
+		delete(allocations, id)
+	}
+	for _, allocs := range [][]Allocation{b.CreateAllocations, b.SaveAllocations} {
+		for i := range allocs {
+			allocCopy := allocs[i]
+			allocations[allocCopy.ID] = &allocCopy
+		}
+	}
+	for i := range b.SavePools {
+		poolCopy := b.SavePools[i]
+		pools[poolCopy.Name] = &poolCopy
+	}
+	for i := range b.SaveAddresses {
+		addressCopy := b.SaveAddresses[i]
+		addresses[addressCopy.ID] = &addressCopy
+	}
+	for name, cursor := range b.Cursors {
+		if pool, exists := pools[name]; exists {
+			poolCopy := *pool
+			poolCopy.Cursor = cursor
+			pools[name] = &poolCopy
+		}
+	}
+	for name, ids := range b.DeleteLeases {
+		if pool, exists := pools[name]; exists {
+			poolCopy := *pool
+			poolCopy.Leases = slices.DeleteFunc(slices.Clone(pool.Leases), func(lease Lease) bool {
+				return slices.Contains(ids, lease.ID)
+			})
+			pools[name] = &poolCopy
+		}
+	}
+
+	return append(auditLog, b.AuditRecords...), nil
+}
+
+// Add appends the changes of other to the batch.
+func (b *Batch) Add(other *Batch) {
+	b.DeleteAllocations = append(b.DeleteAllocations, other.DeleteAllocations...)
+	b.DeleteAddresses = append(b.DeleteAddresses, other.DeleteAddresses...)
+	b.CreateAllocations = append(b.CreateAllocations, other.CreateAllocations...)
+	b.SaveAllocations = append(b.SaveAllocations, other.SaveAllocations...)
+	b.SavePools = append(b.SavePools, other.SavePools...)
+	b.SaveAddresses = append(b.SaveAddresses, other.SaveAddresses...)
+	for name, cursor := range other.Cursors {
+		b.SetCursor(name, cursor)
+	}
+	for name, ids := range other.DeleteLeases {
+		if b.DeleteLeases == nil {
+			b.DeleteLeases = make(map[string][]string)
+		}
+		b.DeleteLeases[name] = append(b.DeleteLeases[name], ids...)
+	}
+	b.AuditRecords = append(b.AuditRecords, other.AuditRecords...)
+}
+
+// SetCursor moves the sequential cursor of a pool.
+func (b *Batch) SetCursor(poolName, cursor string) {
+	if b.Cursors == nil {
+		b.Cursors = make(map[string]string)
+	}
+	b.Cursors[poolName] = cursor
+}
+
+// IsEmpty reports whether the batch holds no changes.
+func (b *Batch) IsEmpty() bool {
+	return len(b.DeleteAllocations) == 0 && len(b.DeleteAddresses) == 0 && len(b.CreateAllocations) == 0 &&
+		len(b.SaveAllocations) == 0 && len(b.SavePools) == 0 && len(b.SaveAddresses) == 0 &&
+		len(b.Cursors) == 0 && len(b.DeleteLeases) == 0 && len(b.AuditRecords) == 0
+}
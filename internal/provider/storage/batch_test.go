@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	fs := newTestFileStorage(t, testSnapshot())
+
+	err := fs.Apply(ctx, &Batch{
+		DeleteAllocations: []string{"db", "missing"},
+		DeleteAddresses:   []string{"web-gw"},
+		CreateAllocations: []Allocation{{ID: "db", PoolName: "prod", AllocatedCIDR: "10.0.2.0/24", PrefixLength: 24}},
+		SaveAllocations:   []Allocation{{ID: "web", PoolName: "prod", AllocatedCIDR: "10.0.0.0/24", PrefixLength: 24, Orphaned: true}},
+		Cursors:           map[string]string{"prod": "10.0.2.0/24", "missing": "10.9.0.0/24"},
+		AuditRecords:      []AuditRecord{{Operation: "test"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if db, _ := fs.GetAllocation(ctx, "db"); db == nil || db.AllocatedCIDR != "10.0.2.0/24" {
+		t.Errorf("expected db to be created again after it was deleted, got %+v", db)
+	}
+	if web, _ := fs.GetAllocation(ctx, "web"); web == nil || !web.Orphaned {
+		t.Errorf("expected web to be saved, got %+v", web)
+	}
+	if _, err := fs.GetAddress(ctx, "web-gw"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the address to be deleted, got %v", err)
+	}
+	if pool, _ := fs.GetPool(ctx, "prod"); pool.Cursor != "10.0.2.0/24" {
+		t.Errorf("expected the cursor to move, got %q", pool.Cursor)
+	}
+	if _, err := fs.GetPool(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the cursor of a missing pool to be skipped, got %v", err)
+	}
+	if records, _ := fs.ListAuditRecords(ctx); len(records) != 1 {
+		t.Errorf("expected one audit record, got %v", records)
+	}
+}
+
+func TestBatchExists(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		batch *Batch
+	}{
+		{"existing allocation", &Batch{
+			CreateAllocations: []Allocation{{ID: "web", PoolName: "prod", AllocatedCIDR: "10.0.5.0/24", PrefixLength: 24}},
+		}},
+		{"created twice", &Batch{
+			CreateAllocations: []Allocation{
+				{ID: "new", PoolName: "prod", AllocatedCIDR: "10.0.5.0/24", PrefixLength: 24},
+				{ID: "new", PoolName: "prod", AllocatedCIDR: "10.0.6.0/24", PrefixLength: 24},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		fs := newTestFileStorage(t, testSnapshot())
+		before, _ := TakeSnapshot(ctx, fs)
+
+		// the other changes of a failed batch are not applied either
+		tt.batch.DeleteAllocations = []string{"db"}
+		tt.batch.AuditRecords = []AuditRecord{{Operation: "test"}}
+		if err := fs.Apply(ctx, tt.batch); !errors.Is(err, ErrExists) {
+			t.Errorf("%s: expected ErrExists, got %v", tt.name, err)
+		}
+
+		after, _ := TakeSnapshot(ctx, fs)
+		if !reflect.DeepEqual(before, after) {
+			t.Errorf("%s: expected storage to be unchanged, got %+v", tt.name, after)
+		}
+		if records, _ := fs.ListAuditRecords(ctx); len(records) != 0 {
+			t.Errorf("%s: expected no audit records, got %v", tt.name, records)
+		}
+	}
+}
+
+func TestBatchAdd(t *testing.T) {
+	b := &Batch{}
+	if !b.IsEmpty() {
+		t.Fatal("expected a new batch to be empty")
+	}
+
+	b.SetCursor("prod", "10.0.0.0/24")
+	b.Add(&Batch{
+		DeleteAllocations: []string{"web"},
+		Cursors:           map[string]string{"dev": "172.16.0.0/24"},
+		AuditRecords:      []AuditRecord{{Operation: "reclaim"}},
+	})
+	want := &Batch{
+		DeleteAllocations: []string{"web"},
+		Cursors:           map[string]string{"prod": "10.0.0.0/24", "dev": "172.16.0.0/24"},
+		AuditRecords:      []AuditRecord{{Operation: "reclaim"}},
+	}
+	if !reflect.DeepEqual(b, want) || b.IsEmpty() {
+		t.Errorf("expected %+v, got %+v", want, b)
+	}
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// maxWriteAttempts is how often a remote backend applies a write to a freshly
+// loaded document when the remote one changed between reading and writing it.
+const maxWriteAttempts = 3
+
+// documentCache tracks the freshness of a storage document that lives in a
+// remote backend. Reads are served from memory while the TTL is valid, after
+// which the backend compares the remote ETag and only downloads the document
+// again when it changed.
+type documentCache struct {
+	backend  string
+	ttl      time.Duration
+	etag     string
+	loadedAt time.Time
+	hits     uint64
+	misses   uint64
+}
+
+func newDocumentCache(backend string, ttl time.Duration) *documentCache {
+	return &documentCache{
+		backend: backend,
+		ttl:     ttl,
+	}
+}
+
+// valid reports whether the cached document can be used without checking the remote.
+func (c *documentCache) valid() bool {
+	if c.ttl <= 0 || c.loadedAt.IsZero() {
+		return false
+	}
+	return time.Since(c.loadedAt) < c.ttl
+}
+
+// matches reports whether the remote etag is the same as the cached one.
+func (c *documentCache) matches(etag string) bool {
+	return c.etag != "" && c.etag == etag
+}
+
+// update records a freshly loaded or saved document version.
+func (c *documentCache) update(etag string) {
+	c.etag = etag
+	c.loadedAt = time.Now()
+}
+
+// invalidate forgets the cached version, so the next revalidation downloads the
+// document again if it exists.
+func (c *documentCache) invalidate() {
+	c.etag = ""
+	c.loadedAt = time.Time{}
+}
+
+// touch extends the TTL of the cached document after a successful revalidation.
+func (c *documentCache) touch() {
+	c.loadedAt = time.Now()
+}
+
+func (c *documentCache) hit(ctx context.Context, reason string) {
+	c.hits++
+	tflog.Debug(ctx, "Storage cache hit", c.fields(reason))
+}
+
+func (c *documentCache) miss(ctx context.Context, reason string) {
+	c.misses++
+	tflog.Debug(ctx, "Storage cache miss", c.fields(reason))
+}
+
+func (c *documentCache) fields(reason string) map[string]any {
+	return map[string]any{
+		"backend": c.backend,
+		"reason":  reason,
+		"etag":    c.etag,
+		"hits":    c.hits,
+		"misses":  c.misses,
+	}
+}
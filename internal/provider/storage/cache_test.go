@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDocumentCacheValid(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		loadedAt time.Duration // before now, negative for never loaded
+		want     bool
+	}{
+		{"never loaded", time.Minute, -1, false},
+		{"zero ttl", 0, 0, false},
+		{"negative ttl", -time.Minute, 0, false},
+		{"within ttl", time.Minute, 30 * time.Second, true},
+		{"ttl expired", time.Minute, 2 * time.Minute, false},
+		{"exactly at ttl", time.Minute, time.Minute, false},
+	}
+	for _, tt := range tests {
+		c := newDocumentCache("test", tt.ttl)
+		if tt.loadedAt >= 0 {
+			c.update("etag")
+			c.loadedAt = time.Now().Add(-tt.loadedAt)
+		}
+		if got := c.valid(); got != tt.want {
+			t.Errorf("%s: valid() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDocumentCacheMatches(t *testing.T) {
+	tests := []struct {
+		cached, remote string
+		want           bool
+	}{
+		{`"v1"`, `"v1"`, true},
+		{`"v1"`, `"v2"`, false},
+		{"", "", false},
+		{"", `"v1"`, false},
+	}
+	for _, tt := range tests {
+		c := newDocumentCache("test", time.Minute)
+		c.update(tt.cached)
+		if got := c.matches(tt.remote); got != tt.want {
+			t.Errorf("matches(%q) with %q cached = %v, want %v", tt.remote, tt.cached, got, tt.want)
+		}
+	}
+}
+
+func TestDocumentCacheExpiry(t *testing.T) {
+	c := newDocumentCache("test", time.Minute)
+	c.update(`"v1"`)
+	if !c.valid() {
+		t.Fatal("expected a freshly loaded document to be valid")
+	}
+
+	// a revalidation that found the same etag extends the ttl
+	c.loadedAt = time.Now().Add(-2 * time.Minute)
+	if c.valid() {
+		t.Fatal("expected the document to expire after the ttl")
+	}
+	c.touch()
+	if !c.valid() || !c.matches(`"v1"`) {
+		t.Fatal("expected touch to extend the ttl and keep the etag")
+	}
+
+	c.invalidate()
+	if c.valid() || c.matches(`"v1"`) {
+		t.Fatal("expected an invalidated cache to need a download")
+	}
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AuditCompact is the audit log operation of entries removed by Compact.
+const AuditCompact = "compact"
+
+// CompactOptions controls what Compact removes.
+type CompactOptions struct {
+	// Now is the time leases are expired at, the zero time means the current time.
+	Now time.Time
+
+	// DryRun only returns the entries that would be removed without removing them.
+	DryRun bool
+}
+
+// CompactResult lists the entries Compact removed, or would remove in a dry run.
+type CompactResult struct {
+	// Addresses whose allocation no longer exists
+	Addresses []Address `json:"addresses,omitempty"`
+
+	// Allocations whose pool no longer exists
+	Allocations []Allocation `json:"allocations,omitempty"`
+
+	// Leases that expired, by pool name
+	Leases map[string][]Lease `json:"leases,omitempty"`
+}
+
+// Count returns the number of removed entries.
+func (r *CompactResult) Count() int {
+	n := len(r.Addresses) + len(r.Allocations)
+	for _, leases := range r.Leases {
+		n += len(leases)
+	}
+	return n
+}
+
+func (r *CompactResult) String() string {
+	leases := 0
+	for _, l := range r.Leases {
+		leases += len(l)
+	}
+	return fmt.Sprintf("%d orphaned address(es), %d orphaned allocation(s) and %d expired lease(s)",
+		len(r.Addresses), len(r.Allocations), leases)
+}
+
+// Compact removes entries nothing can reference anymore: addresses of missing
+// allocations, allocations of missing pools together with their addresses, and
+// expired leases. Allocations marked orphaned because
+// their pool CIDRs changed are still managed by Terraform and are kept.
+// Unless DryRun is set, each removed allocation and address gets an audit
+// record, and a summary record is always appended so the storage document is
+// rewritten even when there was nothing to remove. The removals and the audit
+// records are written to storage at once.
+func Compact(ctx context.Context, s Storage, opts CompactOptions) (*CompactResult, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	snap, err := TakeSnapshot(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CompactResult{
+		Leases: make(map[string][]Lease),
+	}
+
+	removedAllocations := make(map[string]bool)
+	for _, alloc := range snap.Allocations {
+		if snap.pool(alloc.PoolName) == nil {
+			result.Allocations = append(result.Allocations, alloc)
+			removedAllocations[alloc.ID] = true
+		}
+	}
+	for _, address := range snap.Addresses {
+		if removedAllocations[address.AllocationID] || snap.allocation(address.AllocationID) == nil {
+			result.Addresses = append(result.Addresses, address)
+		}
+	}
+
+	// expired leases can't be renewed, so removing them by ID is safe
+	batch := &Batch{DeleteLeases: make(map[string][]string)}
+	for _, pool := range snap.Pools {
+		for _, lease := range pool.Leases {
+			if !lease.ExpiresAt.After(now) {
+				result.Leases[pool.Name] = append(result.Leases[pool.Name], lease)
+				batch.DeleteLeases[pool.Name] = append(batch.DeleteLeases[pool.Name], lease.ID)
+			}
+		}
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for _, address := range result.Addresses {
+		batch.DeleteAddresses = append(batch.DeleteAddresses, address.ID)
+		batch.AuditRecords = append(batch.AuditRecords, AuditRecord{
+			Time:         now.UTC(),
+			Operation:    AuditCompact,
+			AllocationID: address.AllocationID,
+			CIDR:         address.Address,
+			Message:      fmt.Sprintf("removed address %s of missing allocation", address.ID),
+		})
+	}
+	for _, alloc := range result.Allocations {
+		batch.DeleteAllocations = append(batch.DeleteAllocations, alloc.ID)
+		batch.AuditRecords = append(batch.AuditRecords, AuditRecord{
+			Time:         now.UTC(),
+			Operation:    AuditCompact,
+			PoolName:     alloc.PoolName,
+			AllocationID: alloc.ID,
+			CIDR:         alloc.AllocatedCIDR,
+			Message:      "removed allocation of missing pool",
+		})
+	}
+	batch.AuditRecords = append(batch.AuditRecords, AuditRecord{
+		Time:      now.UTC(),
+		Operation: AuditCompact,
+		Message:   "removed " + result.String(),
+	})
+
+	if err := s.Apply(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to compact storage: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCompactSingleWrite(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	f := &fakeS3{}
+	s := newTestS3Storage(t, f)
+	pool := Pool{Name: "prod", CIDRs: []string{"10.0.0.0/16"}, Leases: []Lease{
+		{ID: "expired", CIDR: "10.0.5.0/24", ExpiresAt: now.Add(-time.Hour)},
+		{ID: "live", CIDR: "10.0.6.0/24", ExpiresAt: now.Add(time.Hour)},
+	}}
+	s.SavePool(ctx, &pool)
+	s.SaveAllocation(ctx, &Allocation{ID: "lost", PoolName: "gone", AllocatedCIDR: "10.0.1.0/24", PrefixLength: 24})
+	s.SaveAddress(ctx, &Address{ID: "lost-host", AllocationID: "lost", Address: "10.0.1.10"})
+
+	// another writer moves the cursor while compacting, which must be kept
+	f.beforePut = func(f *fakeS3) {
+		f.beforePut = nil
+		doc := newS3Data()
+		json.Unmarshal(f.body, doc)
+		doc.Pools["prod"].Cursor = "10.0.7.0/24"
+		body, _ := json.Marshal(doc)
+		f.write(body)
+	}
+	puts := f.puts
+	result, err := Compact(ctx, s, CompactOptions{Now: now})
+	if err != nil || result.Count() != 3 {
+		t.Fatalf("expected 3 removed entries, got %v (%v)", result, err)
+	}
+	if f.puts-puts != 2 {
+		t.Errorf("expected a single write retried once, got %d uploads", f.puts-puts)
+	}
+
+	snap, _ := TakeSnapshot(ctx, s)
+	if len(snap.Allocations) != 0 || len(snap.Addresses) != 0 {
+		t.Errorf("expected the orphaned entries to be removed, got %+v", snap)
+	}
+	if got := snap.pool("prod"); len(got.Leases) != 1 || got.Leases[0].ID != "live" || got.Cursor != "10.0.7.0/24" {
+		t.Errorf("expected the live lease and the other writer's cursor, got %+v", got)
+	}
+	if records, _ := s.ListAuditRecords(ctx); len(records) != 3 {
+		t.Errorf("expected 3 audit records, got %v", records)
+	}
+}
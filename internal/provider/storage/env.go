@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Environment variables that configure a storage backend. They mirror the
+// provider configuration attributes with a TFIPAM_ prefix.
+const (
+	EnvStorageType           = "TFIPAM_STORAGE_TYPE"
+	EnvFilePath              = "TFIPAM_FILE_PATH"
+	EnvAzureConnectionString = "TFIPAM_AZURE_CONNECTION_STRING"
+	EnvAzureContainerName    = "TFIPAM_AZURE_CONTAINER_NAME"
+	EnvAzureBlobName         = "TFIPAM_AZURE_BLOB_NAME"
+	EnvS3Region              = "TFIPAM_S3_REGION"
+	EnvS3BucketName          = "TFIPAM_S3_BUCKET_NAME"
+	EnvS3ObjectKey           = "TFIPAM_S3_OBJECT_KEY"
+	EnvS3AccessKeyID         = "TFIPAM_S3_ACCESS_KEY_ID"
+	EnvS3SecretAccessKey     = "TFIPAM_S3_SECRET_ACCESS_KEY"
+	EnvS3SessionToken        = "TFIPAM_S3_SESSION_TOKEN"
+	EnvCacheTTL              = "TFIPAM_CACHE_TTL"
+)
+
+// ApplyEnv fills every field of the config that is still empty from its
+// TFIPAM_* environment variable, so explicitly set values take precedence.
+func (c *Config) ApplyEnv() error {
+	fields := []struct {
+		value *string
+		env   string
+	}{
+		{&c.Type, EnvStorageType},
+		{&c.FilePath, EnvFilePath},
+		{&c.AzureConnectionString, EnvAzureConnectionString},
+		{&c.AzureContainerName, EnvAzureContainerName},
+		{&c.AzureBlobName, EnvAzureBlobName},
+		{&c.S3Region, EnvS3Region},
+		{&c.S3BucketName, EnvS3BucketName},
+		{&c.S3ObjectKey, EnvS3ObjectKey},
+		{&c.S3AccessKeyID, EnvS3AccessKeyID},
+		{&c.S3SecretAccessKey, EnvS3SecretAccessKey},
+		{&c.S3SessionToken, EnvS3SessionToken},
+	}
+	for _, f := range fields {
+		if *f.value == "" {
+			*f.value = os.Getenv(f.env)
+		}
+	}
+
+	if v := os.Getenv(EnvCacheTTL); c.CacheTTL == 0 && v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl < 0 {
+			return fmt.Errorf("%s must be a non-negative duration such as '30s' or '5m', got '%s'", EnvCacheTTL, v)
+		}
+		c.CacheTTL = ttl
+	}
+
+	return nil
+}
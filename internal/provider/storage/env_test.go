@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		config  Config
+		want    Config
+		wantErr string
+	}{
+		{
+			name: "empty",
+			want: Config{},
+		},
+		{
+			name: "fills empty fields",
+			env:  map[string]string{EnvStorageType: "aws_s3", EnvS3Region: "eu-west-1", EnvS3BucketName: "ipam", EnvCacheTTL: "30s"},
+			want: Config{Type: "aws_s3", S3Region: "eu-west-1", S3BucketName: "ipam", CacheTTL: 30 * time.Second},
+		},
+		{
+			name:   "set fields take precedence",
+			env:    map[string]string{EnvStorageType: "aws_s3", EnvFilePath: "env.json", EnvAzureBlobName: "env.json", EnvCacheTTL: "30s"},
+			config: Config{Type: "file", FilePath: "ipam.json", CacheTTL: time.Minute},
+			want:   Config{Type: "file", FilePath: "ipam.json", AzureBlobName: "env.json", CacheTTL: time.Minute},
+		},
+		{
+			name: "credentials",
+			env:  map[string]string{EnvAzureConnectionString: "conn", EnvS3AccessKeyID: "id", EnvS3SecretAccessKey: "secret", EnvS3SessionToken: "token"},
+			want: Config{AzureConnectionString: "conn", S3AccessKeyID: "id", S3SecretAccessKey: "secret", S3SessionToken: "token"},
+		},
+		{
+			name:    "invalid cache ttl",
+			env:     map[string]string{EnvCacheTTL: "soon"},
+			wantErr: EnvCacheTTL + " must be a non-negative duration",
+		},
+		{
+			name:    "negative cache ttl",
+			env:     map[string]string{EnvCacheTTL: "-5m"},
+			wantErr: EnvCacheTTL + " must be a non-negative duration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{EnvStorageType, EnvFilePath, EnvAzureConnectionString, EnvAzureContainerName, EnvAzureBlobName,
+				EnvS3Region, EnvS3BucketName, EnvS3ObjectKey, EnvS3AccessKeyID, EnvS3SecretAccessKey, EnvS3SessionToken, EnvCacheTTL} {
+				t.Setenv(env, tt.env[env])
+			}
+
+			config := tt.config
+			err := config.ApplyEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config != tt.want {
+				t.Errorf("got %+v, want %+v", config, tt.want)
+			}
+		})
+	}
+}
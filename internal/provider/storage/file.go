@@ -18,6 +18,8 @@ type FileStorage struct {
 type fileData struct {
 	Pools       map[string]*Pool       `json:"pools"`
 	Allocations map[string]*Allocation `json:"allocations"`
+	Addresses   map[string]*Address    `json:"addresses"`
+	AuditLog    []AuditRecord          `json:"audit_log,omitempty"`
 }
 
 // Most methods make copies of data to avoid external mutation issues
@@ -41,6 +43,7 @@ func NewFileStorage(filePath string) (*FileStorage, error) {
 		data: &fileData{
 			Pools:       make(map[string]*Pool),
 			Allocations: make(map[string]*Allocation),
+			Addresses:   make(map[string]*Address),
 		},
 	}
 
@@ -121,6 +124,10 @@ func (fs *FileStorage) SavePool(ctx context.Context, pool *Pool) error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
+	if err := checkPool(fs.data.Pools, fs.data.Allocations, pool, nil); err != nil {
+		return err
+	}
+
 	// make a copy to store
 	poolCopy := *pool
 	fs.data.Pools[pool.Name] = &poolCopy
@@ -185,6 +192,10 @@ func (fs *FileStorage) SaveAllocation(ctx context.Context, allocation *Allocatio
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
+	if err := checkAllocations(fs.data.Pools, fs.data.Allocations, []Allocation{*allocation}, nil); err != nil {
+		return err
+	}
+
 	allocCopy := *allocation
 	fs.data.Allocations[allocation.ID] = &allocCopy
 
@@ -203,6 +214,129 @@ func (fs *FileStorage) DeleteAllocation(ctx context.Context, id string) error {
 	return fs.save()
 }
 
+func (fs *FileStorage) SaveAllocations(ctx context.Context, allocations []Allocation) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := checkAllocations(fs.data.Pools, fs.data.Allocations, allocations, nil); err != nil {
+		return err
+	}
+
+	for i := range allocations {
+		allocCopy := allocations[i]
+		fs.data.Allocations[allocCopy.ID] = &allocCopy
+	}
+
+	return fs.save()
+}
+
+func (fs *FileStorage) DeleteAllocations(ctx context.Context, ids []string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	for _, id := range ids {
+		delete(fs.data.Allocations, id)
+	}
+
+	return fs.save()
+}
+
+func (fs *FileStorage) Apply(ctx context.Context, batch *Batch) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	auditLog, err := applyBatch(batch, fs.data.Pools, fs.data.Allocations, fs.data.Addresses, fs.data.AuditLog)
+	if err != nil {
+		return err
+	}
+	fs.data.AuditLog = auditLog
+
+	return fs.save()
+}
+
+func (fs *FileStorage) GetAddress(ctx context.Context, id string) (*Address, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	address, exists := fs.data.Addresses[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	// return copy
+	addressCopy := *address
+	return &addressCopy, nil
+}
+
+func (fs *FileStorage) ListAddresses(ctx context.Context) ([]Address, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	// return copies
+	addresses := make([]Address, 0, len(fs.data.Addresses))
+	for _, address := range fs.data.Addresses {
+		addresses = append(addresses, *address)
+	}
+
+	return addresses, nil
+}
+
+func (fs *FileStorage) ListAddressesByAllocation(ctx context.Context, allocationID string) ([]Address, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	addresses := make([]Address, 0)
+	for _, address := range fs.data.Addresses {
+		if address.AllocationID == allocationID {
+			addresses = append(addresses, *address)
+		}
+	}
+
+	return addresses, nil
+}
+
+func (fs *FileStorage) SaveAddress(ctx context.Context, address *Address) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := checkAddress(fs.data.Addresses, address); err != nil {
+		return err
+	}
+
+	addressCopy := *address
+	fs.data.Addresses[address.ID] = &addressCopy
+
+	return fs.save()
+}
+
+func (fs *FileStorage) DeleteAddress(ctx context.Context, id string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, exists := fs.data.Addresses[id]; !exists {
+		return ErrNotFound
+	}
+
+	delete(fs.data.Addresses, id)
+	return fs.save()
+}
+
+func (fs *FileStorage) AppendAuditRecords(ctx context.Context, records []AuditRecord) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.data.AuditLog = append(fs.data.AuditLog, records...)
+	return fs.save()
+}
+
+func (fs *FileStorage) ListAuditRecords(ctx context.Context) ([]AuditRecord, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	// return a copy
+	return append([]AuditRecord{}, fs.data.AuditLog...), nil
+}
+
 func (fs *FileStorage) Close() error {
 	// file storage doesn't need any cleanup
 	return nil
@@ -3,15 +3,67 @@ package storage
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 var (
 	ErrNotFound = errors.New("not found")
+
+	// ErrExists is returned when a batch creates an allocation whose ID is taken.
+	ErrExists = errors.New("already exists")
+
+	// ErrOverlap is returned by the remote backends when a write would take
+	// space that another writer took since the space was read.
+	ErrOverlap = errors.New("overlaps space that is already taken")
+
+	// ErrConflict is returned by the remote backends when the storage document
+	// kept changing while a write was retried.
+	ErrConflict = errors.New("storage document was changed concurrently")
 )
 
+// Metadata describes what a pool or allocation is for. It has no effect on allocation.
+type Metadata struct {
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
 type Pool struct {
 	Name  string   `json:"name"`
 	CIDRs []string `json:"cidrs"`
+
+	Metadata
+
+	// ParentPool is the pool the CIDRs were allocated from, empty for a top-level pool
+	ParentPool string `json:"parent_pool,omitempty"`
+
+	// ReservedCIDRs are ranges within CIDRs that are never handed out to allocations
+	ReservedCIDRs []string `json:"reserved_cidrs,omitempty"`
+
+	// GatewayOffset is where allocations from the pool put their gateway, nil means the first usable address
+	GatewayOffset *int64 `json:"gateway_offset,omitempty"`
+
+	// ReservedOffsets are addresses of every allocation from the pool that are never handed out as host addresses
+	ReservedOffsets []int64 `json:"reserved_offsets,omitempty"`
+
+	// AllocationStrategy is the default strategy for allocations from this pool, empty means first_fit
+	AllocationStrategy string `json:"allocation_strategy,omitempty"`
+
+	// Cursor is the CIDR allocated last, where the sequential strategy continues
+	Cursor string `json:"cursor,omitempty"`
+
+	// Leases are CIDRs held by ephemeral leases, free again once they expire
+	Leases []Lease `json:"leases,omitempty"`
+
+	// ReclaimExpired makes the CIDRs of expired allocations available to new allocations
+	ReclaimExpired bool `json:"reclaim_expired,omitempty"`
+}
+
+// Lease holds a CIDR for an ephemeral lease until it is closed or expires.
+type Lease struct {
+	ID        string    `json:"id"`
+	CIDR      string    `json:"cidr"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 type Allocation struct {
@@ -19,6 +71,43 @@ type Allocation struct {
 	PoolName      string `json:"pool_name"`
 	AllocatedCIDR string `json:"allocated_cidr"`
 	PrefixLength  int    `json:"prefix_length"`
+
+	Metadata
+
+	// Orphaned is set when the pool CIDRs were changed so they no longer contain the allocation
+	Orphaned bool `json:"orphaned,omitempty"`
+
+	// ExpiresAt is when the allocation may be reclaimed, nil if it never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Blocks are the contiguous CIDRs of an allocation group within AllocatedCIDR, in the requested order
+	Blocks []string `json:"blocks,omitempty"`
+
+	// AllocationSet is the ID of the allocation set this allocation is a member of, empty for other allocations
+	AllocationSet string `json:"allocation_set,omitempty"`
+}
+
+// Expired reports whether the allocation has an expiry that is not after now.
+func (a *Allocation) Expired(now time.Time) bool {
+	return a.ExpiresAt != nil && !a.ExpiresAt.After(now)
+}
+
+// Address is a single host address taken from an allocation.
+type Address struct {
+	ID           string `json:"id"`
+	AllocationID string `json:"allocation_id"`
+	Address      string `json:"address"`
+}
+
+// AuditRecord describes a change made to storage by a maintenance operation
+// rather than by a resource.
+type AuditRecord struct {
+	Time         time.Time `json:"time"`
+	Operation    string    `json:"operation"`
+	PoolName     string    `json:"pool_name,omitempty"`
+	AllocationID string    `json:"allocation_id,omitempty"`
+	CIDR         string    `json:"cidr,omitempty"`
+	Message      string    `json:"message,omitempty"`
 }
 
 type Storage interface {
@@ -35,6 +124,25 @@ type Storage interface {
 	SaveAllocation(ctx context.Context, allocation *Allocation) error
 	DeleteAllocation(ctx context.Context, id string) error
 
+	// batch allocation operations write the document once for all allocations.
+	// DeleteAllocations skips IDs that don't exist.
+	SaveAllocations(ctx context.Context, allocations []Allocation) error
+	DeleteAllocations(ctx context.Context, ids []string) error
+
+	// Apply writes all changes of the batch to the document at once.
+	Apply(ctx context.Context, batch *Batch) error
+
+	// address operations
+	GetAddress(ctx context.Context, id string) (*Address, error)
+	ListAddresses(ctx context.Context) ([]Address, error)
+	ListAddressesByAllocation(ctx context.Context, allocationID string) ([]Address, error)
+	SaveAddress(ctx context.Context, address *Address) error
+	DeleteAddress(ctx context.Context, id string) error
+
+	// audit log operations
+	AppendAuditRecords(ctx context.Context, records []AuditRecord) error
+	ListAuditRecords(ctx context.Context) ([]AuditRecord, error)
+
 	Close() error
 }
 
@@ -56,6 +164,9 @@ type Config struct {
 	S3AccessKeyID     string // Optional: uses default credential chain if empty
 	S3SecretAccessKey string // Optional: required if S3AccessKeyID is provided
 	S3SessionToken    string // Optional: for temporary credentials
+
+	// Cache config for the remote backends. Zero revalidates the document on every read.
+	CacheTTL time.Duration
 }
 
 func Factory(ctx context.Context, config *Config) (Storage, error) {
@@ -63,10 +174,10 @@ func Factory(ctx context.Context, config *Config) (Storage, error) {
 	case "file", "": // default to file
 		return NewFileStorage(config.FilePath)
 	case "azure_blob":
-		return NewAzureBlobStorage(config.AzureConnectionString, config.AzureContainerName, config.AzureBlobName)
+		return NewAzureBlobStorage(config.AzureConnectionString, config.AzureContainerName, config.AzureBlobName, config.CacheTTL)
 	case "aws_s3":
 		return NewS3Storage(config.S3Region, config.S3BucketName, config.S3ObjectKey,
-			config.S3AccessKeyID, config.S3SecretAccessKey, config.S3SessionToken)
+			config.S3AccessKeyID, config.S3SecretAccessKey, config.S3SessionToken, config.CacheTTL)
 	default:
 		return nil, errors.New("unknown storage type")
 	}
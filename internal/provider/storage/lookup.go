@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"net/netip"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+)
+
+// Status of a looked up IP or CIDR.
+const (
+	LookupAllocated = "allocated"
+	LookupReserved  = "reserved"
+	LookupPartial   = "partial"
+	LookupFree      = "free"
+	LookupUnmanaged = "unmanaged"
+)
+
+// LookupResult describes what holds an IP or CIDR.
+type LookupResult struct {
+	CIDR string `json:"cidr"`
+
+	// Status is allocated if an allocation covers the CIDR, reserved if a reserved
+	// range of a pool covers it, partial if it overlaps allocations or reserved
+	// ranges without lying within one, free if it lies within a pool and overlaps
+	// neither, and unmanaged if no pool contains it.
+	Status string `json:"status"`
+
+	// Pools are the pools with a CIDR containing the CIDR, in snapshot order.
+	Pools []Pool `json:"pools"`
+
+	// Allocation is the most specific allocation covering the CIDR.
+	Allocation *Allocation `json:"allocation,omitempty"`
+
+	// Address is the address record holding a single looked up address.
+	Address *Address `json:"address,omitempty"`
+
+	// Overlapping are the allocations overlapping a partial CIDR, sorted by ID.
+	Overlapping []Allocation `json:"overlapping,omitempty"`
+}
+
+// Lookup finds the pools, allocation and address holding the CIDR.
+func Lookup(snap *Snapshot, cidr netip.Prefix) LookupResult {
+	result := LookupResult{CIDR: cidr.String(), Status: LookupUnmanaged, Pools: []Pool{}}
+
+	reserved, partial := false, false
+	for _, pool := range snap.Pools {
+		if !anyWithin(cidr, pool.CIDRs) {
+			continue
+		}
+		result.Pools = append(result.Pools, pool)
+
+		for _, r := range pool.ReservedCIDRs {
+			prefix, err := netip.ParsePrefix(r)
+			if err != nil {
+				continue
+			}
+			if ipam.Contains(prefix, cidr) {
+				reserved = true
+			} else if prefix.Overlaps(cidr) {
+				partial = true
+			}
+		}
+	}
+
+	for i, alloc := range snap.Allocations {
+		prefix, err := netip.ParsePrefix(alloc.AllocatedCIDR)
+		if err != nil || !prefix.Overlaps(cidr) {
+			continue
+		}
+		if !ipam.Contains(prefix, cidr) {
+			result.Overlapping = append(result.Overlapping, alloc)
+			continue
+		}
+		// allocations are sorted by ID, so ties keep the lowest ID
+		if result.Allocation == nil || prefix.Bits() > netip.MustParsePrefix(result.Allocation.AllocatedCIDR).Bits() {
+			result.Allocation = &snap.Allocations[i]
+		}
+	}
+
+	switch {
+	case result.Allocation != nil:
+		result.Status = LookupAllocated
+		result.Overlapping = nil
+	case reserved:
+		result.Status = LookupReserved
+	case partial || len(result.Overlapping) > 0:
+		result.Status = LookupPartial
+	case len(result.Pools) > 0:
+		result.Status = LookupFree
+	}
+
+	if result.Allocation != nil && cidr.IsSingleIP() {
+		for i, address := range snap.Addresses {
+			addr, err := netip.ParseAddr(address.Address)
+			if err == nil && address.AllocationID == result.Allocation.ID && addr == cidr.Addr() {
+				result.Address = &snap.Addresses[i]
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// anyWithin reports whether the CIDR lies within any of the CIDRs.
+func anyWithin(cidr netip.Prefix, cidrs []string) bool {
+	for _, c := range cidrs {
+		prefix, err := netip.ParsePrefix(c)
+		if err == nil && ipam.Contains(prefix.Masked(), cidr) {
+			return true
+		}
+	}
+	return false
+}
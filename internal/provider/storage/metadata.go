@@ -0,0 +1,11 @@
+package storage
+
+// HasTags reports whether the metadata has every one of the tags with the same value.
+func (m Metadata) HasTags(tags map[string]string) bool {
+	for key, value := range tags {
+		if v, ok := m.Tags[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// MigrateOptions controls how Migrate copies data between backends.
+type MigrateOptions struct {
+	// DryRun only computes the changes without writing to the destination.
+	DryRun bool
+
+	// Overwrite migrates into a destination that already holds data, replacing
+	// pools, allocations and addresses with different content. Without it a
+	// destination that isn't empty is refused, and items with different content
+	// are reported as conflicts.
+	Overwrite bool
+}
+
+type ChangeAction string
+
+const (
+	ChangeCreate    ChangeAction = "create"
+	ChangeUpdate    ChangeAction = "update"
+	ChangeUnchanged ChangeAction = "unchanged"
+	ChangeConflict  ChangeAction = "conflict"
+)
+
+// Change describes what a migration does with a single pool, allocation or address.
+type Change struct {
+	Kind   string       `json:"kind"` // "pool", "allocation" or "address"
+	Key    string       `json:"key"`  // pool name, allocation ID or address ID
+	Action ChangeAction `json:"action"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Key)
+}
+
+// MigrationResult reports the outcome of a migration.
+type MigrationResult struct {
+	Changes []Change `json:"changes"`
+	Issues  []Issue  `json:"issues,omitempty"`
+
+	// AuditRecords is the number of audit records of the source that are
+	// appended to the audit log of the destination.
+	AuditRecords int `json:"audit_records"`
+
+	// Applied is set once all changes were written to the destination and
+	// Verified once the destination was read back and matched the source.
+	Applied  bool `json:"applied"`
+	Verified bool `json:"verified"`
+}
+
+// Count returns the number of changes with the given action.
+func (r *MigrationResult) Count(action ChangeAction) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Migrate copies all pools, allocations and addresses from src to dst. The source is
+// validated first, and so is the destination as it would look after the
+// migration, so a migration never leaves the destination in a state the
+// provider can't work with. Everything is written to the destination in a single
+// batch, so a failed migration writes nothing. A destination that already holds
+// pools, allocations or addresses is only written with overwrite, which keeps
+// the data that only exists in the destination. Audit records of the source that
+// the destination doesn't have yet are appended to its audit log. After writing,
+// the destination is read back and compared with the source.
+func Migrate(ctx context.Context, src, dst Storage, opts MigrateOptions) (*MigrationResult, error) {
+	source, err := TakeSnapshot(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source storage: %w", err)
+	}
+
+	result := &MigrationResult{Issues: Verify(source)}
+	if len(result.Issues) > 0 {
+		return result, fmt.Errorf("source storage failed validation with %d issue(s)", len(result.Issues))
+	}
+
+	dest, err := TakeSnapshot(ctx, dst)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destination storage: %w", err)
+	}
+
+	result.Changes = diffSnapshots(source, dest, opts.Overwrite)
+	auditRecords := missingAuditRecords(source, dest)
+	result.AuditRecords = len(auditRecords)
+
+	result.Issues = Verify(mergeSnapshots(source, dest, opts.Overwrite))
+	if len(result.Issues) > 0 {
+		return result, fmt.Errorf("destination storage would fail validation with %d issue(s)", len(result.Issues))
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	if conflicts := result.Count(ChangeConflict); conflicts > 0 {
+		return result, fmt.Errorf("destination has %d conflicting pool(s), allocation(s) or address(es), migrate with overwrite to replace them", conflicts)
+	}
+
+	batch := migrationBatch(source, result.Changes, auditRecords)
+	if batch.IsEmpty() {
+		// the destination holds everything of the source already
+		result.Applied = true
+		result.Verified = true
+		return result, nil
+	}
+	if !opts.Overwrite && !dest.isEmpty() {
+		return result, fmt.Errorf("destination storage is not empty, migrate with overwrite to merge into it")
+	}
+
+	if err := dst.Apply(ctx, batch); err != nil {
+		return result, fmt.Errorf("failed to write destination storage: %w", err)
+	}
+	result.Applied = true
+
+	written, err := TakeSnapshot(ctx, dst)
+	if err != nil {
+		return result, fmt.Errorf("failed to read back destination storage: %w", err)
+	}
+	for _, change := range diffSnapshots(source, written, false) {
+		if change.Action != ChangeUnchanged {
+			return result, fmt.Errorf("verification failed: %s %s does not match the source after migration", change.Kind, change.Key)
+		}
+	}
+	if missing := missingAuditRecords(source, written); len(missing) > 0 {
+		return result, fmt.Errorf("verification failed: %d audit record(s) of the source are missing after migration", len(missing))
+	}
+	result.Verified = true
+
+	return result, nil
+}
+
+// MigrateConfig opens the source and destination backends with Factory and migrates between them.
+func MigrateConfig(ctx context.Context, from, to *Config, opts MigrateOptions) (*MigrationResult, error) {
+	src, err := Factory(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source storage: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := Factory(ctx, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open destination storage: %w", err)
+	}
+	defer dst.Close()
+
+	return Migrate(ctx, src, dst, opts)
+}
+
+// diffSnapshots compares every item of the source with the destination.
+func diffSnapshots(source, dest *Snapshot, overwrite bool) []Change {
+	changes := make([]Change, 0, len(source.Pools)+len(source.Allocations)+len(source.Addresses))
+
+	action := func(exists, equal bool) ChangeAction {
+		switch {
+		case !exists:
+			return ChangeCreate
+		case equal:
+			return ChangeUnchanged
+		case overwrite:
+			return ChangeUpdate
+		default:
+			return ChangeConflict
+		}
+	}
+
+	for _, pool := range source.Pools {
+		existing := dest.pool(pool.Name)
+		changes = append(changes, Change{
+			Kind:   "pool",
+			Key:    pool.Name,
+			Action: action(existing != nil, existing != nil && reflect.DeepEqual(*existing, pool)),
+		})
+	}
+	for _, alloc := range source.Allocations {
+		existing := dest.allocation(alloc.ID)
+		changes = append(changes, Change{
+			Kind:   "allocation",
+			Key:    alloc.ID,
+			Action: action(existing != nil, existing != nil && reflect.DeepEqual(*existing, alloc)),
+		})
+	}
+	for _, address := range source.Addresses {
+		existing := dest.address(address.ID)
+		changes = append(changes, Change{
+			Kind:   "address",
+			Key:    address.ID,
+			Action: action(existing != nil, existing != nil && reflect.DeepEqual(*existing, address)),
+		})
+	}
+
+	return changes
+}
+
+// migrationBatch returns the batch that writes the created and updated items
+// of the source, and the audit records, to the destination.
+func migrationBatch(source *Snapshot, changes []Change, auditRecords []AuditRecord) *Batch {
+	batch := &Batch{AuditRecords: auditRecords}
+	for _, change := range changes {
+		if change.Action != ChangeCreate && change.Action != ChangeUpdate {
+			continue
+		}
+
+		switch change.Kind {
+		case "pool":
+			batch.SavePools = append(batch.SavePools, *source.pool(change.Key))
+		case "allocation":
+			batch.SaveAllocations = append(batch.SaveAllocations, *source.allocation(change.Key))
+		case "address":
+			batch.SaveAddresses = append(batch.SaveAddresses, *source.address(change.Key))
+		}
+	}
+	return batch
+}
+
+// missingAuditRecords returns the audit records of the source that the
+// destination doesn't have, in the order of the source. Records are compared
+// in UTC, as backends may load times in another location.
+func missingAuditRecords(source, dest *Snapshot) []AuditRecord {
+	key := func(record AuditRecord) AuditRecord {
+		record.Time = record.Time.UTC().Round(0)
+		return record
+	}
+
+	existing := make(map[AuditRecord]int, len(dest.AuditLog))
+	for _, record := range dest.AuditLog {
+		existing[key(record)]++
+	}
+
+	var missing []AuditRecord
+	for _, record := range source.AuditLog {
+		if existing[key(record)] > 0 {
+			existing[key(record)]--
+			continue
+		}
+		missing = append(missing, record)
+	}
+	return missing
+}
+
+// mergeSnapshots returns the destination as it would look after migrating the source into it.
+func mergeSnapshots(source, dest *Snapshot, overwrite bool) *Snapshot {
+	pools := make(map[string]Pool, len(dest.Pools)+len(source.Pools))
+	for _, pool := range dest.Pools {
+		pools[pool.Name] = pool
+	}
+	for _, pool := range source.Pools {
+		if _, exists := pools[pool.Name]; !exists || overwrite {
+			pools[pool.Name] = pool
+		}
+	}
+
+	allocations := make(map[string]Allocation, len(dest.Allocations)+len(source.Allocations))
+	for _, alloc := range dest.Allocations {
+		allocations[alloc.ID] = alloc
+	}
+	for _, alloc := range source.Allocations {
+		if _, exists := allocations[alloc.ID]; !exists || overwrite {
+			allocations[alloc.ID] = alloc
+		}
+	}
+
+	addresses := make(map[string]Address, len(dest.Addresses)+len(source.Addresses))
+	for _, address := range dest.Addresses {
+		addresses[address.ID] = address
+	}
+	for _, address := range source.Addresses {
+		if _, exists := addresses[address.ID]; !exists || overwrite {
+			addresses[address.ID] = address
+		}
+	}
+
+	merged := &Snapshot{}
+	for _, pool := range pools {
+		merged.Pools = append(merged.Pools, pool)
+	}
+	for _, alloc := range allocations {
+		merged.Allocations = append(merged.Allocations, alloc)
+	}
+	for _, address := range addresses {
+		merged.Addresses = append(merged.Addresses, address)
+	}
+	merged.sort()
+
+	return merged
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestFileStorage creates a file backend in a temporary directory holding snap.
+func newTestFileStorage(t *testing.T, snap *Snapshot) *FileStorage {
+	t.Helper()
+	ctx := context.Background()
+
+	fs, err := NewFileStorage(filepath.Join(t.TempDir(), "ipam-storage.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap == nil {
+		return fs
+	}
+	for i := range snap.Pools {
+		if err := fs.SavePool(ctx, &snap.Pools[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range snap.Allocations {
+		if err := fs.SaveAllocation(ctx, &snap.Allocations[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range snap.Addresses {
+		if err := fs.SaveAddress(ctx, &snap.Addresses[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+func testSnapshot() *Snapshot {
+	return &Snapshot{
+		Pools: []Pool{
+			{Name: "prod", CIDRs: []string{"10.0.0.0/16"}},
+		},
+		Allocations: []Allocation{
+			{ID: "web", PoolName: "prod", AllocatedCIDR: "10.0.0.0/24", PrefixLength: 24},
+			{ID: "db", PoolName: "prod", AllocatedCIDR: "10.0.1.0/24", PrefixLength: 24},
+		},
+		Addresses: []Address{
+			{ID: "web-gw", AllocationID: "web", Address: "10.0.0.1"},
+		},
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	source := testSnapshot()
+	source.sort()
+
+	changed := testSnapshot()
+	changed.Allocations[1].AllocatedCIDR = "10.0.2.0/24"
+	changed.Addresses = nil
+	changed.sort()
+
+	tests := []struct {
+		name      string
+		dest      *Snapshot
+		overwrite bool
+		want      map[string]ChangeAction
+	}{
+		{
+			name: "empty destination",
+			dest: &Snapshot{},
+			want: map[string]ChangeAction{"pool prod": ChangeCreate, "allocation db": ChangeCreate, "allocation web": ChangeCreate, "address web-gw": ChangeCreate},
+		},
+		{
+			name: "same data",
+			dest: source,
+			want: map[string]ChangeAction{"pool prod": ChangeUnchanged, "allocation db": ChangeUnchanged, "allocation web": ChangeUnchanged, "address web-gw": ChangeUnchanged},
+		},
+		{
+			name: "different data",
+			dest: changed,
+			want: map[string]ChangeAction{"pool prod": ChangeUnchanged, "allocation db": ChangeConflict, "allocation web": ChangeUnchanged, "address web-gw": ChangeCreate},
+		},
+		{
+			name:      "different data with overwrite",
+			dest:      changed,
+			overwrite: true,
+			want:      map[string]ChangeAction{"pool prod": ChangeUnchanged, "allocation db": ChangeUpdate, "allocation web": ChangeUnchanged, "address web-gw": ChangeCreate},
+		},
+	}
+	for _, tt := range tests {
+		got := make(map[string]ChangeAction)
+		for _, change := range diffSnapshots(source, tt.dest, tt.overwrite) {
+			got[change.Kind+" "+change.Key] = change.Action
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMergeSnapshots(t *testing.T) {
+	source := testSnapshot()
+	source.sort()
+
+	dest := &Snapshot{
+		Pools:       []Pool{{Name: "dev", CIDRs: []string{"172.16.0.0/16"}}},
+		Allocations: []Allocation{{ID: "db", PoolName: "prod", AllocatedCIDR: "10.0.9.0/24", PrefixLength: 24}},
+	}
+	dest.sort()
+
+	for _, overwrite := range []bool{false, true} {
+		merged := mergeSnapshots(source, dest, overwrite)
+
+		// data only in the destination is kept
+		if merged.pool("dev") == nil || merged.pool("prod") == nil || len(merged.Pools) != 2 {
+			t.Errorf("overwrite=%v: expected pools dev and prod, got %v", overwrite, merged.Pools)
+		}
+		if len(merged.Allocations) != 2 || merged.address("web-gw") == nil {
+			t.Errorf("overwrite=%v: expected 2 allocations and the address, got %v", overwrite, merged)
+		}
+
+		want := "10.0.9.0/24"
+		if overwrite {
+			want = "10.0.1.0/24"
+		}
+		if got := merged.allocation("db").AllocatedCIDR; got != want {
+			t.Errorf("overwrite=%v: expected allocation db at %s, got %s", overwrite, want, got)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	conflicting := &Snapshot{
+		Pools:       []Pool{{Name: "prod", CIDRs: []string{"10.0.0.0/16"}}},
+		Allocations: []Allocation{{ID: "db", PoolName: "prod", AllocatedCIDR: "10.0.9.0/24", PrefixLength: 24}},
+	}
+	unrelated := &Snapshot{
+		Pools: []Pool{{Name: "dev", CIDRs: []string{"172.16.0.0/16"}}},
+	}
+	overlapping := &Snapshot{
+		Pools:       []Pool{{Name: "prod", CIDRs: []string{"10.0.0.0/16"}}},
+		Allocations: []Allocation{{ID: "other", PoolName: "prod", AllocatedCIDR: "10.0.0.0/23", PrefixLength: 23}},
+	}
+
+	tests := []struct {
+		name     string
+		source   *Snapshot
+		dest     *Snapshot
+		opts     MigrateOptions
+		wantErr  string
+		applied  bool
+		wantDest int // allocations in the destination afterwards
+	}{
+		{name: "empty destination", source: testSnapshot(), applied: true, wantDest: 2},
+		{name: "dry run", source: testSnapshot(), opts: MigrateOptions{DryRun: true}, wantDest: 0},
+		{name: "conflict", source: testSnapshot(), dest: conflicting, wantErr: "conflicting", wantDest: 1},
+		{name: "conflict with overwrite", source: testSnapshot(), dest: conflicting, opts: MigrateOptions{Overwrite: true}, applied: true, wantDest: 2},
+		{name: "non-empty destination", source: testSnapshot(), dest: unrelated, wantErr: "destination storage is not empty", wantDest: 0},
+		{name: "non-empty destination with overwrite", source: testSnapshot(), dest: unrelated, opts: MigrateOptions{Overwrite: true}, applied: true, wantDest: 2},
+		{name: "overlapping destination", source: testSnapshot(), dest: overlapping, wantErr: "destination storage would fail validation", wantDest: 1},
+		{
+			name: "invalid source",
+			source: &Snapshot{
+				Allocations: []Allocation{{ID: "lost", PoolName: "missing", AllocatedCIDR: "10.0.0.0/24", PrefixLength: 24}},
+			},
+			wantErr: "source storage failed validation",
+		},
+	}
+	for _, tt := range tests {
+		src := newTestFileStorage(t, tt.source)
+		dst := newTestFileStorage(t, tt.dest)
+
+		result, err := Migrate(ctx, src, dst, tt.opts)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.name, err)
+		}
+		if result != nil && (result.Applied != tt.applied || result.Verified != tt.applied) {
+			t.Errorf("%s: expected applied and verified %v, got %+v", tt.name, tt.applied, result)
+		}
+
+		allocations, _ := dst.ListAllocations(ctx)
+		if len(allocations) != tt.wantDest {
+			t.Errorf("%s: expected %d allocations in the destination, got %v", tt.name, tt.wantDest, allocations)
+		}
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	ctx := context.Background()
+	src := newTestFileStorage(t, testSnapshot())
+	to := filepath.Join(t.TempDir(), "migrated.json")
+
+	result, err := MigrateConfig(ctx, &Config{Type: "file", FilePath: src.filePath}, &Config{Type: "file", FilePath: to}, MigrateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count(ChangeCreate) != 4 || !result.Verified {
+		t.Fatalf("expected 4 verified creates, got %+v", result)
+	}
+
+	// migrating again finds nothing to change
+	result, err = MigrateConfig(ctx, &Config{Type: "file", FilePath: src.filePath}, &Config{Type: "file", FilePath: to}, MigrateOptions{})
+	if err != nil || result.Count(ChangeUnchanged) != 4 {
+		t.Fatalf("expected 4 unchanged, got %+v (%v)", result, err)
+	}
+
+	if _, err := MigrateConfig(ctx, &Config{Type: "file", FilePath: src.filePath}, &Config{Type: "ftp"}, MigrateOptions{}); err == nil {
+		t.Fatal("expected an unknown destination type to fail")
+	}
+}
+
+func TestMigrateAuditLog(t *testing.T) {
+	ctx := context.Background()
+	src := newTestFileStorage(t, testSnapshot())
+	dst := newTestFileStorage(t, nil)
+
+	records := []AuditRecord{
+		{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Operation: "reclaim", PoolName: "prod", AllocationID: "old", CIDR: "10.0.2.0/24"},
+		{Time: time.Date(2026, 1, 2, 3, 4, 6, 0, time.UTC), Operation: "compact", Message: "removed 1 allocation"},
+	}
+	if err := src.AppendAuditRecords(ctx, records); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Migrate(ctx, src, dst, MigrateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AuditRecords != 2 || !result.Verified {
+		t.Fatalf("expected 2 verified audit records, got %+v", result)
+	}
+
+	reopened, err := NewFileStorage(dst.filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := TakeSnapshot(ctx, reopened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(snap.AuditLog, records) {
+		t.Errorf("expected the audit log of the source, got %v", snap.AuditLog)
+	}
+
+	// migrating again doesn't copy the records twice
+	result, err = Migrate(ctx, src, reopened, MigrateOptions{})
+	if err != nil || result.AuditRecords != 0 {
+		t.Fatalf("expected no audit records to copy, got %+v (%v)", result, err)
+	}
+}
+
+func TestMigrateSingleWrite(t *testing.T) {
+	ctx := context.Background()
+	src := newTestFileStorage(t, testSnapshot())
+
+	f := &fakeS3{}
+	dst := newTestS3Storage(t, f)
+	result, err := Migrate(ctx, src, dst, MigrateOptions{})
+	if err != nil || !result.Verified {
+		t.Fatalf("expected a verified migration, got %+v (%v)", result, err)
+	}
+	if f.puts != 1 {
+		t.Errorf("expected the destination to be written once, got %d uploads", f.puts)
+	}
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// Writes that take space are checked against the document they are applied to.
+// The remote backends apply a write to the latest version of the document, and
+// again when another writer changed it in between, so a CIDR or address picked
+// from an older read fails with ErrOverlap instead of taking space that is no
+// longer free. Space an item already held in the document isn't checked again.
+
+// checkAllocations returns ErrOverlap when one of the changed allocations
+// overlaps another allocation of its pool, a child pool or an unexpired lease.
+// Allocations whose ID is in skip, such as those removed by the same write, are
+// not taken into account.
+func checkAllocations(pools map[string]*Pool, allocations map[string]*Allocation, changed []Allocation, skip map[string]bool) error {
+	changedIDs := make(map[string]bool, len(changed))
+	for _, alloc := range changed {
+		changedIDs[alloc.ID] = true
+	}
+
+	now := time.Now()
+	for i, alloc := range changed {
+		prefix, err := netip.ParsePrefix(alloc.AllocatedCIDR)
+		if err != nil {
+			continue
+		}
+		if stored, exists := allocations[alloc.ID]; exists && !skip[alloc.ID] &&
+			stored.PoolName == alloc.PoolName && stored.AllocatedCIDR == alloc.AllocatedCIDR {
+			continue
+		}
+
+		for id, other := range allocations {
+			if skip[id] || changedIDs[id] || other.PoolName != alloc.PoolName {
+				continue
+			}
+			if cidrOverlaps(other.AllocatedCIDR, prefix) {
+				return fmt.Errorf("allocation %s: CIDR %s overlaps allocation %s: %w", alloc.ID, alloc.AllocatedCIDR, id, ErrOverlap)
+			}
+		}
+		for _, other := range changed[:i] {
+			if other.PoolName == alloc.PoolName && cidrOverlaps(other.AllocatedCIDR, prefix) {
+				return fmt.Errorf("allocation %s: CIDR %s overlaps allocation %s: %w", alloc.ID, alloc.AllocatedCIDR, other.ID, ErrOverlap)
+			}
+		}
+		for _, pool := range pools {
+			if pool.ParentPool != alloc.PoolName {
+				continue
+			}
+			for _, cidr := range pool.CIDRs {
+				if cidrOverlaps(cidr, prefix) {
+					return fmt.Errorf("allocation %s: CIDR %s overlaps pool %s: %w", alloc.ID, alloc.AllocatedCIDR, pool.Name, ErrOverlap)
+				}
+			}
+		}
+		if pool, exists := pools[alloc.PoolName]; exists {
+			for _, lease := range pool.Leases {
+				if lease.ExpiresAt.After(now) && cidrOverlaps(lease.CIDR, prefix) {
+					return fmt.Errorf("allocation %s: CIDR %s overlaps lease %s: %w", alloc.ID, alloc.AllocatedCIDR, lease.ID, ErrOverlap)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkPool returns ErrOverlap when the CIDRs of a child pool overlap an
+// allocation or another child pool of its parent, or when a lease that isn't
+// stored yet overlaps an allocation, a child pool or an unexpired lease of the
+// pool. Allocations whose ID is in skip are not taken into account.
+func checkPool(pools map[string]*Pool, allocations map[string]*Allocation, pool *Pool, skip map[string]bool) error {
+	existing, exists := pools[pool.Name]
+	if pool.ParentPool != "" {
+		for _, cidr := range pool.CIDRs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil || (exists && existing.ParentPool == pool.ParentPool && slices.Contains(existing.CIDRs, cidr)) {
+				continue
+			}
+			for id, alloc := range allocations {
+				if !skip[id] && alloc.PoolName == pool.ParentPool && cidrOverlaps(alloc.AllocatedCIDR, prefix) {
+					return fmt.Errorf("pool %s: CIDR %s overlaps allocation %s: %w", pool.Name, cidr, alloc.ID, ErrOverlap)
+				}
+			}
+			for _, sibling := range pools {
+				if sibling.Name == pool.Name || sibling.ParentPool != pool.ParentPool {
+					continue
+				}
+				for _, other := range sibling.CIDRs {
+					if cidrOverlaps(other, prefix) {
+						return fmt.Errorf("pool %s: CIDR %s overlaps pool %s: %w", pool.Name, cidr, sibling.Name, ErrOverlap)
+					}
+				}
+			}
+		}
+	}
+
+	var stored []Lease
+	if exists {
+		stored = existing.Leases
+	}
+	now := time.Now()
+	for i, lease := range pool.Leases {
+		prefix, err := netip.ParsePrefix(lease.CIDR)
+		if err != nil || !lease.ExpiresAt.After(now) || slices.ContainsFunc(stored, func(l Lease) bool { return l.ID == lease.ID }) {
+			continue
+		}
+
+		for _, other := range stored {
+			if other.ID != lease.ID && other.ExpiresAt.After(now) && cidrOverlaps(other.CIDR, prefix) {
+				return fmt.Errorf("lease %s: CIDR %s overlaps lease %s: %w", lease.ID, lease.CIDR, other.ID, ErrOverlap)
+			}
+		}
+		for _, other := range pool.Leases[:i] {
+			if other.ExpiresAt.After(now) && cidrOverlaps(other.CIDR, prefix) {
+				return fmt.Errorf("lease %s: CIDR %s overlaps lease %s: %w", lease.ID, lease.CIDR, other.ID, ErrOverlap)
+			}
+		}
+		for id, alloc := range allocations {
+			if !skip[id] && alloc.PoolName == pool.Name && cidrOverlaps(alloc.AllocatedCIDR, prefix) {
+				return fmt.Errorf("lease %s: CIDR %s overlaps allocation %s: %w", lease.ID, lease.CIDR, alloc.ID, ErrOverlap)
+			}
+		}
+		for _, child := range pools {
+			if child.ParentPool != pool.Name {
+				continue
+			}
+			for _, cidr := range child.CIDRs {
+				if cidrOverlaps(cidr, prefix) {
+					return fmt.Errorf("lease %s: CIDR %s overlaps pool %s: %w", lease.ID, lease.CIDR, child.Name, ErrOverlap)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkAddress returns ErrOverlap when another address of the same allocation
+// holds the address.
+func checkAddress(addresses map[string]*Address, address *Address) error {
+	for id, other := range addresses {
+		if id != address.ID && other.AllocationID == address.AllocationID && other.Address == address.Address {
+			return fmt.Errorf("address %s: %s is held by address %s: %w", address.ID, address.Address, id, ErrOverlap)
+		}
+	}
+	return nil
+}
+
+// cidrOverlaps reports whether cidr overlaps prefix. Unparsable CIDRs never
+// overlap anything.
+func cidrOverlaps(cidr string, prefix netip.Prefix) bool {
+	other, err := netip.ParsePrefix(cidr)
+	return err == nil && other.Masked().Overlaps(prefix.Masked())
+}
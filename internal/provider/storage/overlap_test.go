@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckOverlap(t *testing.T) {
+	soon := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	pools := map[string]*Pool{
+		"prod":  {Name: "prod", CIDRs: []string{"10.0.0.0/16"}, Leases: []Lease{{ID: "l1", CIDR: "10.0.5.0/24", ExpiresAt: soon}, {ID: "old", CIDR: "10.0.6.0/24", ExpiresAt: past}}},
+		"child": {Name: "child", CIDRs: []string{"10.0.8.0/24"}, ParentPool: "prod"},
+	}
+	allocations := map[string]*Allocation{
+		"web": {ID: "web", PoolName: "prod", AllocatedCIDR: "10.0.0.0/24", PrefixLength: 24},
+	}
+	addresses := map[string]*Address{
+		"gw": {ID: "gw", AllocationID: "web", Address: "10.0.0.1"},
+	}
+
+	alloc := func(id, cidr string) Allocation {
+		return Allocation{ID: id, PoolName: "prod", AllocatedCIDR: cidr, PrefixLength: 24}
+	}
+	tests := []struct {
+		name    string
+		err     error
+		overlap bool
+	}{
+		{"free space", checkAllocations(pools, allocations, []Allocation{alloc("db", "10.0.1.0/24")}, nil), false},
+		{"other allocation", checkAllocations(pools, allocations, []Allocation{alloc("db", "10.0.0.0/23")}, nil), true},
+		{"removed allocation", checkAllocations(pools, allocations, []Allocation{alloc("db", "10.0.0.0/24")}, map[string]bool{"web": true}), false},
+		{"same allocation", checkAllocations(pools, allocations, []Allocation{alloc("web", "10.0.0.0/24")}, nil), false},
+		{"each other", checkAllocations(pools, allocations, []Allocation{alloc("a", "10.0.1.0/24"), alloc("b", "10.0.1.0/25")}, nil), true},
+		{"child pool", checkAllocations(pools, allocations, []Allocation{alloc("db", "10.0.8.0/24")}, nil), true},
+		{"lease", checkAllocations(pools, allocations, []Allocation{alloc("db", "10.0.5.0/24")}, nil), true},
+		{"expired lease", checkAllocations(pools, allocations, []Allocation{alloc("db", "10.0.6.0/24")}, nil), false},
+		{"child pool over allocation", checkPool(pools, allocations, &Pool{Name: "dev", CIDRs: []string{"10.0.0.0/25"}, ParentPool: "prod"}, nil), true},
+		{"child pool over sibling", checkPool(pools, allocations, &Pool{Name: "dev", CIDRs: []string{"10.0.8.0/25"}, ParentPool: "prod"}, nil), true},
+		{"child pool over removed allocation", checkPool(pools, allocations, &Pool{Name: "dev", CIDRs: []string{"10.0.0.0/25"}, ParentPool: "prod"}, map[string]bool{"web": true}), false},
+		{"stored child pool", checkPool(pools, allocations, pools["child"], nil), false},
+		{"new lease", checkPool(pools, allocations, &Pool{Name: "prod", CIDRs: []string{"10.0.0.0/16"}, Leases: []Lease{{ID: "l2", CIDR: "10.0.5.0/25", ExpiresAt: soon}}}, nil), true},
+		{"free lease", checkPool(pools, allocations, &Pool{Name: "prod", CIDRs: []string{"10.0.0.0/16"}, Leases: []Lease{{ID: "l2", CIDR: "10.0.7.0/24", ExpiresAt: soon}}}, nil), false},
+		{"held address", checkAddress(addresses, &Address{ID: "other", AllocationID: "web", Address: "10.0.0.1"}), true},
+		{"same address", checkAddress(addresses, &Address{ID: "gw", AllocationID: "web", Address: "10.0.0.1"}), false},
+	}
+	for _, tt := range tests {
+		if tt.overlap != errors.Is(tt.err, ErrOverlap) || (!tt.overlap && tt.err != nil) {
+			t.Errorf("%s: expected overlap %v, got %v", tt.name, tt.overlap, tt.err)
+		}
+	}
+}
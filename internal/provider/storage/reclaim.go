@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AuditReclaimExpired is the audit log operation of allocations removed by ReclaimExpired.
+const AuditReclaimExpired = "reclaim_expired"
+
+// ReclaimOptions controls which expired allocations ReclaimExpired removes.
+type ReclaimOptions struct {
+	// PoolName limits reclaiming to one pool, empty means all pools.
+	PoolName string
+
+	// Now is the time allocations are expired at, the zero time means the current time.
+	Now time.Time
+
+	// DryRun only returns the expired allocations without removing them.
+	DryRun bool
+}
+
+// ExpiredAllocations returns the allocations that expired at or before now,
+// sorted by ID. An empty poolName returns those of all pools.
+func ExpiredAllocations(allocations []Allocation, poolName string, now time.Time) []Allocation {
+	var expired []Allocation
+	for _, alloc := range allocations {
+		if (poolName == "" || alloc.PoolName == poolName) && alloc.Expired(now) {
+			expired = append(expired, alloc)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ID < expired[j].ID })
+	return expired
+}
+
+// ReclaimExpired removes expired allocations together with the host addresses
+// taken from them, and appends an audit record for each removed allocation. The
+// removals and the audit records are written to storage at once.
+// It returns the expired allocations, which were removed unless DryRun is set.
+func ReclaimExpired(ctx context.Context, s Storage, opts ReclaimOptions) ([]Allocation, error) {
+	expired, batch, err := ReclaimBatch(ctx, s, opts)
+	if err != nil || opts.DryRun || len(expired) == 0 {
+		return expired, err
+	}
+
+	if err := s.Apply(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to remove expired allocations: %w", err)
+	}
+	return expired, nil
+}
+
+// ReclaimBatch returns the expired allocations and the batch that removes them
+// together with their addresses and writes their audit records, so callers can
+// reclaim in the same write as their own changes. Nothing is written.
+func ReclaimBatch(ctx context.Context, s Storage, opts ReclaimOptions) ([]Allocation, *Batch, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	allocations, err := s.ListAllocations(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list allocations: %w", err)
+	}
+
+	batch := &Batch{}
+	expired := ExpiredAllocations(allocations, opts.PoolName, now)
+	if len(expired) == 0 {
+		return nil, batch, nil
+	}
+
+	addresses, err := s.ListAddresses(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+	addressesByAllocation := make(map[string][]string)
+	for _, address := range addresses {
+		addressesByAllocation[address.AllocationID] = append(addressesByAllocation[address.AllocationID], address.ID)
+	}
+
+	for _, alloc := range expired {
+		released := addressesByAllocation[alloc.ID]
+		batch.DeleteAddresses = append(batch.DeleteAddresses, released...)
+		batch.DeleteAllocations = append(batch.DeleteAllocations, alloc.ID)
+		batch.AuditRecords = append(batch.AuditRecords, AuditRecord{
+			Time:         now.UTC(),
+			Operation:    AuditReclaimExpired,
+			PoolName:     alloc.PoolName,
+			AllocationID: alloc.ID,
+			CIDR:         alloc.AllocatedCIDR,
+			Message:      fmt.Sprintf("expired at %s, %d address(es) released", alloc.ExpiresAt.Format(time.RFC3339), len(released)),
+		})
+	}
+
+	return expired, batch, nil
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReclaimExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	snap := testSnapshot()
+	snap.Pools = append(snap.Pools, Pool{Name: "dev", CIDRs: []string{"172.16.0.0/16"}})
+	snap.Allocations[0].ExpiresAt = &past // web, with address web-gw
+	snap.Allocations[1].ExpiresAt = &future
+	snap.Allocations = append(snap.Allocations, Allocation{ID: "test", PoolName: "dev", AllocatedCIDR: "172.16.0.0/24", PrefixLength: 24, ExpiresAt: &past})
+
+	f := &fakeS3{}
+	s := newTestS3Storage(t, f)
+	for i := range snap.Pools {
+		s.SavePool(ctx, &snap.Pools[i])
+	}
+	s.SaveAllocations(ctx, snap.Allocations)
+	s.SaveAddress(ctx, &snap.Addresses[0])
+
+	// a dry run only lists them
+	expired, err := ReclaimExpired(ctx, s, ReclaimOptions{PoolName: "prod", Now: now, DryRun: true})
+	if err != nil || len(expired) != 1 || expired[0].ID != "web" {
+		t.Fatalf("expected web to be expired, got %v (%v)", expired, err)
+	}
+	if _, err := s.GetAllocation(ctx, "web"); err != nil {
+		t.Fatalf("expected a dry run to keep web, got %v", err)
+	}
+
+	puts := f.puts
+	expired, err = ReclaimExpired(ctx, s, ReclaimOptions{PoolName: "prod", Now: now})
+	if err != nil || len(expired) != 1 {
+		t.Fatalf("expected web to be reclaimed, got %v (%v)", expired, err)
+	}
+	if f.puts-puts != 1 {
+		t.Errorf("expected a single write, got %d", f.puts-puts)
+	}
+
+	if _, err := s.GetAllocation(ctx, "web"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected web to be removed, got %v", err)
+	}
+	if _, err := s.GetAddress(ctx, "web-gw"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the address of web to be removed, got %v", err)
+	}
+	for _, id := range []string{"db", "test"} {
+		if _, err := s.GetAllocation(ctx, id); err != nil {
+			t.Errorf("expected %s to be kept, got %v", id, err)
+		}
+	}
+
+	records, _ := s.ListAuditRecords(ctx)
+	if len(records) != 1 || records[0].AllocationID != "web" || records[0].Message != "expired at "+past.Format(time.RFC3339)+", 1 address(es) released" {
+		t.Errorf("expected an audit record for web, got %+v", records)
+	}
+
+	// nothing left to reclaim doesn't write
+	puts = f.puts
+	if expired, err := ReclaimExpired(ctx, s, ReclaimOptions{PoolName: "prod", Now: now}); err != nil || len(expired) != 0 || f.puts != puts {
+		t.Errorf("expected nothing to be reclaimed, got %v (%v) with %d writes", expired, err, f.puts-puts)
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Snapshot is a point in time copy of everything held by a storage backend.
+// Pools are sorted by name and allocations and addresses by ID so snapshots
+// of the same data always compare and serialize the same way. The audit log
+// keeps the order it was written in.
+type Snapshot struct {
+	Pools       []Pool        `json:"pools"`
+	Allocations []Allocation  `json:"allocations"`
+	Addresses   []Address     `json:"addresses,omitempty"`
+	AuditLog    []AuditRecord `json:"audit_log,omitempty"`
+}
+
+// TakeSnapshot reads all pools, allocations, addresses and the audit log from the storage backend.
+func TakeSnapshot(ctx context.Context, s Storage) (*Snapshot, error) {
+	pools, err := s.ListPools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pools: %w", err)
+	}
+
+	allocations, err := s.ListAllocations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list allocations: %w", err)
+	}
+
+	addresses, err := s.ListAddresses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+
+	auditLog, err := s.ListAuditRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit records: %w", err)
+	}
+
+	snap := &Snapshot{
+		Pools:       pools,
+		Allocations: allocations,
+		Addresses:   addresses,
+		AuditLog:    auditLog,
+	}
+	snap.sort()
+
+	return snap, nil
+}
+
+// WriteFile writes the snapshot as indented JSON, the same format as
+// `tfipam export`. The file is written next to path first and renamed, so an
+// existing snapshot is never left half written.
+func (s *Snapshot) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to rename snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Snapshot) sort() {
+	sort.Slice(s.Pools, func(i, j int) bool { return s.Pools[i].Name < s.Pools[j].Name })
+	sort.Slice(s.Allocations, func(i, j int) bool { return s.Allocations[i].ID < s.Allocations[j].ID })
+	sort.Slice(s.Addresses, func(i, j int) bool { return s.Addresses[i].ID < s.Addresses[j].ID })
+}
+
+// isEmpty reports whether the snapshot holds no pools, allocations or addresses.
+func (s *Snapshot) isEmpty() bool {
+	return len(s.Pools) == 0 && len(s.Allocations) == 0 && len(s.Addresses) == 0
+}
+
+// pool returns the pool with the given name, or nil if the snapshot doesn't have it.
+func (s *Snapshot) pool(name string) *Pool {
+	i := sort.Search(len(s.Pools), func(i int) bool { return s.Pools[i].Name >= name })
+	if i < len(s.Pools) && s.Pools[i].Name == name {
+		return &s.Pools[i]
+	}
+	return nil
+}
+
+// allocation returns the allocation with the given ID, or nil if the snapshot doesn't have it.
+func (s *Snapshot) allocation(id string) *Allocation {
+	i := sort.Search(len(s.Allocations), func(i int) bool { return s.Allocations[i].ID >= id })
+	if i < len(s.Allocations) && s.Allocations[i].ID == id {
+		return &s.Allocations[i]
+	}
+	return nil
+}
+
+// address returns the address with the given ID, or nil if the snapshot doesn't have it.
+func (s *Snapshot) address(id string) *Address {
+	i := sort.Search(len(s.Addresses), func(i int) bool { return s.Addresses[i].ID >= id })
+	if i < len(s.Addresses) && s.Addresses[i].ID == id {
+		return &s.Addresses[i]
+	}
+	return nil
+}
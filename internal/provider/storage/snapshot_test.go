@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	fs := newTestFileStorage(t, testSnapshot())
+
+	snap, err := TakeSnapshot(context.Background(), fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sorted by ID, so lookups work
+	if snap.Allocations[0].ID != "db" || snap.Allocations[1].ID != "web" {
+		t.Errorf("expected allocations sorted by ID, got %v", snap.Allocations)
+	}
+	if snap.pool("prod") == nil || snap.allocation("web") == nil || snap.address("web-gw") == nil {
+		t.Errorf("expected the snapshot to hold every item, got %+v", snap)
+	}
+	if snap.pool("dev") != nil || snap.allocation("zzz") != nil || snap.address("") != nil {
+		t.Error("expected missing items not to be found")
+	}
+}
+
+func TestSnapshotWriteFile(t *testing.T) {
+	snap := testSnapshot()
+	snap.sort()
+	path := filepath.Join(t.TempDir(), "backups", "snapshot.json")
+
+	// an existing snapshot is replaced
+	for range 2 {
+		if err := snap.WriteFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read Snapshot
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&read, snap) {
+		t.Errorf("expected the snapshot to round trip, got %+v", read)
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected no temporary file to be left, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"time"
+
+	"terraform-provider-tfipam/internal/provider/ipam"
+)
+
+// PoolSpace is a pool read from storage together with everything that takes
+// space in it. The provider allocates from it and the command line tool lists
+// its free blocks, so both agree on what is free.
+type PoolSpace struct {
+	Pool        *Pool
+	Allocations []Allocation
+	Children    []Pool
+
+	// Expired are the expired allocations of a pool that reclaims them. Their
+	// CIDRs count as free, so they are left out of Allocations.
+	Expired []Allocation
+
+	// now is the time leases and allocations were checked for expiry.
+	now time.Time
+}
+
+// LoadPoolSpace reads the pool, its allocations and its child pools. The
+// allocations whose ID is in released are left out, for callers that remove
+// them in the same write as they allocate.
+func LoadPoolSpace(ctx context.Context, s Storage, poolName string, released []string) (*PoolSpace, error) {
+	pool, err := s.GetPool(ctx, poolName)
+	if err != nil {
+		return nil, fmt.Errorf("pool %s not found: %w", poolName, err)
+	}
+
+	allocations, err := s.ListAllocationsByPool(ctx, poolName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list allocations: %w", err)
+	}
+
+	pools, err := s.ListPools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pools: %w", err)
+	}
+
+	allocations = slices.DeleteFunc(allocations, func(alloc Allocation) bool {
+		return slices.Contains(released, alloc.ID)
+	})
+
+	space := &PoolSpace{
+		Pool:     pool,
+		Children: ChildPools(pools, poolName),
+		now:      time.Now(),
+	}
+	for _, alloc := range allocations {
+		if pool.ReclaimExpired && alloc.Expired(space.now) {
+			space.Expired = append(space.Expired, alloc)
+		} else {
+			space.Allocations = append(space.Allocations, alloc)
+		}
+	}
+	return space, nil
+}
+
+// Occupied returns the CIDRs of the pool that are taken: reserved ranges, the
+// CIDRs of child pools, allocations and unexpired leases.
+func (s *PoolSpace) Occupied() []string {
+	occupied := make([]string, 0, len(s.Pool.ReservedCIDRs)+len(s.Children)+len(s.Allocations)+len(s.Pool.Leases))
+	occupied = append(occupied, s.Pool.ReservedCIDRs...)
+	for _, child := range s.Children {
+		occupied = append(occupied, child.CIDRs...)
+	}
+	for _, alloc := range s.Allocations {
+		occupied = append(occupied, alloc.AllocatedCIDR)
+	}
+	for _, lease := range s.Pool.Leases {
+		if lease.ExpiresAt.After(s.now) {
+			occupied = append(occupied, lease.CIDR)
+		}
+	}
+	return occupied
+}
+
+// Allocator builds the free-list of the pool, with the extra CIDRs taken as
+// well, and places it at the pool's sequential cursor.
+func (s *PoolSpace) Allocator(extra ...string) *ipam.Allocator {
+	allocator := ipam.NewAllocator(ipam.ParseCIDRs(s.Pool.CIDRs), ipam.ParseCIDRs(append(s.Occupied(), extra...)))
+	if cursor, err := netip.ParsePrefix(s.Pool.Cursor); err == nil {
+		allocator.SetCursor(cursor)
+	}
+	return allocator
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadPoolSpace(t *testing.T) {
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	fs := newTestFileStorage(t, &Snapshot{
+		Pools: []Pool{
+			{Name: "prod", CIDRs: []string{"10.0.0.0/24"}, ReservedCIDRs: []string{"10.0.0.0/28"}, ReclaimExpired: true, Leases: []Lease{
+				{ID: "live", CIDR: "10.0.0.16/28", ExpiresAt: future},
+				{ID: "stale", CIDR: "10.0.0.32/28", ExpiresAt: past},
+			}},
+			{Name: "child", CIDRs: []string{"10.0.0.48/28"}, ParentPool: "prod"},
+		},
+		Allocations: []Allocation{
+			{ID: "web", PoolName: "prod", AllocatedCIDR: "10.0.0.64/28", PrefixLength: 28},
+			{ID: "old", PoolName: "prod", AllocatedCIDR: "10.0.0.80/28", PrefixLength: 28, ExpiresAt: &past},
+			{ID: "gone", PoolName: "prod", AllocatedCIDR: "10.0.0.96/28", PrefixLength: 28},
+		},
+	})
+
+	space, err := LoadPoolSpace(ctx, fs, "prod", []string{"gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(space.Expired) != 1 || space.Expired[0].ID != "old" {
+		t.Errorf("expected old to be expired, got %v", space.Expired)
+	}
+
+	// expired leases and allocations, and released allocations, are free
+	want := []string{"10.0.0.0/28", "10.0.0.48/28", "10.0.0.64/28", "10.0.0.16/28"}
+	if got := space.Occupied(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v to be occupied, got %v", want, got)
+	}
+
+	allocator := space.Allocator("10.0.0.32/28")
+	if got, _ := allocator.AllocateWith(28, ""); got.String() != "10.0.0.80/28" {
+		t.Errorf("expected 10.0.0.80/28 to be the first free block, got %s", got)
+	}
+
+	if _, err := LoadPoolSpace(ctx, fs, "missing", nil); err == nil {
+		t.Error("expected a missing pool to fail")
+	}
+}
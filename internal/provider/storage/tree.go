@@ -0,0 +1,47 @@
+package storage
+
+// ChildPools returns the pools whose parent is the named pool.
+func ChildPools(pools []Pool, name string) []Pool {
+	var children []Pool
+	for _, pool := range pools {
+		if pool.ParentPool == name {
+			children = append(children, pool)
+		}
+	}
+	return children
+}
+
+// DescendantPools returns the pools below the named pool, children before
+// grandchildren. A pool that is its own ancestor is only visited once.
+func DescendantPools(pools []Pool, name string) []Pool {
+	var descendants []Pool
+	seen := map[string]bool{name: true}
+	for queue := []string{name}; len(queue) > 0; queue = queue[1:] {
+		for _, child := range ChildPools(pools, queue[0]) {
+			if seen[child.Name] {
+				continue
+			}
+			seen[child.Name] = true
+			descendants = append(descendants, child)
+			queue = append(queue, child.Name)
+		}
+	}
+	return descendants
+}
+
+// SubtreeAllocations returns the allocations of the named pool and of all pools
+// below it, which is what the utilization of the pool rolls up.
+func SubtreeAllocations(snap *Snapshot, name string) []Allocation {
+	inTree := map[string]bool{name: true}
+	for _, pool := range DescendantPools(snap.Pools, name) {
+		inTree[pool.Name] = true
+	}
+
+	var allocations []Allocation
+	for _, alloc := range snap.Allocations {
+		if inTree[alloc.PoolName] {
+			allocations = append(allocations, alloc)
+		}
+	}
+	return allocations
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Issue describes an integrity problem found in stored data.
+type Issue struct {
+	Kind    string `json:"kind"`    // "pool", "allocation" or "address"
+	Subject string `json:"subject"` // pool name, allocation ID or address ID
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s %s: %s", i.Kind, i.Subject, i.Message)
+}
+
+// Verify checks a snapshot for data that the provider could not have written
+// itself: unparsable CIDRs, reservations or allocations lying outside of their
+// pool without being marked orphaned, blocks of allocation groups lying outside
+// of their allocation, child pools lying outside of their parent, allocations
+// referencing missing pools, allocations and child pools overlapping each other,
+// and addresses that lie outside of their allocation or are held twice.
+func Verify(snap *Snapshot) []Issue {
+	var issues []Issue
+
+	poolPrefixes := make(map[string][]netip.Prefix, len(snap.Pools))
+	for _, pool := range snap.Pools {
+		if len(pool.CIDRs) == 0 {
+			issues = append(issues, Issue{"pool", pool.Name, "pool has no CIDRs"})
+		}
+		for _, cidr := range pool.CIDRs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				issues = append(issues, Issue{"pool", pool.Name, fmt.Sprintf("invalid CIDR %q: %s", cidr, err)})
+				continue
+			}
+			poolPrefixes[pool.Name] = append(poolPrefixes[pool.Name], prefix.Masked())
+		}
+		for _, cidr := range pool.ReservedCIDRs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				issues = append(issues, Issue{"pool", pool.Name, fmt.Sprintf("invalid reserved CIDR %q: %s", cidr, err)})
+				continue
+			}
+			if !prefixWithinAny(prefix.Masked(), poolPrefixes[pool.Name]) {
+				issues = append(issues, Issue{"pool", pool.Name, fmt.Sprintf("reserved CIDR %s is outside of the pool", cidr)})
+			}
+		}
+	}
+
+	for _, pool := range snap.Pools {
+		if pool.ParentPool == "" {
+			continue
+		}
+		if snap.pool(pool.ParentPool) == nil {
+			issues = append(issues, Issue{"pool", pool.Name, fmt.Sprintf("parent pool %q does not exist", pool.ParentPool)})
+			continue
+		}
+		for _, prefix := range poolPrefixes[pool.Name] {
+			if !prefixWithinAny(prefix, poolPrefixes[pool.ParentPool]) {
+				issues = append(issues, Issue{"pool", pool.Name, fmt.Sprintf("CIDR %s is outside of parent pool %q", prefix, pool.ParentPool)})
+			}
+		}
+	}
+
+	// allocations grouped by pool so overlaps are only checked within a pool
+	byPool := make(map[string][]Allocation)
+	prefixes := make(map[string]netip.Prefix, len(snap.Allocations))
+	for _, alloc := range snap.Allocations {
+		if snap.pool(alloc.PoolName) == nil {
+			issues = append(issues, Issue{"allocation", alloc.ID, fmt.Sprintf("pool %q does not exist", alloc.PoolName)})
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(alloc.AllocatedCIDR)
+		if err != nil {
+			issues = append(issues, Issue{"allocation", alloc.ID, fmt.Sprintf("invalid CIDR %q: %s", alloc.AllocatedCIDR, err)})
+			continue
+		}
+		if prefix.Bits() != alloc.PrefixLength {
+			issues = append(issues, Issue{"allocation", alloc.ID, fmt.Sprintf("CIDR %s does not match prefix length %d", alloc.AllocatedCIDR, alloc.PrefixLength)})
+		}
+		if !alloc.Orphaned && !prefixWithinAny(prefix, poolPrefixes[alloc.PoolName]) {
+			issues = append(issues, Issue{"allocation", alloc.ID, fmt.Sprintf("CIDR %s is outside of pool %q", alloc.AllocatedCIDR, alloc.PoolName)})
+		}
+
+		for _, cidr := range alloc.Blocks {
+			block, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				issues = append(issues, Issue{"allocation", alloc.ID, fmt.Sprintf("invalid block %q: %s", cidr, err)})
+				continue
+			}
+			if !prefixWithinAny(block.Masked(), []netip.Prefix{prefix.Masked()}) {
+				issues = append(issues, Issue{"allocation", alloc.ID, fmt.Sprintf("block %s is outside of %s", cidr, alloc.AllocatedCIDR)})
+			}
+		}
+
+		prefixes[alloc.ID] = prefix.Masked()
+		byPool[alloc.PoolName] = append(byPool[alloc.PoolName], alloc)
+	}
+
+	for _, pool := range snap.Pools {
+		allocs := byPool[pool.Name]
+
+		// child pools take space of the parent just like allocations do
+		children := ChildPools(snap.Pools, pool.Name)
+		for i, child := range children {
+			for _, prefix := range poolPrefixes[child.Name] {
+				for _, alloc := range allocs {
+					if prefixes[alloc.ID].Overlaps(prefix) {
+						issues = append(issues, Issue{"pool", child.Name, fmt.Sprintf("CIDR %s overlaps allocation %q (%s) of parent pool %q",
+							prefix, alloc.ID, alloc.AllocatedCIDR, pool.Name)})
+					}
+				}
+				for _, sibling := range children[:i] {
+					for _, other := range poolPrefixes[sibling.Name] {
+						if other.Overlaps(prefix) {
+							issues = append(issues, Issue{"pool", child.Name, fmt.Sprintf("CIDR %s overlaps pool %q (%s)", prefix, sibling.Name, other)})
+						}
+					}
+				}
+			}
+		}
+
+		for i := range allocs {
+			for j := i + 1; j < len(allocs); j++ {
+				if prefixes[allocs[i].ID].Overlaps(prefixes[allocs[j].ID]) {
+					issues = append(issues, Issue{"allocation", allocs[j].ID, fmt.Sprintf("CIDR %s overlaps allocation %q (%s)",
+						allocs[j].AllocatedCIDR, allocs[i].ID, allocs[i].AllocatedCIDR)})
+				}
+			}
+		}
+	}
+
+	// addresses only need to be unique within an allocation, pools may overlap
+	type heldAddress struct {
+		allocationID string
+		addr         netip.Addr
+	}
+	held := make(map[heldAddress]string, len(snap.Addresses))
+	for _, address := range snap.Addresses {
+		prefix, exists := prefixes[address.AllocationID]
+		if !exists {
+			if snap.allocation(address.AllocationID) == nil {
+				issues = append(issues, Issue{"address", address.ID, fmt.Sprintf("allocation %q does not exist", address.AllocationID)})
+			}
+			continue
+		}
+
+		addr, err := netip.ParseAddr(address.Address)
+		if err != nil {
+			issues = append(issues, Issue{"address", address.ID, fmt.Sprintf("invalid address %q: %s", address.Address, err)})
+			continue
+		}
+		if !prefix.Contains(addr) {
+			issues = append(issues, Issue{"address", address.ID, fmt.Sprintf("address %s is outside of allocation %q (%s)", addr, address.AllocationID, prefix)})
+		}
+
+		key := heldAddress{address.AllocationID, addr}
+		if holder, exists := held[key]; exists {
+			issues = append(issues, Issue{"address", address.ID, fmt.Sprintf("address %s is also held by address %q", addr, holder)})
+			continue
+		}
+		held[key] = address.ID
+	}
+
+	return issues
+}
+
+// prefixWithinAny reports whether prefix is fully contained in one of the parents.
+func prefixWithinAny(prefix netip.Prefix, parents []netip.Prefix) bool {
+	for _, parent := range parents {
+		if parent.Bits() <= prefix.Bits() && parent.Contains(prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
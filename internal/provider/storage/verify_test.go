@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(s *Snapshot)
+		want   string // part of the single expected issue, empty for none
+	}{
+		{"valid", func(s *Snapshot) {}, ""},
+		{"pool without CIDRs", func(s *Snapshot) {
+			s.Pools = append(s.Pools, Pool{Name: "empty"})
+		}, "pool empty: pool has no CIDRs"},
+		{"invalid pool CIDR", func(s *Snapshot) {
+			s.Pools = append(s.Pools, Pool{Name: "bad", CIDRs: []string{"10.0.0.0/33"}})
+		}, `pool bad: invalid CIDR "10.0.0.0/33"`},
+		{"reservation outside pool", func(s *Snapshot) {
+			s.Pools[0].ReservedCIDRs = []string{"192.168.0.0/24"}
+		}, "reserved CIDR 192.168.0.0/24 is outside of the pool"},
+		{"missing parent pool", func(s *Snapshot) {
+			s.Pools = append(s.Pools, Pool{Name: "child", CIDRs: []string{"10.0.8.0/24"}, ParentPool: "gone"})
+		}, `parent pool "gone" does not exist`},
+		{"child outside parent", func(s *Snapshot) {
+			s.Pools = append(s.Pools, Pool{Name: "child", CIDRs: []string{"10.1.0.0/24"}, ParentPool: "prod"})
+		}, `CIDR 10.1.0.0/24 is outside of parent pool "prod"`},
+		{"child overlapping allocation", func(s *Snapshot) {
+			s.Pools = append(s.Pools, Pool{Name: "child", CIDRs: []string{"10.0.1.0/25"}, ParentPool: "prod"})
+		}, `overlaps allocation "db"`},
+		{"allocation of missing pool", func(s *Snapshot) {
+			s.Allocations = append(s.Allocations, Allocation{ID: "lost", PoolName: "gone", AllocatedCIDR: "10.0.5.0/24", PrefixLength: 24})
+		}, `allocation lost: pool "gone" does not exist`},
+		{"prefix length mismatch", func(s *Snapshot) {
+			s.Allocations[1].PrefixLength = 25
+		}, "does not match prefix length 25"},
+		{"allocation outside pool", func(s *Snapshot) {
+			s.Allocations = append(s.Allocations, Allocation{ID: "out", PoolName: "prod", AllocatedCIDR: "10.9.0.0/24", PrefixLength: 24})
+		}, `CIDR 10.9.0.0/24 is outside of pool "prod"`},
+		{"orphaned allocation outside pool", func(s *Snapshot) {
+			s.Allocations = append(s.Allocations, Allocation{ID: "out", PoolName: "prod", AllocatedCIDR: "10.9.0.0/24", PrefixLength: 24, Orphaned: true})
+		}, ""},
+		{"overlapping allocations", func(s *Snapshot) {
+			s.Allocations = append(s.Allocations, Allocation{ID: "x", PoolName: "prod", AllocatedCIDR: "10.0.0.128/25", PrefixLength: 25})
+		}, `overlaps allocation "web"`},
+		{"block outside group", func(s *Snapshot) {
+			s.Allocations[0].Blocks = []string{"10.0.0.0/25", "10.0.3.0/25"}
+		}, "allocation web: block 10.0.3.0/25 is outside of 10.0.0.0/24"},
+		{"address of missing allocation", func(s *Snapshot) {
+			s.Addresses = append(s.Addresses, Address{ID: "lost", AllocationID: "gone", Address: "10.0.0.2"})
+		}, `address lost: allocation "gone" does not exist`},
+		{"address outside allocation", func(s *Snapshot) {
+			s.Addresses = append(s.Addresses, Address{ID: "out", AllocationID: "web", Address: "10.0.1.2"})
+		}, `address 10.0.1.2 is outside of allocation "web"`},
+		{"address held twice", func(s *Snapshot) {
+			s.Addresses = append(s.Addresses, Address{ID: "web-gw2", AllocationID: "web", Address: "10.0.0.1"})
+		}, `address 10.0.0.1 is also held by address "web-gw"`},
+	}
+	for _, tt := range tests {
+		snap := testSnapshot()
+		tt.change(snap)
+		snap.sort()
+
+		issues := Verify(snap)
+		switch {
+		case tt.want == "" && len(issues) != 0:
+			t.Errorf("%s: expected no issues, got %v", tt.name, issues)
+		case tt.want != "" && (len(issues) != 1 || !strings.Contains(issues[0].String(), tt.want)):
+			t.Errorf("%s: expected one issue containing %q, got %v", tt.name, tt.want, issues)
+		}
+	}
+}
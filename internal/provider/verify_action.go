@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/action"
+	"github.com/hashicorp/terraform-plugin-framework/action/schema"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"terraform-provider-tfipam/internal/provider/storage"
+)
+
+var _ action.Action = &VerifyAction{}
+var _ action.ActionWithConfigure = &VerifyAction{}
+
+func NewVerifyAction() action.Action {
+	return &VerifyAction{}
+}
+
+type VerifyAction struct {
+	provider *IpamProvider
+}
+
+func (a *VerifyAction) Metadata(ctx context.Context, req action.MetadataRequest, resp *action.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_verify"
+}
+
+func (a *VerifyAction) Schema(ctx context.Context, req action.SchemaRequest, resp *action.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Checks the integrity of the provider's storage backend, the same checks as `tfipam fsck`, and fails when it finds problems",
+	}
+}
+
+func (a *VerifyAction) Configure(ctx context.Context, req action.ConfigureRequest, resp *action.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	provider, ok := req.ProviderData.(*IpamProvider)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Action Configure Type",
+			fmt.Sprintf("Expected *IpamProvider, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	a.provider = provider
+}
+
+func (a *VerifyAction) Invoke(ctx context.Context, req action.InvokeRequest, resp *action.InvokeResponse) {
+	snap, err := storage.TakeSnapshot(ctx, a.provider.storage)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Storage Read Failed",
+			fmt.Sprintf("Could not read storage: %s", err),
+		)
+		return
+	}
+
+	issues := storage.Verify(snap)
+	for _, issue := range issues {
+		resp.Diagnostics.AddError("Storage Validation Failed", issue.String())
+	}
+
+	resp.SendProgress(action.InvokeProgressEvent{
+		Message: fmt.Sprintf("checked %d pools, %d allocations and %d addresses, %d issue(s) found",
+			len(snap.Pools), len(snap.Allocations), len(snap.Addresses), len(issues)),
+	})
+
+	tflog.Trace(ctx, "invoked verify action", map[string]any{
+		"issues": len(issues),
+	})
+}